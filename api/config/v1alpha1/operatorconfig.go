@@ -0,0 +1,271 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// environment variables used for the options that are not set in the configuration file, for compatibility with the previous releases
+const (
+	SyncPeriodSecondsEnvVarKey     = "SYNC_PERIOD_SECONDS"
+	AllowSystemNamespacesEnvVarKey = "ALLOW_SYSTEM_NAMESPACES"
+)
+
+// default values of the options
+const (
+	DefaultSyncPeriod             = 10 * time.Hour
+	DefaultLeaderElectionID       = "b0b2f089.redhat.io"
+	DefaultMetricsBindAddress     = ":8080"
+	DefaultHealthProbeBindAddress = ":8081"
+	DefaultRateLimiterBaseDelay   = 5 * time.Millisecond
+	DefaultRateLimiterMaxDelay    = 1000 * time.Second
+	DefaultRateLimiterQPS         = 10
+	DefaultRateLimiterBurst       = 100
+)
+
+// DefaultProtectedNamespacePatterns are the namespaces protected by default, so that the operator does not interfere with the core of the system
+var DefaultProtectedNamespacePatterns = []string{"default", "kube-*", "openshift-*"}
+
+// Load loads the configuration from the given file, then fills the options that are not set with the environment variables and the defaults, and validates the result.
+// When the path is empty only the environment variables and the defaults are used.
+func Load(file string) (*OperatorConfig, error) {
+	config := &OperatorConfig{}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the configuration file: %w", err)
+		}
+		err = yaml.UnmarshalStrict(data, config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the configuration file %s: %w", file, err)
+		}
+		if config.APIVersion != GroupVersion.String() || config.Kind != OperatorConfigKind {
+			return nil, fmt.Errorf("the configuration file %s must have apiVersion %s and kind %s, found apiVersion %q and kind %q", file, GroupVersion.String(), OperatorConfigKind, config.APIVersion, config.Kind)
+		}
+	}
+	err := config.applyEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	config.Default()
+	err = config.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid operator configuration: %w", err)
+	}
+	return config, nil
+}
+
+// applyEnvironment fills the options that are not set with the environment variables
+func (c *OperatorConfig) applyEnvironment() error {
+	if value, ok := os.LookupEnv(SyncPeriodSecondsEnvVarKey); ok && value != "" && c.SyncPeriod == nil {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s environment variable %q: %w", SyncPeriodSecondsEnvVarKey, value, err)
+		}
+		c.SyncPeriod = &metav1.Duration{Duration: time.Duration(seconds) * time.Second}
+	}
+	if value, ok := os.LookupEnv(AllowSystemNamespacesEnvVarKey); ok && value != "" {
+		allow, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s environment variable %q: %w", AllowSystemNamespacesEnvVarKey, value, err)
+		}
+		c.ProtectedNamespaces.Disabled = c.ProtectedNamespaces.Disabled || allow
+	}
+	return nil
+}
+
+// Default sets the default values of the options that are not set
+func (c *OperatorConfig) Default() {
+	if c.APIVersion == "" {
+		c.APIVersion = GroupVersion.String()
+		c.Kind = OperatorConfigKind
+	}
+	if c.SyncPeriod == nil {
+		c.SyncPeriod = &metav1.Duration{Duration: DefaultSyncPeriod}
+	}
+	if c.ProtectedNamespaces.Patterns == nil {
+		c.ProtectedNamespaces.Patterns = append([]string{}, DefaultProtectedNamespacePatterns...)
+	}
+	if c.IdentitySource == "" {
+		c.IdentitySource = IdentitySourceAuto
+	}
+	c.Controllers.NamespaceConfig.Default()
+	c.Controllers.GroupConfig.Default()
+	c.Controllers.UserConfig.Default()
+	c.Controllers.ResourceConfig.Default()
+	c.Controllers.NamespacedResourceConfig.Default()
+	if c.LeaderElection.LeaderElect == nil {
+		leaderElect := false
+		c.LeaderElection.LeaderElect = &leaderElect
+	}
+	if c.LeaderElection.ResourceName == "" {
+		c.LeaderElection.ResourceName = DefaultLeaderElectionID
+	}
+	if c.Metrics.BindAddress == "" {
+		c.Metrics.BindAddress = DefaultMetricsBindAddress
+	}
+	if c.Health.HealthProbeBindAddress == "" {
+		c.Health.HealthProbeBindAddress = DefaultHealthProbeBindAddress
+	}
+}
+
+// Default sets the default values of the options of a controller that are not set
+func (c *Controller) Default() {
+	if c.Enabled == nil {
+		enabled := true
+		c.Enabled = &enabled
+	}
+	if c.MaxConcurrentReconciles == 0 {
+		c.MaxConcurrentReconciles = 1
+	}
+	c.RateLimiter.Default()
+}
+
+// Default sets the default values of the options of a rate limiter that are not set, the defaults are the ones of the controllers of controller-runtime
+func (r *RateLimiter) Default() {
+	if r.BaseDelay == nil {
+		r.BaseDelay = &metav1.Duration{Duration: DefaultRateLimiterBaseDelay}
+	}
+	if r.MaxDelay == nil {
+		r.MaxDelay = &metav1.Duration{Duration: DefaultRateLimiterMaxDelay}
+	}
+	if r.QPS == 0 {
+		r.QPS = DefaultRateLimiterQPS
+	}
+	if r.Burst == 0 {
+		r.Burst = DefaultRateLimiterBurst
+	}
+}
+
+// Validate returns all the invalid options of the configuration
+func (c *OperatorConfig) Validate() error {
+	errs := []error{}
+	if c.SyncPeriod != nil && c.SyncPeriod.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("syncPeriod: must be positive, found %s", c.SyncPeriod.Duration))
+	}
+	for i, pattern := range c.ProtectedNamespaces.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("protectedNamespaces.patterns[%d]: invalid pattern %q: %w", i, pattern, err))
+		}
+	}
+	switch c.IdentitySource {
+	case "", IdentitySourceAuto, IdentitySourceOpenShift, IdentitySourceTenant:
+	default:
+		errs = append(errs, fmt.Errorf("identitySource: must be %s, %s or %s, found %q", IdentitySourceAuto, IdentitySourceOpenShift, IdentitySourceTenant, c.IdentitySource))
+	}
+	if _, err := labels.Parse(c.ConfigSelector); err != nil {
+		errs = append(errs, fmt.Errorf("configSelector: invalid selector %q: %w", c.ConfigSelector, err))
+	}
+	for _, controller := range []struct {
+		name   string
+		config Controller
+	}{
+		{"namespaceConfig", c.Controllers.NamespaceConfig},
+		{"groupConfig", c.Controllers.GroupConfig},
+		{"userConfig", c.Controllers.UserConfig},
+		{"resourceConfig", c.Controllers.ResourceConfig},
+		{"namespacedResourceConfig", c.Controllers.NamespacedResourceConfig},
+	} {
+		if controller.config.MaxConcurrentReconciles < 0 {
+			errs = append(errs, fmt.Errorf("controllers.%s.maxConcurrentReconciles: must not be negative, found %d", controller.name, controller.config.MaxConcurrentReconciles))
+		}
+		errs = append(errs, controller.config.RateLimiter.validate("controllers."+controller.name+".rateLimiter")...)
+	}
+	return errors.Join(errs...)
+}
+
+// validate returns the invalid options of a rate limiter, prefixed with the path of the rate limiter in the configuration
+func (r *RateLimiter) validate(prefix string) []error {
+	errs := []error{}
+	if r.BaseDelay != nil && r.BaseDelay.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("%s.baseDelay: must be positive, found %s", prefix, r.BaseDelay.Duration))
+	}
+	if r.MaxDelay != nil && r.MaxDelay.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("%s.maxDelay: must be positive, found %s", prefix, r.MaxDelay.Duration))
+	}
+	if r.BaseDelay != nil && r.MaxDelay != nil && r.MaxDelay.Duration < r.BaseDelay.Duration {
+		errs = append(errs, fmt.Errorf("%s.maxDelay: must not be lower than baseDelay %s, found %s", prefix, r.BaseDelay.Duration, r.MaxDelay.Duration))
+	}
+	if r.QPS < 0 {
+		errs = append(errs, fmt.Errorf("%s.qps: must not be negative, found %v", prefix, r.QPS))
+	}
+	if r.Burst < 0 {
+		errs = append(errs, fmt.Errorf("%s.burst: must not be negative, found %d", prefix, r.Burst))
+	}
+	return errs
+}
+
+// GetConfigSelector returns the selector of the configs reconciled by this instance of the operator
+func (c *OperatorConfig) GetConfigSelector() (labels.Selector, error) {
+	return labels.Parse(c.ConfigSelector)
+}
+
+// EnableOnly enables the controllers with the given names, NamespaceConfig, GroupConfig, UserConfig, ResourceConfig or NamespacedResourceConfig, and disables the others
+func (c *Controllers) EnableOnly(names []string) error {
+	controllers := map[string]*Controller{
+		"namespaceconfig":          &c.NamespaceConfig,
+		"groupconfig":              &c.GroupConfig,
+		"userconfig":               &c.UserConfig,
+		"resourceconfig":           &c.ResourceConfig,
+		"namespacedresourceconfig": &c.NamespacedResourceConfig,
+	}
+	enabled := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := controllers[name]; !ok {
+			return fmt.Errorf("unknown controller %q, the controllers are NamespaceConfig, GroupConfig, UserConfig, ResourceConfig and NamespacedResourceConfig", name)
+		}
+		enabled[name] = true
+	}
+	for name, controller := range controllers {
+		isEnabled := enabled[name]
+		controller.Enabled = &isEnabled
+	}
+	return nil
+}
+
+// IsEnabled tells whether the controller runs
+func (c *Controller) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// IsProtected tells whether the NamespaceConfigs must never select the namespace with the given name
+func (p *ProtectedNamespaces) IsProtected(name string) bool {
+	if p.Disabled {
+		return false
+	}
+	for _, pattern := range p.Patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
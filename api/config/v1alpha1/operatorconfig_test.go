@@ -0,0 +1,367 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func duration(d time.Duration) *metav1.Duration {
+	return &metav1.Duration{Duration: d}
+}
+
+func TestValidateRateLimiter(t *testing.T) {
+	tests := []struct {
+		name        string
+		rateLimiter RateLimiter
+		errors      []string
+	}{
+		{
+			name:        "defaults",
+			rateLimiter: RateLimiter{},
+		},
+		{
+			name:        "valid",
+			rateLimiter: RateLimiter{BaseDelay: duration(time.Second), MaxDelay: duration(time.Minute), QPS: 5, Burst: 50},
+		},
+		{
+			name:        "base delay equal to max delay",
+			rateLimiter: RateLimiter{BaseDelay: duration(time.Second), MaxDelay: duration(time.Second)},
+		},
+		{
+			name:        "zero base delay",
+			rateLimiter: RateLimiter{BaseDelay: duration(0)},
+			errors:      []string{"controllers.namespaceConfig.rateLimiter.baseDelay: must be positive"},
+		},
+		{
+			name:        "negative max delay",
+			rateLimiter: RateLimiter{MaxDelay: duration(-time.Second)},
+			errors:      []string{"controllers.namespaceConfig.rateLimiter.maxDelay: must be positive"},
+		},
+		{
+			name:        "base delay greater than max delay",
+			rateLimiter: RateLimiter{BaseDelay: duration(time.Minute), MaxDelay: duration(time.Second)},
+			errors:      []string{"controllers.namespaceConfig.rateLimiter.maxDelay: must not be lower than baseDelay"},
+		},
+		{
+			name:        "negative qps",
+			rateLimiter: RateLimiter{QPS: -1},
+			errors:      []string{"controllers.namespaceConfig.rateLimiter.qps: must not be negative"},
+		},
+		{
+			name:        "negative burst",
+			rateLimiter: RateLimiter{Burst: -1},
+			errors:      []string{"controllers.namespaceConfig.rateLimiter.burst: must not be negative"},
+		},
+		{
+			name:        "several errors",
+			rateLimiter: RateLimiter{QPS: -1, Burst: -1},
+			errors:      []string{"rateLimiter.qps", "rateLimiter.burst"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := &OperatorConfig{}
+			config.Controllers.NamespaceConfig.RateLimiter = test.rateLimiter
+			config.Default()
+			assertErrors(t, config.Validate(), test.errors)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name                  string
+		file                  string
+		syncPeriodSeconds     string
+		allowSystemNamespaces string
+		errors                []string
+		check                 func(t *testing.T, config *OperatorConfig)
+	}{
+		{
+			name: "no file",
+			check: func(t *testing.T, config *OperatorConfig) {
+				if config.SyncPeriod.Duration != DefaultSyncPeriod {
+					t.Errorf("expected sync period %s, found %s", DefaultSyncPeriod, config.SyncPeriod.Duration)
+				}
+				if config.IdentitySource != IdentitySourceAuto {
+					t.Errorf("expected identity source %s, found %s", IdentitySourceAuto, config.IdentitySource)
+				}
+				if !config.ProtectedNamespaces.IsProtected("kube-system") {
+					t.Errorf("expected kube-system to be protected")
+				}
+				if !config.Controllers.UserConfig.IsEnabled() || config.Controllers.UserConfig.MaxConcurrentReconciles != 1 {
+					t.Errorf("expected the default options of the controllers, found %+v", config.Controllers.UserConfig)
+				}
+			},
+		},
+		{
+			name: "file",
+			file: `apiVersion: config.redhatcop.redhat.io/v1alpha1
+kind: OperatorConfig
+syncPeriod: 1h
+identitySource: Tenant
+controllers:
+  groupConfig:
+    enabled: false
+  namespaceConfig:
+    maxConcurrentReconciles: 4
+    rateLimiter:
+      qps: 20
+`,
+			check: func(t *testing.T, config *OperatorConfig) {
+				if config.SyncPeriod.Duration != time.Hour {
+					t.Errorf("expected sync period 1h, found %s", config.SyncPeriod.Duration)
+				}
+				if config.IdentitySource != IdentitySourceTenant {
+					t.Errorf("expected identity source %s, found %s", IdentitySourceTenant, config.IdentitySource)
+				}
+				if config.Controllers.GroupConfig.IsEnabled() {
+					t.Errorf("expected the GroupConfig controller to be disabled")
+				}
+				namespaceConfig := config.Controllers.NamespaceConfig
+				if namespaceConfig.MaxConcurrentReconciles != 4 || namespaceConfig.RateLimiter.QPS != 20 || namespaceConfig.RateLimiter.Burst != DefaultRateLimiterBurst {
+					t.Errorf("expected the options of the file merged with the defaults, found %+v", namespaceConfig)
+				}
+			},
+		},
+		{
+			name:                  "environment",
+			syncPeriodSeconds:     "60",
+			allowSystemNamespaces: "true",
+			check: func(t *testing.T, config *OperatorConfig) {
+				if config.SyncPeriod.Duration != time.Minute {
+					t.Errorf("expected sync period 1m, found %s", config.SyncPeriod.Duration)
+				}
+				if config.ProtectedNamespaces.IsProtected("kube-system") {
+					t.Errorf("expected the protection of the namespaces to be disabled")
+				}
+			},
+		},
+		{
+			name: "file takes precedence over environment",
+			file: `apiVersion: config.redhatcop.redhat.io/v1alpha1
+kind: OperatorConfig
+syncPeriod: 1h
+`,
+			syncPeriodSeconds: "60",
+			check: func(t *testing.T, config *OperatorConfig) {
+				if config.SyncPeriod.Duration != time.Hour {
+					t.Errorf("expected sync period 1h, found %s", config.SyncPeriod.Duration)
+				}
+			},
+		},
+		{
+			name:              "invalid environment",
+			syncPeriodSeconds: "ten",
+			errors:            []string{"invalid SYNC_PERIOD_SECONDS environment variable"},
+		},
+		{
+			name: "wrong kind",
+			file: `apiVersion: config.redhatcop.redhat.io/v1alpha1
+kind: Config
+`,
+			errors: []string{"must have apiVersion config.redhatcop.redhat.io/v1alpha1 and kind OperatorConfig"},
+		},
+		{
+			name: "unknown field",
+			file: `apiVersion: config.redhatcop.redhat.io/v1alpha1
+kind: OperatorConfig
+syncPeriods: 1h
+`,
+			errors: []string{"unable to parse the configuration file", "syncPeriods"},
+		},
+		{
+			name: "invalid options",
+			file: `apiVersion: config.redhatcop.redhat.io/v1alpha1
+kind: OperatorConfig
+identitySource: LDAP
+`,
+			errors: []string{"invalid operator configuration", "identitySource"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(SyncPeriodSecondsEnvVarKey, test.syncPeriodSeconds)
+			t.Setenv(AllowSystemNamespacesEnvVarKey, test.allowSystemNamespaces)
+			file := ""
+			if test.file != "" {
+				file = filepath.Join(t.TempDir(), "config.yaml")
+				err := os.WriteFile(file, []byte(test.file), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			config, err := Load(file)
+			assertErrors(t, err, test.errors)
+			if test.check != nil && config != nil {
+				test.check(t, config)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assertErrors(t, err, []string{"unable to read the configuration file"})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config OperatorConfig
+		errors []string
+	}{
+		{
+			name:   "defaults",
+			config: OperatorConfig{},
+		},
+		{
+			name:   "negative sync period",
+			config: OperatorConfig{SyncPeriod: duration(-time.Minute)},
+			errors: []string{"syncPeriod: must be positive"},
+		},
+		{
+			name:   "invalid protected namespace pattern",
+			config: OperatorConfig{ProtectedNamespaces: ProtectedNamespaces{Patterns: []string{"kube-*", "openshift-["}}},
+			errors: []string{"protectedNamespaces.patterns[1]: invalid pattern"},
+		},
+		{
+			name:   "unknown identity source",
+			config: OperatorConfig{IdentitySource: "LDAP"},
+			errors: []string{"identitySource: must be Auto, OpenShift or Tenant"},
+		},
+		{
+			name:   "invalid config selector",
+			config: OperatorConfig{ConfigSelector: "shard in (a"},
+			errors: []string{"configSelector: invalid selector"},
+		},
+		{
+			name:   "valid config selector",
+			config: OperatorConfig{ConfigSelector: "shard in (a,b)"},
+		},
+		{
+			name: "negative max concurrent reconciles",
+			config: OperatorConfig{Controllers: Controllers{
+				ResourceConfig: Controller{MaxConcurrentReconciles: -1},
+			}},
+			errors: []string{"controllers.resourceConfig.maxConcurrentReconciles: must not be negative"},
+		},
+		{
+			name: "invalid rate limiter",
+			config: OperatorConfig{Controllers: Controllers{
+				NamespacedResourceConfig: Controller{RateLimiter: RateLimiter{Burst: -1}},
+			}},
+			errors: []string{"controllers.namespacedResourceConfig.rateLimiter.burst: must not be negative"},
+		},
+		{
+			name: "several errors",
+			config: OperatorConfig{
+				SyncPeriod:     duration(0),
+				IdentitySource: "LDAP",
+			},
+			errors: []string{"syncPeriod", "identitySource"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.Default()
+			assertErrors(t, config.Validate(), test.errors)
+		})
+	}
+}
+
+func TestEnableOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		enabled []string
+		errors  []string
+	}{
+		{
+			name:    "one controller",
+			names:   []string{"NamespaceConfig"},
+			enabled: []string{"namespaceConfig"},
+		},
+		{
+			name:    "several controllers with spaces and empty names",
+			names:   []string{" userconfig", "", "GroupConfig "},
+			enabled: []string{"groupConfig", "userConfig"},
+		},
+		{
+			name:    "no controller",
+			names:   []string{},
+			enabled: []string{},
+		},
+		{
+			name:   "unknown controller",
+			names:  []string{"NamespaceConfig", "ClusterConfig"},
+			errors: []string{`unknown controller "clusterconfig"`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			controllers := &Controllers{}
+			err := controllers.EnableOnly(test.names)
+			assertErrors(t, err, test.errors)
+			if err != nil {
+				return
+			}
+			expected := map[string]bool{}
+			for _, name := range test.enabled {
+				expected[name] = true
+			}
+			for name, controller := range map[string]Controller{
+				"namespaceConfig":          controllers.NamespaceConfig,
+				"groupConfig":              controllers.GroupConfig,
+				"userConfig":               controllers.UserConfig,
+				"resourceConfig":           controllers.ResourceConfig,
+				"namespacedResourceConfig": controllers.NamespacedResourceConfig,
+			} {
+				if controller.IsEnabled() != expected[name] {
+					t.Errorf("expected %s to be enabled %t, found %t", name, expected[name], controller.IsEnabled())
+				}
+			}
+		})
+	}
+}
+
+// assertErrors checks that err contains all the expected messages, or that it is nil when no message is expected
+func assertErrors(t *testing.T, err error, expected []string) {
+	t.Helper()
+	if len(expected) == 0 {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("expected errors %q, found none", expected)
+	}
+	for _, message := range expected {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("expected error %q, found %v", message, err)
+		}
+	}
+}
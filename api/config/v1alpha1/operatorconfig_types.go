@@ -0,0 +1,161 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains the v1alpha1 version of the configuration file of the operator
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupVersion is the group version of the configuration file
+var GroupVersion = schema.GroupVersion{Group: "config.redhatcop.redhat.io", Version: "v1alpha1"}
+
+// OperatorConfigKind is the kind of the configuration file
+const OperatorConfigKind = "OperatorConfig"
+
+// OperatorConfig is the configuration of the operator, loaded from the file passed with the --config flag
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// SyncPeriod is the period after which all the configs are reconciled again, even if nothing changed. Defaults to 10h.
+	SyncPeriod *metav1.Duration `json:"syncPeriod,omitempty"`
+
+	// ProtectedNamespaces are the namespaces that the NamespaceConfigs never select, for the safety of the core of the system
+	ProtectedNamespaces ProtectedNamespaces `json:"protectedNamespaces,omitempty"`
+
+	// Controllers configures each controller of the operator
+	Controllers Controllers `json:"controllers,omitempty"`
+
+	// IdentitySource is the source of the users and groups selected by the UserConfigs and GroupConfigs: OpenShift, Tenant or Auto, which uses the OpenShift user API when it is available and the Tenant and TenantGroup resources otherwise. Defaults to Auto.
+	IdentitySource IdentitySource `json:"identitySource,omitempty"`
+
+	// Cluster describes the cluster to the templates, through the cluster function. The fields not set are discovered at startup.
+	Cluster Cluster `json:"cluster,omitempty"`
+
+	// ConfigSelector is a label selector, in the kubectl format, restricting the configs reconciled by this instance of the operator, so that multiple instances can split the configs in shards. All the configs are reconciled if empty.
+	ConfigSelector string `json:"configSelector,omitempty"`
+
+	// LeaderElection configures the leader election among the replicas of the operator
+	LeaderElection LeaderElection `json:"leaderElection,omitempty"`
+
+	// Metrics configures the metrics endpoint
+	Metrics Metrics `json:"metrics,omitempty"`
+
+	// Health configures the health probes endpoint
+	Health Health `json:"health,omitempty"`
+}
+
+// ProtectedNamespaces are the namespaces that the NamespaceConfigs never select
+type ProtectedNamespaces struct {
+	// Disabled allows the NamespaceConfigs to select any namespace, including the protected ones
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Patterns are the glob patterns matching the names of the protected namespaces. Defaults to default, kube-* and openshift-*.
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// Controllers configures each controller of the operator
+type Controllers struct {
+	// NamespaceConfig configures the NamespaceConfig controller
+	NamespaceConfig Controller `json:"namespaceConfig,omitempty"`
+
+	// GroupConfig configures the GroupConfig controller
+	GroupConfig Controller `json:"groupConfig,omitempty"`
+
+	// UserConfig configures the UserConfig controller
+	UserConfig Controller `json:"userConfig,omitempty"`
+
+	// ResourceConfig configures the ResourceConfig controller
+	ResourceConfig Controller `json:"resourceConfig,omitempty"`
+
+	// NamespacedResourceConfig configures the NamespacedResourceConfig controller
+	NamespacedResourceConfig Controller `json:"namespacedResourceConfig,omitempty"`
+}
+
+// Controller configures a controller of the operator
+type Controller struct {
+	// Enabled tells whether the controller runs. Defaults to true.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// MaxConcurrentReconciles is the number of configs that the controller reconciles concurrently. Defaults to 1.
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// RateLimiter configures how fast the configs are queued for reconciliation by the controller
+	RateLimiter RateLimiter `json:"rateLimiter,omitempty"`
+}
+
+// RateLimiter configures the rate limiting of the work queue of a controller, which combines an exponential backoff of the configs failing to reconcile with an overall token bucket
+type RateLimiter struct {
+	// BaseDelay is the delay before a failed config is reconciled again, doubled at each consecutive failure. Defaults to 5ms.
+	BaseDelay *metav1.Duration `json:"baseDelay,omitempty"`
+
+	// MaxDelay is the maximum delay before a failed config is reconciled again. Defaults to 1000s.
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+
+	// QPS is the overall number of configs queued per second. Defaults to 10.
+	QPS float64 `json:"qps,omitempty"`
+
+	// Burst is the number of configs that can be queued at once above the QPS. Defaults to 100.
+	Burst int `json:"burst,omitempty"`
+}
+
+// IdentitySource is the source of the users and groups selected by the UserConfigs and GroupConfigs
+type IdentitySource string
+
+// the supported identity sources
+const (
+	IdentitySourceAuto      IdentitySource = "Auto"
+	IdentitySourceOpenShift IdentitySource = "OpenShift"
+	IdentitySourceTenant    IdentitySource = "Tenant"
+)
+
+// LeaderElection configures the leader election among the replicas of the operator
+type LeaderElection struct {
+	// LeaderElect enables the leader election, so that only one replica of the operator is active
+	LeaderElect *bool `json:"leaderElect,omitempty"`
+
+	// ResourceName is the name of the lease used for the leader election. Defaults to b0b2f089.redhat.io.
+	ResourceName string `json:"resourceName,omitempty"`
+
+	// ResourceNamespace is the namespace of the lease used for the leader election. Defaults to the namespace of the operator.
+	ResourceNamespace string `json:"resourceNamespace,omitempty"`
+}
+
+// Metrics configures the metrics endpoint
+type Metrics struct {
+	// BindAddress is the address the metrics endpoint binds to, 0 disables the endpoint. Defaults to :8080.
+	BindAddress string `json:"bindAddress,omitempty"`
+}
+
+// Health configures the health probes endpoint
+type Health struct {
+	// HealthProbeBindAddress is the address the health probes endpoint binds to. Defaults to :8081.
+	HealthProbeBindAddress string `json:"healthProbeBindAddress,omitempty"`
+}
+
+// Cluster describes the cluster to the templates
+type Cluster struct {
+	// BaseDomain is the base DNS domain of the cluster. Discovered from the OpenShift DNS configuration if not set.
+	BaseDomain string `json:"baseDomain,omitempty"`
+
+	// APIServerURL is the URL of the API server. Discovered from the OpenShift infrastructure configuration, or from the connection of the operator, if not set.
+	APIServerURL string `json:"apiServerURL,omitempty"`
+
+	// InfrastructureName is the unique name of the cluster infrastructure. Discovered from the OpenShift infrastructure configuration if not set.
+	InfrastructureName string `json:"infrastructureName,omitempty"`
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	apis "github.com/redhat-cop/operator-utils/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ResourceTemplate is a template of the resources to be created for the selected objects, together with the options controlling when it is processed
+type ResourceTemplate struct {
+	apis.LockedResourceTemplate `json:",inline"`
+
+	// When restricts the objects, among the ones selected by the config, for which this template is processed. If not set the template is processed for all the selected objects.
+	// +kubebuilder:validation:Optional
+	When *TemplateSelector `json:"when,omitempty"`
+
+	// Wave orders the application of the templates. The resources generated by a template are applied only after all the resources generated by the templates with a lower wave exist and are ready.
+	// A resource is considered ready when its Ready condition, if any, is True. Namespaces must also be in the Active phase and CustomResourceDefinitions must be Established.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=0
+	Wave int32 `json:"wave,omitempty"`
+
+	// AdoptionPolicy decides what happens when an object generated by this template already exists and is not managed by this config.
+	// Adopt takes the ownership of the object and enforces it, SkipIfExists leaves the object alone and reports it in the status, Fail fails the reconcile.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Adopt;SkipIfExists;Fail
+	// +kubebuilder:default=Adopt
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+
+	// Mode decides how the objects generated by this template are managed. Enforce creates the objects and reverts any change to them, CreateOnly creates the missing objects and then leaves them alone,
+	// Patch merges the generated fields into the objects, if they exist, every time the config is processed. Objects generated with CreateOnly and Patch are not watched and are never deleted.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Enforce;CreateOnly;Patch
+	// +kubebuilder:default=Enforce
+	Mode TemplateMode `json:"mode,omitempty"`
+}
+
+// TemplateMode decides how the objects generated by a template are managed
+type TemplateMode string
+
+const (
+	// TemplateModeEnforce the objects are created and any change to them is reverted
+	TemplateModeEnforce TemplateMode = "Enforce"
+	// TemplateModeCreateOnly the objects are created if they do not exist and then left alone
+	TemplateModeCreateOnly TemplateMode = "CreateOnly"
+	// TemplateModePatch the generated fields are merged into the existing objects
+	TemplateModePatch TemplateMode = "Patch"
+)
+
+// AdoptionPolicy decides what happens when a generated object already exists and is not managed by the config generating it
+type AdoptionPolicy string
+
+const (
+	// AdoptionPolicyAdopt the config takes the ownership of the existing object
+	AdoptionPolicyAdopt AdoptionPolicy = "Adopt"
+	// AdoptionPolicySkipIfExists the existing object is left alone
+	AdoptionPolicySkipIfExists AdoptionPolicy = "SkipIfExists"
+	// AdoptionPolicyFail the existing object causes the reconcile to fail
+	AdoptionPolicyFail AdoptionPolicy = "Fail"
+)
+
+// BlockedWaveStatus reports the first wave of templates that has not been applied because some resources of the previous waves are not ready
+type BlockedWaveStatus struct {
+	// Wave the blocked wave.
+	Wave int32 `json:"wave"`
+
+	// WaitingFor the resources of the previous waves that are not ready yet, in the kind/namespace/name format.
+	// +listType=set
+	WaitingFor []string `json:"waitingFor,omitempty"`
+}
+
+// TemplateSelector selects objects by label and by annotation. Selectors are considered in AND, so if both are defined they must both be true for an object to be selected.
+type TemplateSelector struct {
+	// LabelSelector selects objects by label.
+	// +kubebuilder:validation:Optional
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+
+	// AnnotationSelector selects objects by annotation.
+	// +kubebuilder:validation:Optional
+	AnnotationSelector *metav1.LabelSelector `json:"annotationSelector,omitempty"`
+}
+
+// EnforcementMode decides whether the generated objects are enforced or only compared with the live objects
+type EnforcementMode string
+
+const (
+	// EnforcementModeEnforce the generated objects are created and enforced
+	EnforcementModeEnforce EnforcementMode = "Enforce"
+	// EnforcementModeAudit the generated objects are compared with the live objects and the drifts are reported, nothing is modified
+	EnforcementModeAudit EnforcementMode = "Audit"
+)
+
+// DriftStatus reports an object whose live state deviates from the one generated by the templates
+type DriftStatus struct {
+	// Object is the drifted object, in the kind/namespace/name format.
+	Object string `json:"object"`
+
+	// Diff summarizes the differences, one line per drifted field with the generated value and the live value. The values of the Secrets and of the data, stringData and binaryData fields are not reported.
+	Diff string `json:"diff"`
+}
+
+// EnforcementStatus reports how the resources generated by a config are enforced
+type EnforcementStatus struct {
+	// BlockedWave is set when some templates have not been applied yet, because they are waiting for the resources of a previous wave to become ready
+	// +kubebuilder:validation:Optional
+	BlockedWave *BlockedWaveStatus `json:"blockedWave,omitempty"`
+
+	// SkippedResources are the objects, in the kind/namespace/name format, that already existed and have been left alone because of the SkipIfExists adoption policy
+	// +kubebuilder:validation:Optional
+	// +listType=set
+	SkippedResources []string `json:"skippedResources,omitempty"`
+
+	// ApplyConflicts are the conflicts with other field managers that prevented some objects from being applied, when ServerSideApply is set
+	// +kubebuilder:validation:Optional
+	// +listType=set
+	ApplyConflicts []string `json:"applyConflicts,omitempty"`
+
+	// AppliedKinds are the kinds, in the <apiVersion>/<kind> format, of the objects applied when ServerSideApply is set, so that the objects no longer generated are deleted after the operator restarts
+	// +kubebuilder:validation:Optional
+	// +listType=set
+	AppliedKinds []string `json:"appliedKinds,omitempty"`
+
+	// DriftedObjects is the number of generated objects whose live state deviates from the generated one, when EnforcementMode is Audit
+	// +kubebuilder:validation:Optional
+	DriftedObjects int32 `json:"driftedObjects,omitempty"`
+
+	// Drifts are the generated objects whose live state deviates from the generated one, when EnforcementMode is Audit. At most 100 objects are reported.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=object
+	Drifts []DriftStatus `json:"drifts,omitempty"`
+
+	// Targets are the selected objects for which resources have been generated, with the number of resources generated for each of them. At most 100 objects are reported.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=target
+	Targets []TargetStatus `json:"targets,omitempty"`
+}
+
+// TargetStatus reports the resources generated for an object selected by a config
+type TargetStatus struct {
+	// Target is the selected object, in the kind/namespace/name format.
+	Target string `json:"target"`
+
+	// UID of the selected object.
+	// +kubebuilder:validation:Optional
+	UID string `json:"uid,omitempty"`
+
+	// Resources is the number of resources generated for the selected object.
+	Resources int32 `json:"resources"`
+}
+
+// ServerSideApply configures the enforcement of the generated objects with server side apply
+type ServerSideApply struct {
+	// FieldManager is the name of the field manager used to apply the objects.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=namespace-configuration-operator
+	FieldManager string `json:"fieldManager,omitempty"`
+
+	// Force takes the ownership of the fields owned by other field managers instead of reporting the conflicts.
+	// +kubebuilder:validation:Optional
+	Force bool `json:"force,omitempty"`
+}
+
+// ValuesReference references a ConfigMap or a Secret whose data is made available to the templates as parameters
+type ValuesReference struct {
+	// Kind of the referenced object, either ConfigMap or Secret.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +kubebuilder:default=ConfigMap
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the referenced object.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the referenced object.
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+
+	// Optional when true a missing object is ignored instead of failing the reconcile.
+	// +kubebuilder:validation:Optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// CopySource references a ConfigMap or Secret copied into each selected namespace
+type CopySource struct {
+	// Kind of the copied object, either ConfigMap or Secret.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +kubebuilder:default=ConfigMap
+	Kind string `json:"kind,omitempty"`
+
+	// Namespace of the copied object.
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+
+	// Name of the copied object.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// TargetName is the name of the copies. Defaults to the name of the copied object.
+	// +kubebuilder:validation:Optional
+	TargetName string `json:"targetName,omitempty"`
+
+	// Keys are the keys of the data to copy, optionally renamed. All the data is copied if empty.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=key
+	Keys []CopiedKey `json:"keys,omitempty"`
+
+	// Optional when true a missing object is ignored instead of failing the reconcile.
+	// +kubebuilder:validation:Optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// CopiedKey is a key of the data of a copied object
+type CopiedKey struct {
+	// Key in the data of the copied object.
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+
+	// To is the key in the data of the copies. Defaults to Key.
+	// +kubebuilder:validation:Optional
+	To string `json:"to,omitempty"`
+}
+
+// TemplateReference references a ConfigTemplate whose templates are processed together with the ones defined inline
+type TemplateReference struct {
+	// Name of the referenced ConfigTemplate.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Parameters override the parameters of the config and the defaults of the ConfigTemplate when processing the referenced templates.
+	// +kubebuilder:validation:Optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
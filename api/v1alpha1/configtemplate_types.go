@@ -0,0 +1,58 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConfigTemplateSpec defines a reusable set of templates that NamespaceConfigs, GroupConfigs and UserConfigs can reference by name
+type ConfigTemplateSpec struct {
+	// Templates these are the templates of the resources to be created for each object selected by a config referencing this ConfigTemplate
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Templates []ResourceTemplate `json:"templates,omitempty"`
+
+	// Parameters are the default values of the parameters used by the templates. They can be overridden by the referencing configs.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ConfigTemplate is the Schema for the configtemplates API
+// +kubebuilder:resource:path=configtemplates,scope=Cluster
+type ConfigTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ConfigTemplateSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ConfigTemplateList contains a list of ConfigTemplate
+type ConfigTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ConfigTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ConfigTemplate{}, &ConfigTemplateList{})
+}
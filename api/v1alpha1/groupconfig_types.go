@@ -46,7 +46,37 @@ type GroupConfigSpec struct {
 	// Templates these are the templates of the resources to be created when a selected groups is created/updated
 	// +kubebuilder:validation:Optional
 	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
-	Templates []apis.LockedResourceTemplate `json:"templates,omitempty"`
+	Templates []ResourceTemplate `json:"templates,omitempty"`
+
+	// TemplateRefs references ConfigTemplates whose templates are processed, in addition to the ones defined in Templates, for each selected object
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	TemplateRefs []TemplateReference `json:"templateRefs,omitempty"`
+
+	// Parameters are made available to the templates through the parameters and parameter functions. They take precedence over the values loaded with ValuesFrom.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// ValuesFrom references ConfigMaps or Secrets whose data is merged into the parameters. When the same key is defined more than once, later references take precedence.
+	// Changes to the referenced objects cause the templates to be processed again.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+
+	// ServerSideApply when set, the generated objects are enforced with server side apply: the operator owns and enforces only the fields present in the templates, and the conflicts with other field managers are reported in the status.
+	// ExcludedPaths are ignored in this mode.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ServerSideApply *ServerSideApply `json:"serverSideApply,omitempty"`
+
+	// EnforcementMode with Enforce the generated objects are created and enforced, with Audit they are only compared with the live objects and the drifted objects are reported in the status, without modifying anything.
+	// ExcludedPaths are not compared.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Enforce;Audit
+	// +kubebuilder:default=Enforce
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
 }
 
 // GroupConfigStatus defines the observed state of GroupConfig
@@ -57,6 +87,17 @@ type GroupConfigStatus struct {
 
 	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
 	apis.EnforcingReconcileStatus `json:",inline"`
+
+	// ObservedGeneration is the generation of the config the status refers to
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SelectedCount is the number of groups selected by the config
+	// +kubebuilder:validation:Optional
+	SelectedCount int32 `json:"selectedCount,omitempty"`
+
+	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
+	EnforcementStatus `json:",inline"`
 }
 
 func (m *GroupConfig) GetEnforcingReconcileStatus() apis.EnforcingReconcileStatus {
@@ -67,8 +108,32 @@ func (m *GroupConfig) SetEnforcingReconcileStatus(reconcileStatus apis.Enforcing
 	m.Status.EnforcingReconcileStatus = reconcileStatus
 }
 
+func (m *GroupConfig) GetEnforcementStatus() *EnforcementStatus {
+	return &m.Status.EnforcementStatus
+}
+
+func (m *GroupConfig) SetObservedGeneration(generation int64) {
+	m.Status.ObservedGeneration = generation
+}
+
+func (m *GroupConfig) GetTemplates() []ResourceTemplate {
+	return m.Spec.Templates
+}
+
+func (m *GroupConfig) GetTemplateRefs() []TemplateReference {
+	return m.Spec.TemplateRefs
+}
+
+func (m *GroupConfig) GetValuesFrom() []ValuesReference {
+	return m.Spec.ValuesFrom
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Selected",type=integer,JSONPath=`.status.selectedCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // GroupConfig is the Schema for the groupconfigs API
 // +kubebuilder:resource:path=groupconfigs,scope=Cluster
@@ -25,7 +25,7 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // NamespaceConfigSpec defines the desired state of NamespaceConfig
-// There are two selectors: "labelSelector", "annotationSelector".
+// There are three selectors: "labelSelector", "annotationSelector", "ancestorSelector".
 // Selectors are considered in AND, so if multiple are defined they must all be true for a Namespace to be selected.
 type NamespaceConfigSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -44,12 +44,79 @@ type NamespaceConfigSpec struct {
 	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors.x-descriptors="urn:alm:descriptor:com.tectonic.ui:selector:"
 	AnnotationSelector metav1.LabelSelector `json:"annotationSelector,omitempty"`
 
+	// AncestorSelector selects Namespaces by the labels of their ancestors: a Namespace is selected when one of its ancestors matches.
+	// The parent of a Namespace is named by its redhatcop.redhat.io/parent annotation.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors.x-descriptors="urn:alm:descriptor:com.tectonic.ui:selector:"
+	AncestorSelector *metav1.LabelSelector `json:"ancestorSelector,omitempty"`
+
 	// Templates these are the templates of the resources to be created when a selected namespace is created/updated
 	// +kubebuilder:validation:Optional
 	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
-	Templates []apis.LockedResourceTemplate `json:"templates,omitempty"`
+	Templates []ResourceTemplate `json:"templates,omitempty"`
+
+	// TemplateRefs references ConfigTemplates whose templates are processed, in addition to the ones defined in Templates, for each selected object
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	TemplateRefs []TemplateReference `json:"templateRefs,omitempty"`
+
+	// CopyFrom references ConfigMaps or Secrets copied, as they are or with a subset of their data, into each selected namespace.
+	// Changes to the referenced objects are propagated to the copies.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	CopyFrom []CopySource `json:"copyFrom,omitempty"`
+
+	// Parameters are made available to the templates through the parameters and parameter functions. They take precedence over the values loaded with ValuesFrom.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// ValuesFrom references ConfigMaps or Secrets whose data is merged into the parameters. When the same key is defined more than once, later references take precedence.
+	// Changes to the referenced objects cause the templates to be processed again.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+
+	// ServerSideApply when set, the generated objects are enforced with server side apply: the operator owns and enforces only the fields present in the templates, and the conflicts with other field managers are reported in the status.
+	// ExcludedPaths are ignored in this mode.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ServerSideApply *ServerSideApply `json:"serverSideApply,omitempty"`
+
+	// EnforcementMode with Enforce the generated objects are created and enforced, with Audit they are only compared with the live objects and the drifted objects are reported in the status, without modifying anything.
+	// ExcludedPaths are not compared.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Enforce;Audit
+	// +kubebuilder:default=Enforce
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+
+	// Priority decides which config manages an object when several NamespaceConfigs generate it: the config with the highest priority wins. Configs with the same priority are in conflict.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=0
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Priority int32 `json:"priority,omitempty"`
+
+	// MergeMode decides what happens to the objects this config wins over configs with a lower priority. With Override the objects generated by this config replace the other ones,
+	// with Merge the objects generated by this config are merged on top of the ones generated by the configs with a lower priority, strategically for the built-in types.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Override;Merge
+	// +kubebuilder:default=Override
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	MergeMode MergeMode `json:"mergeMode,omitempty"`
 }
 
+// MergeMode decides how an object generated by several configs is computed
+type MergeMode string
+
+const (
+	// MergeModeOverride the object generated by the config with the highest priority is used as is
+	MergeModeOverride MergeMode = "Override"
+	// MergeModeMerge the objects generated by the configs are merged in increasing order of priority
+	MergeModeMerge MergeMode = "Merge"
+)
+
 // NamespaceConfigStatus defines the observed state of NamespaceSConfig
 type NamespaceConfigStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -58,6 +125,17 @@ type NamespaceConfigStatus struct {
 
 	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
 	apis.EnforcingReconcileStatus `json:",inline"`
+
+	// ObservedGeneration is the generation of the config the status refers to
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SelectedCount is the number of namespaces selected by the config
+	// +kubebuilder:validation:Optional
+	SelectedCount int32 `json:"selectedCount,omitempty"`
+
+	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
+	EnforcementStatus `json:",inline"`
 }
 
 func (m *NamespaceConfig) GetEnforcingReconcileStatus() apis.EnforcingReconcileStatus {
@@ -68,8 +146,36 @@ func (m *NamespaceConfig) SetEnforcingReconcileStatus(reconcileStatus apis.Enfor
 	m.Status.EnforcingReconcileStatus = reconcileStatus
 }
 
+func (m *NamespaceConfig) GetEnforcementStatus() *EnforcementStatus {
+	return &m.Status.EnforcementStatus
+}
+
+func (m *NamespaceConfig) SetObservedGeneration(generation int64) {
+	m.Status.ObservedGeneration = generation
+}
+
+func (m *NamespaceConfig) GetTemplates() []ResourceTemplate {
+	return m.Spec.Templates
+}
+
+func (m *NamespaceConfig) GetTemplateRefs() []TemplateReference {
+	return m.Spec.TemplateRefs
+}
+
+func (m *NamespaceConfig) GetValuesFrom() []ValuesReference {
+	return m.Spec.ValuesFrom
+}
+
+func (m *NamespaceConfig) GetCopyFrom() []CopySource {
+	return m.Spec.CopyFrom
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Selected",type=integer,JSONPath=`.status.selectedCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // NamespaceConfig is the Schema for the namespaceconfigs API
 // +kubebuilder:resource:path=namespaceconfigs,scope=Cluster
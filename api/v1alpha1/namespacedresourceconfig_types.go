@@ -0,0 +1,163 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	apis "github.com/redhat-cop/operator-utils/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NamespacedResourceConfigSpec defines the desired state of NamespacedResourceConfig
+// The objects are selected, and generated, only in the namespace of the config.
+// There are two selectors: "labelSelector", "annotationSelector".
+// Selectors are considered in AND, so if multiple are defined they must all be true for an object to be selected.
+type NamespacedResourceConfigSpec struct {
+	// TargetGVK is the group, version and kind of the selected objects, which must be a namespaced kind
+	// +kubebuilder:validation:Required
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	TargetGVK TargetGVK `json:"targetGVK"`
+
+	// ServiceAccountName is the name of a ServiceAccount in the namespace of the config, which must be allowed to get, list, watch, create, update, patch and delete the generated objects.
+	// The operator manages the generated objects impersonating the ServiceAccount, so that the authors of the config cannot generate objects they could not manage themselves.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ServiceAccountName string `json:"serviceAccountName"`
+
+	// LabelSelector selects the objects of the target kind by label.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors.x-descriptors="urn:alm:descriptor:com.tectonic.ui:selector:"
+	LabelSelector metav1.LabelSelector `json:"labelSelector,omitempty"`
+
+	// AnnotationSelector selects the objects of the target kind by annotation.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors.x-descriptors="urn:alm:descriptor:com.tectonic.ui:selector:"
+	AnnotationSelector metav1.LabelSelector `json:"annotationSelector,omitempty"`
+
+	// Templates these are the templates of the resources to be created when a selected object is created/updated
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Templates []ResourceTemplate `json:"templates,omitempty"`
+
+	// TemplateRefs references ConfigTemplates whose templates are processed, in addition to the ones defined in Templates, for each selected object
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	TemplateRefs []TemplateReference `json:"templateRefs,omitempty"`
+
+	// Parameters are made available to the templates through the parameters and parameter functions. They take precedence over the values loaded with ValuesFrom.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// ValuesFrom references ConfigMaps or Secrets, in the namespace of the config, whose data is merged into the parameters. When the same key is defined more than once, later references take precedence.
+	// Changes to the referenced objects cause the templates to be processed again.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+
+	// ServerSideApply when set, the generated objects are enforced with server side apply: the operator owns and enforces only the fields present in the templates, and the conflicts with other field managers are reported in the status.
+	// ExcludedPaths are ignored in this mode.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ServerSideApply *ServerSideApply `json:"serverSideApply,omitempty"`
+
+	// EnforcementMode with Enforce the generated objects are created and enforced, with Audit they are only compared with the live objects and the drifted objects are reported in the status, without modifying anything.
+	// ExcludedPaths are not compared.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Enforce;Audit
+	// +kubebuilder:default=Enforce
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+}
+
+// NamespacedResourceConfigStatus defines the observed state of NamespacedResourceConfig
+type NamespacedResourceConfigStatus struct {
+	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
+	apis.EnforcingReconcileStatus `json:",inline"`
+
+	// ObservedGeneration is the generation of the config the status refers to
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SelectedCount is the number of objects selected by the config
+	// +kubebuilder:validation:Optional
+	SelectedCount int32 `json:"selectedCount,omitempty"`
+
+	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
+	EnforcementStatus `json:",inline"`
+}
+
+func (m *NamespacedResourceConfig) GetEnforcingReconcileStatus() apis.EnforcingReconcileStatus {
+	return m.Status.EnforcingReconcileStatus
+}
+
+func (m *NamespacedResourceConfig) SetEnforcingReconcileStatus(reconcileStatus apis.EnforcingReconcileStatus) {
+	m.Status.EnforcingReconcileStatus = reconcileStatus
+}
+
+func (m *NamespacedResourceConfig) GetEnforcementStatus() *EnforcementStatus {
+	return &m.Status.EnforcementStatus
+}
+
+func (m *NamespacedResourceConfig) SetObservedGeneration(generation int64) {
+	m.Status.ObservedGeneration = generation
+}
+
+func (m *NamespacedResourceConfig) GetTemplates() []ResourceTemplate {
+	return m.Spec.Templates
+}
+
+func (m *NamespacedResourceConfig) GetTemplateRefs() []TemplateReference {
+	return m.Spec.TemplateRefs
+}
+
+func (m *NamespacedResourceConfig) GetValuesFrom() []ValuesReference {
+	return m.Spec.ValuesFrom
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.targetGVK.kind`
+// +kubebuilder:printcolumn:name="Selected",type=integer,JSONPath=`.status.selectedCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// NamespacedResourceConfig is the Schema for the namespacedresourceconfigs API
+// +kubebuilder:resource:path=namespacedresourceconfigs,scope=Namespaced
+type NamespacedResourceConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NamespacedResourceConfigSpec   `json:"spec,omitempty"`
+	Status NamespacedResourceConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NamespacedResourceConfigList contains a list of NamespacedResourceConfig
+type NamespacedResourceConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespacedResourceConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NamespacedResourceConfig{}, &NamespacedResourceConfigList{})
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	apis "github.com/redhat-cop/operator-utils/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TargetGVK is the group, version and kind of the objects selected by a ResourceConfig
+type TargetGVK struct {
+	// Group of the selected objects, empty for the core group
+	// +kubebuilder:validation:Optional
+	Group string `json:"group,omitempty"`
+
+	// Version of the selected objects
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// Kind of the selected objects
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+}
+
+// GroupVersionKind returns the target as a GroupVersionKind
+func (t TargetGVK) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   t.Group,
+		Version: t.Version,
+		Kind:    t.Kind,
+	}
+}
+
+// ResourceConfigSpec defines the desired state of ResourceConfig
+// There are two selectors: "labelSelector", "annotationSelector".
+// Selectors are considered in AND, so if multiple are defined they must all be true for an object to be selected.
+type ResourceConfigSpec struct {
+	// TargetGVK is the group, version and kind of the selected objects
+	// +kubebuilder:validation:Required
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	TargetGVK TargetGVK `json:"targetGVK"`
+
+	// LabelSelector selects the objects of the target kind by label.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors.x-descriptors="urn:alm:descriptor:com.tectonic.ui:selector:"
+	LabelSelector metav1.LabelSelector `json:"labelSelector,omitempty"`
+
+	// AnnotationSelector selects the objects of the target kind by annotation.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors.x-descriptors="urn:alm:descriptor:com.tectonic.ui:selector:"
+	AnnotationSelector metav1.LabelSelector `json:"annotationSelector,omitempty"`
+
+	// Templates these are the templates of the resources to be created when a selected object is created/updated
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Templates []ResourceTemplate `json:"templates,omitempty"`
+
+	// TemplateRefs references ConfigTemplates whose templates are processed, in addition to the ones defined in Templates, for each selected object
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	TemplateRefs []TemplateReference `json:"templateRefs,omitempty"`
+
+	// Parameters are made available to the templates through the parameters and parameter functions. They take precedence over the values loaded with ValuesFrom.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// ValuesFrom references ConfigMaps or Secrets whose data is merged into the parameters. When the same key is defined more than once, later references take precedence.
+	// Changes to the referenced objects cause the templates to be processed again.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+
+	// ServerSideApply when set, the generated objects are enforced with server side apply: the operator owns and enforces only the fields present in the templates, and the conflicts with other field managers are reported in the status.
+	// ExcludedPaths are ignored in this mode.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ServerSideApply *ServerSideApply `json:"serverSideApply,omitempty"`
+
+	// EnforcementMode with Enforce the generated objects are created and enforced, with Audit they are only compared with the live objects and the drifted objects are reported in the status, without modifying anything.
+	// ExcludedPaths are not compared.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Enforce;Audit
+	// +kubebuilder:default=Enforce
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+}
+
+// ResourceConfigStatus defines the observed state of ResourceConfig
+type ResourceConfigStatus struct {
+	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
+	apis.EnforcingReconcileStatus `json:",inline"`
+
+	// ObservedGeneration is the generation of the config the status refers to
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SelectedCount is the number of objects selected by the config
+	// +kubebuilder:validation:Optional
+	SelectedCount int32 `json:"selectedCount,omitempty"`
+
+	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
+	EnforcementStatus `json:",inline"`
+}
+
+func (m *ResourceConfig) GetEnforcingReconcileStatus() apis.EnforcingReconcileStatus {
+	return m.Status.EnforcingReconcileStatus
+}
+
+func (m *ResourceConfig) SetEnforcingReconcileStatus(reconcileStatus apis.EnforcingReconcileStatus) {
+	m.Status.EnforcingReconcileStatus = reconcileStatus
+}
+
+func (m *ResourceConfig) GetEnforcementStatus() *EnforcementStatus {
+	return &m.Status.EnforcementStatus
+}
+
+func (m *ResourceConfig) SetObservedGeneration(generation int64) {
+	m.Status.ObservedGeneration = generation
+}
+
+func (m *ResourceConfig) GetTemplates() []ResourceTemplate {
+	return m.Spec.Templates
+}
+
+func (m *ResourceConfig) GetTemplateRefs() []TemplateReference {
+	return m.Spec.TemplateRefs
+}
+
+func (m *ResourceConfig) GetValuesFrom() []ValuesReference {
+	return m.Spec.ValuesFrom
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.targetGVK.kind`
+// +kubebuilder:printcolumn:name="Selected",type=integer,JSONPath=`.status.selectedCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ResourceConfig is the Schema for the resourceconfigs API
+// +kubebuilder:resource:path=resourceconfigs,scope=Cluster
+type ResourceConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ResourceConfigSpec   `json:"spec,omitempty"`
+	Status ResourceConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ResourceConfigList contains a list of ResourceConfig
+type ResourceConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ResourceConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ResourceConfig{}, &ResourceConfigList{})
+}
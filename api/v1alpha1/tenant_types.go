@@ -0,0 +1,71 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TenantIdentity is an identity through which a tenant authenticates
+type TenantIdentity struct {
+	// ProviderName is the name of the identity provider, matched by the providerName of the UserConfigs
+	// +kubebuilder:validation:Required
+	ProviderName string `json:"providerName"`
+
+	// ProviderUserName is the name of the tenant in the identity provider
+	// +kubebuilder:validation:Optional
+	ProviderUserName string `json:"providerUserName,omitempty"`
+
+	// Extra are the extra fields of the identity, matched by the identityExtraFieldSelector of the UserConfigs
+	// +kubebuilder:validation:Optional
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// Tenant is a user of the cluster, it is selected by the UserConfigs on the clusters without the OpenShift user API.
+// Like the OpenShift users, it has no spec, so that the same templates can be processed for both.
+// +kubebuilder:resource:path=tenants,scope=Cluster
+// +kubebuilder:printcolumn:name="Full Name",type=string,JSONPath=`.fullName`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type Tenant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// FullName is the full name of the tenant
+	// +kubebuilder:validation:Optional
+	FullName string `json:"fullName,omitempty"`
+
+	// Identities are the identities through which the tenant authenticates
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=providerName
+	Identities []TenantIdentity `json:"identities,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TenantList contains a list of Tenant
+type TenantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Tenant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Tenant{}, &TenantList{})
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// TenantGroup is a group of tenants, it is selected by the GroupConfigs on the clusters without the OpenShift user API.
+// Like the OpenShift groups, it has no spec, so that the same templates can be processed for both.
+// +kubebuilder:resource:path=tenantgroups,scope=Cluster
+// +kubebuilder:printcolumn:name="Users",type=string,JSONPath=`.users`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type TenantGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Users are the names of the tenants in the group
+	// +kubebuilder:validation:Optional
+	Users []string `json:"users,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TenantGroupList contains a list of TenantGroup
+type TenantGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TenantGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TenantGroup{}, &TenantGroupList{})
+}
@@ -64,7 +64,37 @@ type UserConfigSpec struct {
 	// Templates these are the templates of the resources to be created when a selected user is created/updated
 	// +kubebuilder:validation:Optional
 	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
-	Templates []apis.LockedResourceTemplate `json:"templates,omitempty"`
+	Templates []ResourceTemplate `json:"templates,omitempty"`
+
+	// TemplateRefs references ConfigTemplates whose templates are processed, in addition to the ones defined in Templates, for each selected object
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	TemplateRefs []TemplateReference `json:"templateRefs,omitempty"`
+
+	// Parameters are made available to the templates through the parameters and parameter functions. They take precedence over the values loaded with ValuesFrom.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// ValuesFrom references ConfigMaps or Secrets whose data is merged into the parameters. When the same key is defined more than once, later references take precedence.
+	// Changes to the referenced objects cause the templates to be processed again.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+
+	// ServerSideApply when set, the generated objects are enforced with server side apply: the operator owns and enforces only the fields present in the templates, and the conflicts with other field managers are reported in the status.
+	// ExcludedPaths are ignored in this mode.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	ServerSideApply *ServerSideApply `json:"serverSideApply,omitempty"`
+
+	// EnforcementMode with Enforce the generated objects are created and enforced, with Audit they are only compared with the live objects and the drifted objects are reported in the status, without modifying anything.
+	// ExcludedPaths are not compared.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Enforce;Audit
+	// +kubebuilder:default=Enforce
+	// +operator-sdk:gen-csv:customresourcedefinitions.specDescriptors=true
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
 }
 
 // UserConfigStatus defines the observed state of UserConfig
@@ -75,6 +105,17 @@ type UserConfigStatus struct {
 
 	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
 	apis.EnforcingReconcileStatus `json:",inline"`
+
+	// ObservedGeneration is the generation of the config the status refers to
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SelectedCount is the number of users selected by the config
+	// +kubebuilder:validation:Optional
+	SelectedCount int32 `json:"selectedCount,omitempty"`
+
+	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
+	EnforcementStatus `json:",inline"`
 }
 
 func (m *UserConfig) GetEnforcingReconcileStatus() apis.EnforcingReconcileStatus {
@@ -85,8 +126,32 @@ func (m *UserConfig) SetEnforcingReconcileStatus(reconcileStatus apis.EnforcingR
 	m.Status.EnforcingReconcileStatus = reconcileStatus
 }
 
+func (m *UserConfig) GetEnforcementStatus() *EnforcementStatus {
+	return &m.Status.EnforcementStatus
+}
+
+func (m *UserConfig) SetObservedGeneration(generation int64) {
+	m.Status.ObservedGeneration = generation
+}
+
+func (m *UserConfig) GetTemplates() []ResourceTemplate {
+	return m.Spec.Templates
+}
+
+func (m *UserConfig) GetTemplateRefs() []TemplateReference {
+	return m.Spec.TemplateRefs
+}
+
+func (m *UserConfig) GetValuesFrom() []ValuesReference {
+	return m.Spec.ValuesFrom
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Selected",type=integer,JSONPath=`.status.selectedCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // UserConfig is the Schema for the userconfigs API
 // +kubebuilder:resource:path=userconfigs,scope=Cluster
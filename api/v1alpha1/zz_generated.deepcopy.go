@@ -22,10 +22,212 @@ limitations under the License.
 package v1alpha1
 
 import (
-	apiv1alpha1 "github.com/redhat-cop/operator-utils/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockedWaveStatus) DeepCopyInto(out *BlockedWaveStatus) {
+	*out = *in
+	if in.WaitingFor != nil {
+		in, out := &in.WaitingFor, &out.WaitingFor
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockedWaveStatus.
+func (in *BlockedWaveStatus) DeepCopy() *BlockedWaveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockedWaveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigTemplate) DeepCopyInto(out *ConfigTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigTemplate.
+func (in *ConfigTemplate) DeepCopy() *ConfigTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigTemplateList) DeepCopyInto(out *ConfigTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ConfigTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigTemplateList.
+func (in *ConfigTemplateList) DeepCopy() *ConfigTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigTemplateSpec) DeepCopyInto(out *ConfigTemplateSpec) {
+	*out = *in
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make([]ResourceTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigTemplateSpec.
+func (in *ConfigTemplateSpec) DeepCopy() *ConfigTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CopiedKey) DeepCopyInto(out *CopiedKey) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CopiedKey.
+func (in *CopiedKey) DeepCopy() *CopiedKey {
+	if in == nil {
+		return nil
+	}
+	out := new(CopiedKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CopySource) DeepCopyInto(out *CopySource) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]CopiedKey, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CopySource.
+func (in *CopySource) DeepCopy() *CopySource {
+	if in == nil {
+		return nil
+	}
+	out := new(CopySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftStatus) DeepCopyInto(out *DriftStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftStatus.
+func (in *DriftStatus) DeepCopy() *DriftStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnforcementStatus) DeepCopyInto(out *EnforcementStatus) {
+	*out = *in
+	if in.BlockedWave != nil {
+		in, out := &in.BlockedWave, &out.BlockedWave
+		*out = new(BlockedWaveStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SkippedResources != nil {
+		in, out := &in.SkippedResources, &out.SkippedResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ApplyConflicts != nil {
+		in, out := &in.ApplyConflicts, &out.ApplyConflicts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppliedKinds != nil {
+		in, out := &in.AppliedKinds, &out.AppliedKinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Drifts != nil {
+		in, out := &in.Drifts, &out.Drifts
+		*out = make([]DriftStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]TargetStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnforcementStatus.
+func (in *EnforcementStatus) DeepCopy() *EnforcementStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EnforcementStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupConfig) DeepCopyInto(out *GroupConfig) {
 	*out = *in
@@ -92,11 +294,35 @@ func (in *GroupConfigSpec) DeepCopyInto(out *GroupConfigSpec) {
 	in.AnnotationSelector.DeepCopyInto(&out.AnnotationSelector)
 	if in.Templates != nil {
 		in, out := &in.Templates, &out.Templates
-		*out = make([]apiv1alpha1.LockedResourceTemplate, len(*in))
+		*out = make([]ResourceTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TemplateRefs != nil {
+		in, out := &in.TemplateRefs, &out.TemplateRefs
+		*out = make([]TemplateReference, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApply)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupConfigSpec.
@@ -113,6 +339,7 @@ func (in *GroupConfigSpec) DeepCopy() *GroupConfigSpec {
 func (in *GroupConfigStatus) DeepCopyInto(out *GroupConfigStatus) {
 	*out = *in
 	in.EnforcingReconcileStatus.DeepCopyInto(&out.EnforcingReconcileStatus)
+	in.EnforcementStatus.DeepCopyInto(&out.EnforcementStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupConfigStatus.
@@ -189,13 +416,49 @@ func (in *NamespaceConfigSpec) DeepCopyInto(out *NamespaceConfigSpec) {
 	*out = *in
 	in.LabelSelector.DeepCopyInto(&out.LabelSelector)
 	in.AnnotationSelector.DeepCopyInto(&out.AnnotationSelector)
+	if in.AncestorSelector != nil {
+		in, out := &in.AncestorSelector, &out.AncestorSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Templates != nil {
 		in, out := &in.Templates, &out.Templates
-		*out = make([]apiv1alpha1.LockedResourceTemplate, len(*in))
+		*out = make([]ResourceTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TemplateRefs != nil {
+		in, out := &in.TemplateRefs, &out.TemplateRefs
+		*out = make([]TemplateReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CopyFrom != nil {
+		in, out := &in.CopyFrom, &out.CopyFrom
+		*out = make([]CopySource, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApply)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceConfigSpec.
@@ -212,6 +475,7 @@ func (in *NamespaceConfigSpec) DeepCopy() *NamespaceConfigSpec {
 func (in *NamespaceConfigStatus) DeepCopyInto(out *NamespaceConfigStatus) {
 	*out = *in
 	in.EnforcingReconcileStatus.DeepCopyInto(&out.EnforcingReconcileStatus)
+	in.EnforcementStatus.DeepCopyInto(&out.EnforcementStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceConfigStatus.
@@ -225,7 +489,7 @@ func (in *NamespaceConfigStatus) DeepCopy() *NamespaceConfigStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UserConfig) DeepCopyInto(out *UserConfig) {
+func (in *NamespacedResourceConfig) DeepCopyInto(out *NamespacedResourceConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -233,18 +497,18 @@ func (in *UserConfig) DeepCopyInto(out *UserConfig) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserConfig.
-func (in *UserConfig) DeepCopy() *UserConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedResourceConfig.
+func (in *NamespacedResourceConfig) DeepCopy() *NamespacedResourceConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(UserConfig)
+	out := new(NamespacedResourceConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *UserConfig) DeepCopyObject() runtime.Object {
+func (in *NamespacedResourceConfig) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -252,31 +516,31 @@ func (in *UserConfig) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UserConfigList) DeepCopyInto(out *UserConfigList) {
+func (in *NamespacedResourceConfigList) DeepCopyInto(out *NamespacedResourceConfigList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]UserConfig, len(*in))
+		*out = make([]NamespacedResourceConfig, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserConfigList.
-func (in *UserConfigList) DeepCopy() *UserConfigList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedResourceConfigList.
+func (in *NamespacedResourceConfigList) DeepCopy() *NamespacedResourceConfigList {
 	if in == nil {
 		return nil
 	}
-	out := new(UserConfigList)
+	out := new(NamespacedResourceConfigList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *UserConfigList) DeepCopyObject() runtime.Object {
+func (in *NamespacedResourceConfigList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -284,42 +548,593 @@ func (in *UserConfigList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UserConfigSpec) DeepCopyInto(out *UserConfigSpec) {
+func (in *NamespacedResourceConfigSpec) DeepCopyInto(out *NamespacedResourceConfigSpec) {
 	*out = *in
+	out.TargetGVK = in.TargetGVK
 	in.LabelSelector.DeepCopyInto(&out.LabelSelector)
 	in.AnnotationSelector.DeepCopyInto(&out.AnnotationSelector)
-	in.IdentityExtraFieldSelector.DeepCopyInto(&out.IdentityExtraFieldSelector)
 	if in.Templates != nil {
 		in, out := &in.Templates, &out.Templates
-		*out = make([]apiv1alpha1.LockedResourceTemplate, len(*in))
+		*out = make([]ResourceTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TemplateRefs != nil {
+		in, out := &in.TemplateRefs, &out.TemplateRefs
+		*out = make([]TemplateReference, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApply)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserConfigSpec.
-func (in *UserConfigSpec) DeepCopy() *UserConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedResourceConfigSpec.
+func (in *NamespacedResourceConfigSpec) DeepCopy() *NamespacedResourceConfigSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(UserConfigSpec)
+	out := new(NamespacedResourceConfigSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UserConfigStatus) DeepCopyInto(out *UserConfigStatus) {
+func (in *NamespacedResourceConfigStatus) DeepCopyInto(out *NamespacedResourceConfigStatus) {
 	*out = *in
 	in.EnforcingReconcileStatus.DeepCopyInto(&out.EnforcingReconcileStatus)
+	in.EnforcementStatus.DeepCopyInto(&out.EnforcementStatus)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserConfigStatus.
-func (in *UserConfigStatus) DeepCopy() *UserConfigStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedResourceConfigStatus.
+func (in *NamespacedResourceConfigStatus) DeepCopy() *NamespacedResourceConfigStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(UserConfigStatus)
+	out := new(NamespacedResourceConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceConfig) DeepCopyInto(out *ResourceConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceConfig.
+func (in *ResourceConfig) DeepCopy() *ResourceConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ResourceConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceConfigList) DeepCopyInto(out *ResourceConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ResourceConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceConfigList.
+func (in *ResourceConfigList) DeepCopy() *ResourceConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ResourceConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceConfigSpec) DeepCopyInto(out *ResourceConfigSpec) {
+	*out = *in
+	out.TargetGVK = in.TargetGVK
+	in.LabelSelector.DeepCopyInto(&out.LabelSelector)
+	in.AnnotationSelector.DeepCopyInto(&out.AnnotationSelector)
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make([]ResourceTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TemplateRefs != nil {
+		in, out := &in.TemplateRefs, &out.TemplateRefs
+		*out = make([]TemplateReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApply)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceConfigSpec.
+func (in *ResourceConfigSpec) DeepCopy() *ResourceConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceConfigStatus) DeepCopyInto(out *ResourceConfigStatus) {
+	*out = *in
+	in.EnforcingReconcileStatus.DeepCopyInto(&out.EnforcingReconcileStatus)
+	in.EnforcementStatus.DeepCopyInto(&out.EnforcementStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceConfigStatus.
+func (in *ResourceConfigStatus) DeepCopy() *ResourceConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceTemplate) DeepCopyInto(out *ResourceTemplate) {
+	*out = *in
+	in.LockedResourceTemplate.DeepCopyInto(&out.LockedResourceTemplate)
+	if in.When != nil {
+		in, out := &in.When, &out.When
+		*out = new(TemplateSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceTemplate.
+func (in *ResourceTemplate) DeepCopy() *ResourceTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSideApply) DeepCopyInto(out *ServerSideApply) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSideApply.
+func (in *ServerSideApply) DeepCopy() *ServerSideApply {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSideApply)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetGVK) DeepCopyInto(out *TargetGVK) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetGVK.
+func (in *TargetGVK) DeepCopy() *TargetGVK {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetGVK)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetStatus) DeepCopyInto(out *TargetStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetStatus.
+func (in *TargetStatus) DeepCopy() *TargetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateReference) DeepCopyInto(out *TemplateReference) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateReference.
+func (in *TemplateReference) DeepCopy() *TemplateReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateSelector) DeepCopyInto(out *TemplateSelector) {
+	*out = *in
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AnnotationSelector != nil {
+		in, out := &in.AnnotationSelector, &out.AnnotationSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateSelector.
+func (in *TemplateSelector) DeepCopy() *TemplateSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Tenant) DeepCopyInto(out *Tenant) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Identities != nil {
+		in, out := &in.Identities, &out.Identities
+		*out = make([]TenantIdentity, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tenant.
+func (in *Tenant) DeepCopy() *Tenant {
+	if in == nil {
+		return nil
+	}
+	out := new(Tenant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Tenant) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantGroup) DeepCopyInto(out *TenantGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantGroup.
+func (in *TenantGroup) DeepCopy() *TenantGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TenantGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantGroupList) DeepCopyInto(out *TenantGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TenantGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantGroupList.
+func (in *TenantGroupList) DeepCopy() *TenantGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TenantGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantIdentity) DeepCopyInto(out *TenantIdentity) {
+	*out = *in
+	if in.Extra != nil {
+		in, out := &in.Extra, &out.Extra
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantIdentity.
+func (in *TenantIdentity) DeepCopy() *TenantIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantList) DeepCopyInto(out *TenantList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Tenant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantList.
+func (in *TenantList) DeepCopy() *TenantList {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TenantList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserConfig) DeepCopyInto(out *UserConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserConfig.
+func (in *UserConfig) DeepCopy() *UserConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(UserConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UserConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserConfigList) DeepCopyInto(out *UserConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]UserConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserConfigList.
+func (in *UserConfigList) DeepCopy() *UserConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(UserConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UserConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserConfigSpec) DeepCopyInto(out *UserConfigSpec) {
+	*out = *in
+	in.LabelSelector.DeepCopyInto(&out.LabelSelector)
+	in.AnnotationSelector.DeepCopyInto(&out.AnnotationSelector)
+	in.IdentityExtraFieldSelector.DeepCopyInto(&out.IdentityExtraFieldSelector)
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make([]ResourceTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TemplateRefs != nil {
+		in, out := &in.TemplateRefs, &out.TemplateRefs
+		*out = make([]TemplateReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApply)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserConfigSpec.
+func (in *UserConfigSpec) DeepCopy() *UserConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UserConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserConfigStatus) DeepCopyInto(out *UserConfigStatus) {
+	*out = *in
+	in.EnforcingReconcileStatus.DeepCopyInto(&out.EnforcingReconcileStatus)
+	in.EnforcementStatus.DeepCopyInto(&out.EnforcementStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserConfigStatus.
+func (in *UserConfigStatus) DeepCopy() *UserConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UserConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesReference) DeepCopyInto(out *ValuesReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesReference.
+func (in *ValuesReference) DeepCopy() *ValuesReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesReference)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -38,3 +38,14 @@ kube_rbac_proxy:
       memory: 20Mi
 
 enableMonitoring: true
+
+# operatorConfig is the operator configuration file, see the Operator Configuration section of the README
+operatorConfig:
+  apiVersion: config.redhatcop.redhat.io/v1alpha1
+  kind: OperatorConfig
+  leaderElection:
+    leaderElect: true
+  metrics:
+    bindAddress: 127.0.0.1:8080
+  health:
+    healthProbeBindAddress: :8081
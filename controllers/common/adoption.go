@@ -0,0 +1,48 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/scylladb/go-set/strset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ApplyAdoptionPolicies checks the resources whose template does not adopt existing objects against the live objects.
+// It returns the resources to be enforced, without the ones that already exist and are not managed by the given config, which are returned separately if their policy is SkipIfExists or cause an error if their policy is Fail.
+func ApplyAdoptionPolicies(context context.Context, reader client.Reader, config ConfigReference, resources []RenderedResource) ([]RenderedResource, []string, error) {
+	kept := []RenderedResource{}
+	skipped := strset.New()
+	failed := strset.New()
+	for _, resource := range resources {
+		if resource.AdoptionPolicy != redhatcopv1alpha1.AdoptionPolicySkipIfExists && resource.AdoptionPolicy != redhatcopv1alpha1.AdoptionPolicyFail {
+			kept = append(kept, resource)
+			continue
+		}
+		liveObj, err := getLiveObject(context, reader, &resource.Unstructured)
+		if err != nil {
+			return []RenderedResource{}, []string{}, err
+		}
+		if liveObj == nil || liveObj.GetAnnotations()[ManagedByAnnotation] == config.String() {
+			kept = append(kept, resource)
+			continue
+		}
+		key := getObjectReference(&resource.Unstructured).String()
+		if resource.AdoptionPolicy == redhatcopv1alpha1.AdoptionPolicyFail {
+			failed.Add(key)
+			continue
+		}
+		skipped.Add(key)
+	}
+	if !failed.IsEmpty() {
+		failedList := failed.List()
+		sort.Strings(failedList)
+		return []RenderedResource{}, []string{}, errors.New("objects not managed by " + config.String() + " already exist and the adoption policy is Fail: " + strings.Join(failedList, ", "))
+	}
+	skippedList := skipped.List()
+	sort.Strings(skippedList)
+	return kept, skippedList, nil
+}
@@ -0,0 +1,113 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestAdoptionResource(policy redhatcopv1alpha1.AdoptionPolicy, name string) RenderedResource {
+	obj := newTestObject("v1", "ConfigMap", "tenant", name, nil)
+	return RenderedResource{
+		LockedResource: lockedresource.LockedResource{Unstructured: *obj},
+		AdoptionPolicy: policy,
+	}
+}
+
+func newTestLiveObject(name string, managedBy string) *unstructured.Unstructured {
+	obj := newTestObject("v1", "ConfigMap", "tenant", name, nil)
+	if managedBy != "" {
+		obj.SetAnnotations(map[string]string{ManagedByAnnotation: managedBy})
+	}
+	return obj
+}
+
+func TestApplyAdoptionPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		live      []*unstructured.Unstructured
+		resources []RenderedResource
+		kept      []string
+		skipped   []string
+		wantErr   bool
+	}{
+		{
+			name: "missing objects",
+			resources: []RenderedResource{
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicyAdopt, "adopted"),
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicySkipIfExists, "skipped"),
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicyFail, "failed"),
+			},
+			kept: []string{"adopted", "skipped", "failed"},
+		},
+		{
+			name: "existing objects not managed by the config",
+			live: []*unstructured.Unstructured{
+				newTestLiveObject("adopted", ""),
+				newTestLiveObject("default", ""),
+				newTestLiveObject("skipped", "NamespaceConfig/b"),
+				newTestLiveObject("other", ""),
+			},
+			resources: []RenderedResource{
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicyAdopt, "adopted"),
+				newTestAdoptionResource("", "default"),
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicySkipIfExists, "skipped"),
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicySkipIfExists, "other"),
+			},
+			kept:    []string{"adopted", "default"},
+			skipped: []string{"ConfigMap/tenant/other", "ConfigMap/tenant/skipped"},
+		},
+		{
+			name: "existing objects managed by the config",
+			live: []*unstructured.Unstructured{
+				newTestLiveObject("skipped", clusterA.String()),
+				newTestLiveObject("failed", clusterA.String()),
+			},
+			resources: []RenderedResource{
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicySkipIfExists, "skipped"),
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicyFail, "failed"),
+			},
+			kept: []string{"skipped", "failed"},
+		},
+		{
+			name: "existing object with the policy Fail",
+			live: []*unstructured.Unstructured{
+				newTestLiveObject("failed", ""),
+			},
+			resources: []RenderedResource{
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicySkipIfExists, "skipped"),
+				newTestAdoptionResource(redhatcopv1alpha1.AdoptionPolicyFail, "failed"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := []client.Object{}
+			for _, obj := range tt.live {
+				live = append(live, obj)
+			}
+			reader := fake.NewClientBuilder().WithObjects(live...).Build()
+			kept, skipped, err := ApplyAdoptionPolicies(context.TODO(), reader, clusterA, tt.resources)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyAdoptionPolicies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			keptNames := []string{}
+			for _, resource := range kept {
+				keptNames = append(keptNames, resource.GetName())
+			}
+			if !reflect.DeepEqual(keptNames, append([]string{}, tt.kept...)) {
+				t.Errorf("kept = %v, want %v", keptNames, tt.kept)
+			}
+			if !reflect.DeepEqual(skipped, append([]string{}, tt.skipped...)) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.skipped)
+			}
+		})
+	}
+}
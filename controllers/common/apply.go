@@ -0,0 +1,235 @@
+package common
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultFieldManager is the field manager used when the config does not specify one
+const DefaultFieldManager = "namespace-configuration-operator"
+
+// Applier enforces the objects generated by the configs with server side apply, so that the operator owns only the fields present in the templates.
+// It remembers the objects applied for each config and deletes the ones that are no longer generated.
+// The kinds of the applied objects are recorded in the status of the config, so that after a restart the objects applied for a config are found again through their ownership stamp.
+type Applier struct {
+	client  client.Client
+	mutex   sync.Mutex
+	applied map[types.NamespacedName]map[objectReference]*unstructured.Unstructured
+	log     logr.Logger
+}
+
+// NewApplier creates a new Applier using the given client to apply and delete the objects
+func NewApplier(kubeClient client.Client, log logr.Logger) *Applier {
+	return &Applier{
+		client:  kubeClient,
+		mutex:   sync.Mutex{},
+		applied: map[types.NamespacedName]map[objectReference]*unstructured.Unstructured{},
+		log:     log.WithName("applier"),
+	}
+}
+
+// Apply applies the resources generated by a config of the given kind with the given options and deletes the objects previously applied for the config that are no longer generated. No resource is applied if options is nil.
+// appliedKinds are the kinds recorded in the status of the config, used to find the objects previously applied when the config has not been applied since the operator started.
+// It returns the references to the applied objects, that should be watched to apply them again when they drift, and the conflicts with other field managers.
+func (a *Applier) Apply(context context.Context, kind string, config client.Object, appliedKinds []string, options *redhatcopv1alpha1.ServerSideApply, resources []lockedresource.LockedResource) ([]LookupReference, []string, error) {
+	return a.ApplyAs(context, a.client, kind, config, appliedKinds, options, resources)
+}
+
+// ApplyAs is like Apply, but applies and deletes the objects with the given client, so that the namespaced configs apply only the objects their service account is allowed to manage
+func (a *Applier) ApplyAs(context context.Context, kubeClient client.Client, kind string, config client.Object, appliedKinds []string, options *redhatcopv1alpha1.ServerSideApply, resources []lockedresource.LockedResource) ([]LookupReference, []string, error) {
+	key := client.ObjectKeyFromObject(config)
+	previous, ok := a.getApplied(key)
+	if !ok {
+		var err error
+		previous, err = a.listApplied(context, NewConfigReference(kind, config), appliedKinds)
+		if err != nil {
+			return []LookupReference{}, []string{}, err
+		}
+	}
+	applied := map[objectReference]*unstructured.Unstructured{}
+	references := []LookupReference{}
+	conflicts := []string{}
+	if options != nil {
+		fieldManager := options.FieldManager
+		if fieldManager == "" {
+			fieldManager = DefaultFieldManager
+		}
+		patchOptions := []client.PatchOption{client.FieldOwner(fieldManager)}
+		if options.Force {
+			patchOptions = append(patchOptions, client.ForceOwnership)
+		}
+		for _, resource := range resources {
+			objKey := getObjectReference(&resource.Unstructured)
+			if _, ok := applied[objKey]; ok {
+				continue
+			}
+			obj := resource.Unstructured.DeepCopy()
+			obj.SetManagedFields(nil)
+			obj.SetResourceVersion("")
+			applied[objKey] = obj.DeepCopy()
+			references = append(references, LookupReference{
+				GroupVersionKind: obj.GroupVersionKind(),
+				Namespace:        obj.GetNamespace(),
+				Name:             obj.GetName(),
+			})
+			err := kubeClient.Patch(context, obj, client.Apply, patchOptions...)
+			if err != nil {
+				if errors.IsConflict(err) {
+					conflicts = append(conflicts, objKey.String()+": "+err.Error())
+					continue
+				}
+				a.log.Error(err, "unable to apply", "object", objKey)
+				a.setApplied(key, mergeApplied(previous, applied))
+				return []LookupReference{}, []string{}, err
+			}
+		}
+	}
+	for objKey, obj := range previous {
+		if _, ok := applied[objKey]; ok {
+			continue
+		}
+		err := kubeClient.Delete(context, obj)
+		if err != nil && !errors.IsNotFound(err) {
+			a.log.Error(err, "unable to delete", "object", objKey)
+			a.setApplied(key, mergeApplied(previous, applied))
+			return []LookupReference{}, []string{}, err
+		}
+	}
+	a.setApplied(key, applied)
+	sort.Strings(conflicts)
+	return references, conflicts, nil
+}
+
+// Delete deletes all the objects applied for a config of the given kind, it should be called when the config is deleted
+func (a *Applier) Delete(context context.Context, kind string, config client.Object, appliedKinds []string) error {
+	_, _, err := a.Apply(context, kind, config, appliedKinds, nil, []lockedresource.LockedResource{})
+	if err != nil {
+		return err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	delete(a.applied, client.ObjectKeyFromObject(config))
+	return nil
+}
+
+// Forget forgets the objects applied for a config without deleting them, it should be called when the objects of the config are no longer to be enforced but must be preserved.
+// The applied kinds should be removed from the status of the config too.
+func (a *Applier) Forget(config client.Object) {
+	a.setApplied(client.ObjectKeyFromObject(config), map[objectReference]*unstructured.Unstructured{})
+}
+
+// GetAppliedKinds returns the kinds of the objects applied for a config, in the <apiVersion>/<kind> format, to be recorded in its status
+func (a *Applier) GetAppliedKinds(config client.Object) []string {
+	applied, _ := a.getApplied(client.ObjectKeyFromObject(config))
+	kinds := map[string]bool{}
+	for _, obj := range applied {
+		kinds[obj.GetAPIVersion()+"/"+obj.GetKind()] = true
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+	result := []string{}
+	for kind := range kinds {
+		result = append(result, kind)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// getApplied returns the objects applied for a config and whether the config has been applied since the operator started, a config is never reconciled concurrently so the lock is needed only to access the map
+func (a *Applier) getApplied(key types.NamespacedName) (map[objectReference]*unstructured.Unstructured, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	applied, ok := a.applied[key]
+	return applied, ok
+}
+
+func (a *Applier) setApplied(key types.NamespacedName, applied map[objectReference]*unstructured.Unstructured) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.applied[key] = applied
+}
+
+// listApplied returns the objects of the given kinds applied for a config, they are found through the ownership stamp set on the generated objects and the apply entry of their managed fields
+func (a *Applier) listApplied(context context.Context, ref ConfigReference, appliedKinds []string) (map[objectReference]*unstructured.Unstructured, error) {
+	applied := map[objectReference]*unstructured.Unstructured{}
+	selector := labels.Set{ConfigKindLabel: ref.Kind}
+	if len(validation.IsValidLabelValue(ref.Name)) == 0 {
+		selector[ConfigNameLabel] = ref.Name
+	}
+	for _, appliedKind := range appliedKinds {
+		gvk, ok := parseAppliedKind(appliedKind)
+		if !ok {
+			continue
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := a.client.List(context, list, &client.ListOptions{LabelSelector: selector.AsSelector()})
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			a.log.Error(err, "unable to list the applied objects of", "kind", appliedKind, "config", ref)
+			return map[objectReference]*unstructured.Unstructured{}, err
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			annotations := obj.GetAnnotations()
+			if annotations[ConfigNameLabel] != ref.Name || annotations[ConfigNamespaceLabel] != ref.Namespace || !isApplied(obj) {
+				continue
+			}
+			applied[getObjectReference(obj)] = obj
+		}
+	}
+	return applied, nil
+}
+
+// parseAppliedKind parses a kind in the <apiVersion>/<kind> format
+func parseAppliedKind(appliedKind string) (schema.GroupVersionKind, bool) {
+	index := strings.LastIndex(appliedKind, "/")
+	if index <= 0 {
+		return schema.GroupVersionKind{}, false
+	}
+	gv, err := schema.ParseGroupVersion(appliedKind[:index])
+	if err != nil {
+		return schema.GroupVersionKind{}, false
+	}
+	return gv.WithKind(appliedKind[index+1:]), true
+}
+
+// isApplied returns whether some fields of an object are owned through server side apply
+func isApplied(obj *unstructured.Unstructured) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeApplied returns the union of the applied objects, so that the objects applied before a failure can still be deleted later
+func mergeApplied(previous map[objectReference]*unstructured.Unstructured, current map[objectReference]*unstructured.Unstructured) map[objectReference]*unstructured.Unstructured {
+	result := map[objectReference]*unstructured.Unstructured{}
+	for objKey, obj := range previous {
+		result[objKey] = obj
+	}
+	for objKey, obj := range current {
+		result[objKey] = obj
+	}
+	return result
+}
@@ -0,0 +1,236 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// testApplyClient records the objects applied and deleted through a fake client, which does not support server side apply
+type testApplyClient struct {
+	client.WithWatch
+	applied []string
+	deleted []string
+}
+
+func newTestApplyClient(live []client.Object, failures map[string]error) *testApplyClient {
+	testClient := &testApplyClient{}
+	testClient.WithWatch = fake.NewClientBuilder().WithObjects(live...).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patchOptions := &client.PatchOptions{}
+			patchOptions.ApplyOptions(opts)
+			applied := obj.GetName() + ":" + patchOptions.FieldManager
+			if patchOptions.Force != nil && *patchOptions.Force {
+				applied += ":force"
+			}
+			testClient.applied = append(testClient.applied, applied)
+			return failures[obj.GetName()]
+		},
+		Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			testClient.deleted = append(testClient.deleted, obj.GetName())
+			return c.Delete(ctx, obj, opts...)
+		},
+	}).Build()
+	return testClient
+}
+
+func newTestAppliedResources(names ...string) []lockedresource.LockedResource {
+	resources := []lockedresource.LockedResource{}
+	for _, name := range names {
+		resources = append(resources, lockedresource.LockedResource{Unstructured: *newTestObject("v1", "ConfigMap", "tenant", name, nil)})
+	}
+	return resources
+}
+
+func TestApplierApply(t *testing.T) {
+	config := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "a"}}
+	conflict := apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "conflicting", errors.New("owned by another manager"))
+	kubeClient := newTestApplyClient(nil, map[string]error{"conflicting": conflict})
+	applier := NewApplier(kubeClient, logr.Discard())
+	steps := []struct {
+		name       string
+		options    *redhatcopv1alpha1.ServerSideApply
+		resources  []lockedresource.LockedResource
+		applied    []string
+		deleted    []string
+		references int
+		conflicts  int
+		kinds      []string
+	}{
+		{
+			name:       "apply with the default field manager",
+			options:    &redhatcopv1alpha1.ServerSideApply{},
+			resources:  newTestAppliedResources("first", "second", "first"),
+			applied:    []string{"first:" + DefaultFieldManager, "second:" + DefaultFieldManager},
+			references: 2,
+			kinds:      []string{"v1/ConfigMap"},
+		},
+		{
+			name:       "delete the objects no longer generated",
+			options:    &redhatcopv1alpha1.ServerSideApply{FieldManager: "tenants", Force: true},
+			resources:  newTestAppliedResources("first"),
+			applied:    []string{"first:tenants:force"},
+			deleted:    []string{"second"},
+			references: 1,
+			kinds:      []string{"v1/ConfigMap"},
+		},
+		{
+			name:       "conflict with another field manager",
+			options:    &redhatcopv1alpha1.ServerSideApply{},
+			resources:  newTestAppliedResources("first", "conflicting"),
+			applied:    []string{"first:" + DefaultFieldManager, "conflicting:" + DefaultFieldManager},
+			references: 2,
+			conflicts:  1,
+			kinds:      []string{"v1/ConfigMap"},
+		},
+		{
+			name:    "server side apply disabled",
+			deleted: []string{"conflicting", "first"},
+		},
+	}
+	for _, step := range steps {
+		kubeClient.applied, kubeClient.deleted = nil, nil
+		references, conflicts, err := applier.Apply(context.TODO(), "NamespaceConfig", config, nil, step.options, step.resources)
+		if err != nil {
+			t.Fatalf("%s: Apply() error = %v", step.name, err)
+		}
+		sort.Strings(kubeClient.deleted)
+		if !reflect.DeepEqual(kubeClient.applied, step.applied) {
+			t.Errorf("%s: applied = %v, want %v", step.name, kubeClient.applied, step.applied)
+		}
+		if !reflect.DeepEqual(kubeClient.deleted, step.deleted) {
+			t.Errorf("%s: deleted = %v, want %v", step.name, kubeClient.deleted, step.deleted)
+		}
+		if len(references) != step.references {
+			t.Errorf("%s: references = %v, want %d references", step.name, references, step.references)
+		}
+		if len(conflicts) != step.conflicts {
+			t.Errorf("%s: conflicts = %v, want %d conflicts", step.name, conflicts, step.conflicts)
+		}
+		if kinds := applier.GetAppliedKinds(config); !reflect.DeepEqual(kinds, step.kinds) {
+			t.Errorf("%s: GetAppliedKinds() = %v, want %v", step.name, kinds, step.kinds)
+		}
+	}
+}
+
+func TestApplierDeleteAfterRestart(t *testing.T) {
+	config := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "a"}}
+	newLiveObject := func(name string, configName string, operation metav1.ManagedFieldsOperationType) client.Object {
+		obj := newTestObject("v1", "ConfigMap", "tenant", name, nil)
+		obj.SetLabels(map[string]string{ConfigKindLabel: "NamespaceConfig", ConfigNameLabel: configName})
+		obj.SetAnnotations(map[string]string{ConfigNameLabel: configName})
+		obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: DefaultFieldManager, Operation: operation}})
+		return obj
+	}
+	live := []client.Object{
+		newLiveObject("applied", "a", metav1.ManagedFieldsOperationApply),
+		newLiveObject("enforced", "a", metav1.ManagedFieldsOperationUpdate),
+		newLiveObject("other", "b", metav1.ManagedFieldsOperationApply),
+	}
+	tests := []struct {
+		name         string
+		appliedKinds []string
+		deleted      []string
+	}{
+		{
+			name:         "applied kind recorded",
+			appliedKinds: []string{"v1/ConfigMap"},
+			deleted:      []string{"applied"},
+		},
+		{
+			name:         "kinds not served or invalid",
+			appliedKinds: []string{"example.com/v1/Widget", "ConfigMap"},
+		},
+		{
+			name: "no applied kind",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []client.Object{}
+			for _, obj := range live {
+				objects = append(objects, obj.DeepCopyObject().(client.Object))
+			}
+			kubeClient := newTestApplyClient(objects, nil)
+			applier := NewApplier(kubeClient, logr.Discard())
+			err := applier.Delete(context.TODO(), "NamespaceConfig", config, tt.appliedKinds)
+			if err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if !reflect.DeepEqual(kubeClient.deleted, tt.deleted) {
+				t.Errorf("deleted = %v, want %v", kubeClient.deleted, tt.deleted)
+			}
+		})
+	}
+}
+
+func TestApplierForget(t *testing.T) {
+	config := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "a"}}
+	kubeClient := newTestApplyClient(nil, nil)
+	applier := NewApplier(kubeClient, logr.Discard())
+	_, _, err := applier.Apply(context.TODO(), "NamespaceConfig", config, nil, &redhatcopv1alpha1.ServerSideApply{}, newTestAppliedResources("first"))
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	applier.Forget(config)
+	if kinds := applier.GetAppliedKinds(config); kinds != nil {
+		t.Errorf("GetAppliedKinds() = %v after Forget(), want none", kinds)
+	}
+	_, _, err = applier.Apply(context.TODO(), "NamespaceConfig", config, []string{"v1/ConfigMap"}, nil, nil)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(kubeClient.deleted) != 0 {
+		t.Errorf("deleted = %v after Forget(), want none", kubeClient.deleted)
+	}
+}
+
+func TestParseAppliedKind(t *testing.T) {
+	tests := []struct {
+		appliedKind string
+		gvk         schema.GroupVersionKind
+		ok          bool
+	}{
+		{appliedKind: "v1/ConfigMap", gvk: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, ok: true},
+		{appliedKind: "apps/v1/Deployment", gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, ok: true},
+		{appliedKind: "ConfigMap"},
+		{appliedKind: "/ConfigMap"},
+		{appliedKind: "a/b/c/Kind"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.appliedKind, func(t *testing.T) {
+			gvk, ok := parseAppliedKind(tt.appliedKind)
+			if gvk != tt.gvk || ok != tt.ok {
+				t.Errorf("parseAppliedKind() = %v, %v, want %v, %v", gvk, ok, tt.gvk, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIsApplied(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	if isApplied(obj) {
+		t.Errorf("isApplied() = true without managed fields")
+	}
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate}})
+	if isApplied(obj) {
+		t.Errorf("isApplied() = true without apply entry")
+	}
+	obj.SetManagedFields(append(obj.GetManagedFields(), metav1.ManagedFieldsEntry{Manager: DefaultFieldManager, Operation: metav1.ManagedFieldsOperationApply}))
+	if !isApplied(obj) {
+		t.Errorf("isApplied() = false with an apply entry")
+	}
+}
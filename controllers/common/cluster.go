@@ -0,0 +1,72 @@
+package common
+
+import (
+	"context"
+
+	configv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/config/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// openShiftConfigGroupVersion is the group version of the OpenShift cluster configuration, whose objects are all named cluster
+var openShiftConfigGroupVersion = schema.GroupVersion{Group: "config.openshift.io", Version: "v1"}
+
+// ClusterInfo describes the cluster to the templates, exposed by the cluster function
+type ClusterInfo struct {
+	// BaseDomain is the base DNS domain of the cluster
+	BaseDomain string
+	// APIServerURL is the URL of the API server
+	APIServerURL string
+	// InfrastructureName is the unique name of the cluster infrastructure
+	InfrastructureName string
+}
+
+// DiscoverClusterInfo returns the description of the cluster, made of the given fields completed with the ones discovered in the OpenShift cluster configuration, when it exists.
+// The URL of the API server defaults to the one the operator connects to.
+func DiscoverClusterInfo(context context.Context, reader client.Reader, restConfig *rest.Config, cluster configv1alpha1.Cluster) (ClusterInfo, error) {
+	info := ClusterInfo{
+		BaseDomain:         cluster.BaseDomain,
+		APIServerURL:       cluster.APIServerURL,
+		InfrastructureName: cluster.InfrastructureName,
+	}
+	dns, err := getOpenShiftConfig(context, reader, "DNS")
+	if err != nil {
+		return ClusterInfo{}, err
+	}
+	infrastructure, err := getOpenShiftConfig(context, reader, "Infrastructure")
+	if err != nil {
+		return ClusterInfo{}, err
+	}
+	if info.BaseDomain == "" && dns != nil {
+		info.BaseDomain, _, _ = unstructured.NestedString(dns.Object, "spec", "baseDomain")
+	}
+	if info.APIServerURL == "" && infrastructure != nil {
+		info.APIServerURL, _, _ = unstructured.NestedString(infrastructure.Object, "status", "apiServerURL")
+	}
+	if info.APIServerURL == "" {
+		info.APIServerURL = restConfig.Host
+	}
+	if info.InfrastructureName == "" && infrastructure != nil {
+		info.InfrastructureName, _, _ = unstructured.NestedString(infrastructure.Object, "status", "infrastructureName")
+	}
+	return info, nil
+}
+
+// getOpenShiftConfig returns the OpenShift cluster configuration of the given kind, or nil if the cluster does not have it
+func getOpenShiftConfig(context context.Context, reader client.Reader, kind string) (*unstructured.Unstructured, error) {
+	config := &unstructured.Unstructured{}
+	config.SetGroupVersionKind(openShiftConfigGroupVersion.WithKind(kind))
+	err := reader.Get(context, types.NamespacedName{Name: "cluster"}, config)
+	if err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return config, nil
+}
@@ -0,0 +1,125 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// standard condition types of the configs, so that tools like kubectl wait and Argo CD can assess their health
+const (
+	ReadyCondition       = "Ready"
+	ProgressingCondition = "Progressing"
+	DegradedCondition    = "Degraded"
+)
+
+// reasons of the standard conditions
+const (
+	SelectorInvalidReason = "SelectorInvalid"
+	RenderFailedReason    = "RenderFailed"
+	ApplyFailedReason     = "ApplyFailed"
+	ConflictReason        = "Conflict"
+	ForbiddenReason       = "Forbidden"
+	WaitingForWaveReason  = "WaitingForWave"
+	WaitingForKindsReason = "WaitingForKinds"
+	ReconciledReason      = "Reconciled"
+)
+
+// ReconcileOutcome describes a completed reconciliation of a config, from which the standard conditions are computed
+type ReconcileOutcome struct {
+	// BlockedWave is the wave waiting for the resources of a previous wave to become ready, if any
+	BlockedWave *redhatcopv1alpha1.BlockedWaveStatus
+	// Conflicts are the resources that could not be enforced because they are claimed by other configs or field managers
+	Conflicts []string
+	// Failures are the resources that the enforcing controllers failed to enforce
+	Failures []string
+	// UnservedKinds are the kinds the config needs to watch that are not served yet, the config is reconciled again after UnservedKindRequeueInterval
+	UnservedKinds []string
+}
+
+// SetReconciledConditions sets the standard conditions of a config whose reconciliation completed with the given outcome
+func SetReconciledConditions(conditions []metav1.Condition, generation int64, outcome ReconcileOutcome) []metav1.Condition {
+	ready := metav1.Condition{
+		Type:               ReadyCondition,
+		ObservedGeneration: generation,
+		Reason:             ReconciledReason,
+		Status:             metav1.ConditionTrue,
+	}
+	progressing := metav1.Condition{
+		Type:               ProgressingCondition,
+		ObservedGeneration: generation,
+		Reason:             ReconciledReason,
+		Status:             metav1.ConditionFalse,
+	}
+	degraded := metav1.Condition{
+		Type:               DegradedCondition,
+		ObservedGeneration: generation,
+		Reason:             ReconciledReason,
+		Status:             metav1.ConditionFalse,
+	}
+	if outcome.BlockedWave != nil {
+		progressing.Status = metav1.ConditionTrue
+		progressing.Reason = WaitingForWaveReason
+		progressing.Message = fmt.Sprintf("wave %d is waiting for %s", outcome.BlockedWave.Wave, strings.Join(outcome.BlockedWave.WaitingFor, ", "))
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = progressing.Reason
+		ready.Message = progressing.Message
+	} else if len(outcome.UnservedKinds) > 0 {
+		progressing.Status = metav1.ConditionTrue
+		progressing.Reason = WaitingForKindsReason
+		progressing.Message = "waiting for " + strings.Join(outcome.UnservedKinds, ", ") + " to be served"
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = progressing.Reason
+		ready.Message = progressing.Message
+	}
+	switch {
+	case len(outcome.Conflicts) > 0:
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = ConflictReason
+		degraded.Message = strings.Join(outcome.Conflicts, "; ")
+	case len(outcome.Failures) > 0:
+		failures := append([]string{}, outcome.Failures...)
+		sort.Strings(failures)
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = ApplyFailedReason
+		degraded.Message = "unable to enforce " + strings.Join(failures, ", ")
+	}
+	if degraded.Status == metav1.ConditionTrue {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = degraded.Reason
+		ready.Message = degraded.Message
+	}
+	meta.SetStatusCondition(&conditions, ready)
+	meta.SetStatusCondition(&conditions, progressing)
+	meta.SetStatusCondition(&conditions, degraded)
+	return conditions
+}
+
+// SetFailedConditions sets the standard conditions of a config whose reconciliation failed for the given reason
+func SetFailedConditions(conditions []metav1.Condition, generation int64, reason string, issue error) []metav1.Condition {
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               ReadyCondition,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            issue.Error(),
+		Status:             metav1.ConditionFalse,
+	})
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               ProgressingCondition,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Status:             metav1.ConditionFalse,
+	})
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               DegradedCondition,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            issue.Error(),
+		Status:             metav1.ConditionTrue,
+	})
+	return conditions
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testCondition is the expected status, reason and message of a condition
+type testCondition struct {
+	status  metav1.ConditionStatus
+	reason  string
+	message string
+}
+
+func checkCondition(t *testing.T, conditions []metav1.Condition, conditionType string, want testCondition) {
+	t.Helper()
+	condition := meta.FindStatusCondition(conditions, conditionType)
+	if condition == nil {
+		t.Errorf("condition %v not set", conditionType)
+		return
+	}
+	if condition.Status != want.status || condition.Reason != want.reason || condition.Message != want.message {
+		t.Errorf("condition %v = %v %v %q, want %v %v %q", conditionType, condition.Status, condition.Reason, condition.Message, want.status, want.reason, want.message)
+	}
+	if condition.ObservedGeneration != 2 {
+		t.Errorf("condition %v observed generation = %v, want %v", conditionType, condition.ObservedGeneration, 2)
+	}
+}
+
+func TestSetReconciledConditions(t *testing.T) {
+	tests := []struct {
+		name        string
+		outcome     ReconcileOutcome
+		ready       testCondition
+		progressing testCondition
+		degraded    testCondition
+	}{
+		{
+			name:        "reconciled",
+			ready:       testCondition{status: metav1.ConditionTrue, reason: ReconciledReason},
+			progressing: testCondition{status: metav1.ConditionFalse, reason: ReconciledReason},
+			degraded:    testCondition{status: metav1.ConditionFalse, reason: ReconciledReason},
+		},
+		{
+			name:        "blocked wave",
+			outcome:     ReconcileOutcome{BlockedWave: &redhatcopv1alpha1.BlockedWaveStatus{Wave: 2, WaitingFor: []string{"Deployment/tenant/db", "Job/tenant/migrate"}}},
+			ready:       testCondition{status: metav1.ConditionFalse, reason: WaitingForWaveReason, message: "wave 2 is waiting for Deployment/tenant/db, Job/tenant/migrate"},
+			progressing: testCondition{status: metav1.ConditionTrue, reason: WaitingForWaveReason, message: "wave 2 is waiting for Deployment/tenant/db, Job/tenant/migrate"},
+			degraded:    testCondition{status: metav1.ConditionFalse, reason: ReconciledReason},
+		},
+		{
+			name:        "unserved kinds",
+			outcome:     ReconcileOutcome{UnservedKinds: []string{"example.com/v1, Kind=Widget"}},
+			ready:       testCondition{status: metav1.ConditionFalse, reason: WaitingForKindsReason, message: "waiting for example.com/v1, Kind=Widget to be served"},
+			progressing: testCondition{status: metav1.ConditionTrue, reason: WaitingForKindsReason, message: "waiting for example.com/v1, Kind=Widget to be served"},
+			degraded:    testCondition{status: metav1.ConditionFalse, reason: ReconciledReason},
+		},
+		{
+			name:        "conflicts",
+			outcome:     ReconcileOutcome{Conflicts: []string{"ConfigMap/tenant/cm is managed by NamespaceConfig/a", "ConfigMap/tenant/other is managed by NamespaceConfig/b"}},
+			ready:       testCondition{status: metav1.ConditionFalse, reason: ConflictReason, message: "ConfigMap/tenant/cm is managed by NamespaceConfig/a; ConfigMap/tenant/other is managed by NamespaceConfig/b"},
+			progressing: testCondition{status: metav1.ConditionFalse, reason: ReconciledReason},
+			degraded:    testCondition{status: metav1.ConditionTrue, reason: ConflictReason, message: "ConfigMap/tenant/cm is managed by NamespaceConfig/a; ConfigMap/tenant/other is managed by NamespaceConfig/b"},
+		},
+		{
+			name:        "failures",
+			outcome:     ReconcileOutcome{Failures: []string{"ConfigMap/tenant/z", "ConfigMap/tenant/a"}},
+			ready:       testCondition{status: metav1.ConditionFalse, reason: ApplyFailedReason, message: "unable to enforce ConfigMap/tenant/a, ConfigMap/tenant/z"},
+			progressing: testCondition{status: metav1.ConditionFalse, reason: ReconciledReason},
+			degraded:    testCondition{status: metav1.ConditionTrue, reason: ApplyFailedReason, message: "unable to enforce ConfigMap/tenant/a, ConfigMap/tenant/z"},
+		},
+		{
+			name: "conflicts while progressing",
+			outcome: ReconcileOutcome{
+				BlockedWave: &redhatcopv1alpha1.BlockedWaveStatus{Wave: 1, WaitingFor: []string{"Job/tenant/migrate"}},
+				Conflicts:   []string{"ConfigMap/tenant/cm is managed by NamespaceConfig/a"},
+				Failures:    []string{"ConfigMap/tenant/a"},
+			},
+			ready:       testCondition{status: metav1.ConditionFalse, reason: ConflictReason, message: "ConfigMap/tenant/cm is managed by NamespaceConfig/a"},
+			progressing: testCondition{status: metav1.ConditionTrue, reason: WaitingForWaveReason, message: "wave 1 is waiting for Job/tenant/migrate"},
+			degraded:    testCondition{status: metav1.ConditionTrue, reason: ConflictReason, message: "ConfigMap/tenant/cm is managed by NamespaceConfig/a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions := SetFailedConditions([]metav1.Condition{}, 1, RenderFailedReason, errors.New("unable to render"))
+			conditions = SetReconciledConditions(conditions, 2, tt.outcome)
+			if len(conditions) != 3 {
+				t.Fatalf("conditions = %v, want %v conditions", conditions, 3)
+			}
+			checkCondition(t, conditions, ReadyCondition, tt.ready)
+			checkCondition(t, conditions, ProgressingCondition, tt.progressing)
+			checkCondition(t, conditions, DegradedCondition, tt.degraded)
+		})
+	}
+}
+
+func TestSetFailedConditions(t *testing.T) {
+	conditions := SetReconciledConditions([]metav1.Condition{}, 1, ReconcileOutcome{})
+	conditions = SetFailedConditions(conditions, 2, SelectorInvalidReason, errors.New("invalid selector"))
+	if len(conditions) != 3 {
+		t.Fatalf("conditions = %v, want %v conditions", conditions, 3)
+	}
+	checkCondition(t, conditions, ReadyCondition, testCondition{status: metav1.ConditionFalse, reason: SelectorInvalidReason, message: "invalid selector"})
+	checkCondition(t, conditions, ProgressingCondition, testCondition{status: metav1.ConditionFalse, reason: SelectorInvalidReason})
+	checkCondition(t, conditions, DegradedCondition, testCondition{status: metav1.ConditionTrue, reason: SelectorInvalidReason, message: "invalid selector"})
+}
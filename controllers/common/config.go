@@ -0,0 +1,101 @@
+package common
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/scylladb/go-set/strset"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// copyingConfig is a config copying objects into the selected namespaces
+type copyingConfig interface {
+	GetCopyFrom() []redhatcopv1alpha1.CopySource
+}
+
+// ConfigName returns the name identifying a config in the metrics, the spans and the events, prefixed by its namespace for the namespaced configs
+func ConfigName(config client.Object) string {
+	if config.GetNamespace() != "" {
+		return client.ObjectKeyFromObject(config).String()
+	}
+	return config.GetName()
+}
+
+// IsInitialized adds the default excluded paths to the templates of a config, and adds the given finalizer when the config generates resources or removes it when it does not.
+// It returns false when the config has been modified and must be updated.
+func IsInitialized(config EnforcedConfig, finalizer string) bool {
+	needsUpdate := true
+	templates := config.GetTemplates()
+	for i := range templates {
+		currentSet := strset.New(templates[i].ExcludedPaths...)
+		if !currentSet.IsEqual(strset.Union(DefaultExcludedPathsSet, currentSet)) {
+			templates[i].ExcludedPaths = strset.Union(DefaultExcludedPathsSet, currentSet).List()
+			needsUpdate = false
+		}
+	}
+	generates := len(templates) > 0 || len(config.GetTemplateRefs()) > 0
+	if copying, ok := config.(copyingConfig); ok {
+		generates = generates || len(copying.GetCopyFrom()) > 0
+	}
+	if generates && !util.HasFinalizer(config, finalizer) {
+		util.AddFinalizer(config, finalizer)
+		needsUpdate = false
+	}
+	if !generates && util.HasFinalizer(config, finalizer) {
+		util.RemoveFinalizer(config, finalizer)
+		needsUpdate = false
+	}
+	return needsUpdate
+}
+
+// FindConfigsReferencing returns a function mapping a ConfigMap or Secret of the given kind to the configs of the given list type referencing it in valuesFrom.
+// The configs are listed in the namespace of the ConfigMap or Secret when namespaced is set.
+func FindConfigsReferencing(reader client.Reader, list client.ObjectList, kind string, namespaced bool, log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, a client.Object) []reconcile.Request {
+		listOptions := &client.ListOptions{}
+		if namespaced {
+			listOptions.Namespace = a.GetNamespace()
+		}
+		return findConfigs(ctx, reader, list.DeepCopyObject().(client.ObjectList), listOptions, log, func(config EnforcedConfig) bool {
+			return IsReferenced(config.GetValuesFrom(), kind, a)
+		})
+	}
+}
+
+// FindConfigsFromConfigTemplate returns a function mapping a ConfigTemplate to the configs of the given list type referencing it
+func FindConfigsFromConfigTemplate(reader client.Reader, list client.ObjectList, log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, a client.Object) []reconcile.Request {
+		return findConfigs(ctx, reader, list.DeepCopyObject().(client.ObjectList), &client.ListOptions{}, log, func(config EnforcedConfig) bool {
+			return IsTemplateReferenced(config.GetTemplateRefs(), a.GetName())
+		})
+	}
+}
+
+// findConfigs lists the configs and returns the requests to reconcile the ones matching
+func findConfigs(ctx context.Context, reader client.Reader, list client.ObjectList, listOptions *client.ListOptions, log logr.Logger, matches func(EnforcedConfig) bool) []reconcile.Request {
+	reconcileRequests := []reconcile.Request{}
+	err := reader.List(ctx, list, listOptions)
+	if err != nil {
+		log.Error(err, "unable to get all the configs of", "list", list.GetObjectKind().GroupVersionKind().Kind)
+		return []reconcile.Request{}
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		log.Error(err, "unable to extract the configs of", "list", list.GetObjectKind().GroupVersionKind().Kind)
+		return []reconcile.Request{}
+	}
+	for _, item := range items {
+		config, ok := item.(EnforcedConfig)
+		if ok && matches(config) {
+			reconcileRequests = append(reconcileRequests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(config),
+			})
+		}
+	}
+	return reconcileRequests
+}
@@ -0,0 +1,258 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/scylladb/go-set/strset"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testFinalizer = "test-controller"
+
+// newTestScheme returns a scheme with the kubernetes kinds and the config kinds
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	testScheme := runtime.NewScheme()
+	if err := scheme.AddToScheme(testScheme); err != nil {
+		t.Fatalf("unable to add the kubernetes kinds to the scheme: %v", err)
+	}
+	if err := redhatcopv1alpha1.AddToScheme(testScheme); err != nil {
+		t.Fatalf("unable to add the config kinds to the scheme: %v", err)
+	}
+	return testScheme
+}
+
+func TestConfigName(t *testing.T) {
+	tests := []struct {
+		name   string
+		config client.Object
+		want   string
+	}{
+		{
+			name:   "cluster scoped config",
+			config: &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}},
+			want:   "tenant",
+		},
+		{
+			name:   "namespaced config",
+			config: &redhatcopv1alpha1.NamespacedResourceConfig{ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "team-a"}},
+			want:   "team-a/quota",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConfigName(tt.config); got != tt.want {
+				t.Errorf("ConfigName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsInitialized(t *testing.T) {
+	template := redhatcopv1alpha1.ResourceTemplate{}
+	template.ObjectTemplate = "kind: ConfigMap"
+	templateWithDefaults := redhatcopv1alpha1.ResourceTemplate{}
+	templateWithDefaults.ObjectTemplate = "kind: ConfigMap"
+	templateWithDefaults.ExcludedPaths = DefaultExcludedPathsSet.List()
+	tests := []struct {
+		name        string
+		config      EnforcedConfig
+		initialized bool
+		finalizer   bool
+	}{
+		{
+			name:        "config without templates",
+			config:      &redhatcopv1alpha1.UserConfig{},
+			initialized: true,
+			finalizer:   false,
+		},
+		{
+			name: "config without templates and with the finalizer",
+			config: &redhatcopv1alpha1.UserConfig{
+				ObjectMeta: metav1.ObjectMeta{Finalizers: []string{testFinalizer}},
+			},
+			initialized: false,
+			finalizer:   false,
+		},
+		{
+			name: "template without the default excluded paths",
+			config: &redhatcopv1alpha1.GroupConfig{
+				ObjectMeta: metav1.ObjectMeta{Finalizers: []string{testFinalizer}},
+				Spec:       redhatcopv1alpha1.GroupConfigSpec{Templates: []redhatcopv1alpha1.ResourceTemplate{template}},
+			},
+			initialized: false,
+			finalizer:   true,
+		},
+		{
+			name: "template without the finalizer",
+			config: &redhatcopv1alpha1.ResourceConfig{
+				Spec: redhatcopv1alpha1.ResourceConfigSpec{Templates: []redhatcopv1alpha1.ResourceTemplate{templateWithDefaults}},
+			},
+			initialized: false,
+			finalizer:   true,
+		},
+		{
+			name: "initialized template",
+			config: &redhatcopv1alpha1.ResourceConfig{
+				ObjectMeta: metav1.ObjectMeta{Finalizers: []string{testFinalizer}},
+				Spec:       redhatcopv1alpha1.ResourceConfigSpec{Templates: []redhatcopv1alpha1.ResourceTemplate{templateWithDefaults}},
+			},
+			initialized: true,
+			finalizer:   true,
+		},
+		{
+			name: "template reference without the finalizer",
+			config: &redhatcopv1alpha1.NamespacedResourceConfig{
+				Spec: redhatcopv1alpha1.NamespacedResourceConfigSpec{TemplateRefs: []redhatcopv1alpha1.TemplateReference{{Name: "quota"}}},
+			},
+			initialized: false,
+			finalizer:   true,
+		},
+		{
+			name: "copy without the finalizer",
+			config: &redhatcopv1alpha1.NamespaceConfig{
+				Spec: redhatcopv1alpha1.NamespaceConfigSpec{CopyFrom: []redhatcopv1alpha1.CopySource{{Namespace: "shared", Name: "ca"}}},
+			},
+			initialized: false,
+			finalizer:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsInitialized(tt.config, testFinalizer); got != tt.initialized {
+				t.Errorf("IsInitialized() = %v, want %v", got, tt.initialized)
+			}
+			if got := util.HasFinalizer(tt.config, testFinalizer); got != tt.finalizer {
+				t.Errorf("HasFinalizer() = %v, want %v", got, tt.finalizer)
+			}
+			for _, template := range tt.config.GetTemplates() {
+				if !strset.New(template.ExcludedPaths...).IsSubset(DefaultExcludedPathsSet) {
+					t.Errorf("ExcludedPaths = %v, want a superset of %v", template.ExcludedPaths, DefaultExcludedPathsSet.List())
+				}
+			}
+		})
+	}
+}
+
+func TestFindConfigsReferencing(t *testing.T) {
+	configs := []client.Object{
+		&redhatcopv1alpha1.NamespacedResourceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "configmap", Namespace: "team-a"},
+			Spec: redhatcopv1alpha1.NamespacedResourceConfigSpec{ValuesFrom: []redhatcopv1alpha1.ValuesReference{
+				{Name: "values", Namespace: "team-a"},
+			}},
+		},
+		&redhatcopv1alpha1.NamespacedResourceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "team-a"},
+			Spec: redhatcopv1alpha1.NamespacedResourceConfigSpec{ValuesFrom: []redhatcopv1alpha1.ValuesReference{
+				{Kind: SecretKind, Name: "values", Namespace: "team-a"},
+			}},
+		},
+		&redhatcopv1alpha1.NamespacedResourceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-namespace", Namespace: "team-b"},
+			Spec: redhatcopv1alpha1.NamespacedResourceConfigSpec{ValuesFrom: []redhatcopv1alpha1.ValuesReference{
+				{Name: "values", Namespace: "team-a"},
+			}},
+		},
+		&redhatcopv1alpha1.NamespacedResourceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-values", Namespace: "team-a"},
+		},
+	}
+	reader := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(configs...).Build()
+	values := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "values", Namespace: "team-a"}}
+	tests := []struct {
+		name       string
+		kind       string
+		namespaced bool
+		want       []string
+	}{
+		{
+			name:       "configs in any namespace",
+			kind:       ConfigMapKind,
+			namespaced: false,
+			want:       []string{"team-a/configmap", "team-b/other-namespace"},
+		},
+		{
+			name:       "configs in the namespace of the object",
+			kind:       ConfigMapKind,
+			namespaced: true,
+			want:       []string{"team-a/configmap"},
+		},
+		{
+			name:       "configs referencing a secret",
+			kind:       SecretKind,
+			namespaced: true,
+			want:       []string{"team-a/secret"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapFunc := FindConfigsReferencing(reader, &redhatcopv1alpha1.NamespacedResourceConfigList{}, tt.kind, tt.namespaced, logr.Discard())
+			got := []string{}
+			for _, request := range mapFunc(context.TODO(), values) {
+				got = append(got, request.String())
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindConfigsReferencing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindConfigsFromConfigTemplate(t *testing.T) {
+	configs := []client.Object{
+		&redhatcopv1alpha1.UserConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "home"},
+			Spec: redhatcopv1alpha1.UserConfigSpec{TemplateRefs: []redhatcopv1alpha1.TemplateReference{
+				{Name: "sandbox"},
+			}},
+		},
+		&redhatcopv1alpha1.UserConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "quota"},
+			Spec: redhatcopv1alpha1.UserConfigSpec{TemplateRefs: []redhatcopv1alpha1.TemplateReference{
+				{Name: "quota"},
+			}},
+		},
+	}
+	reader := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(configs...).Build()
+	tests := []struct {
+		name     string
+		template string
+		want     []string
+	}{
+		{
+			name:     "referenced template",
+			template: "sandbox",
+			want:     []string{"/home"},
+		},
+		{
+			name:     "template not referenced",
+			template: "network",
+			want:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapFunc := FindConfigsFromConfigTemplate(reader, &redhatcopv1alpha1.UserConfigList{}, logr.Discard())
+			configTemplate := &redhatcopv1alpha1.ConfigTemplate{ObjectMeta: metav1.ObjectMeta{Name: tt.template}}
+			got := []string{}
+			for _, request := range mapFunc(context.TODO(), configTemplate) {
+				got = append(got, request.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindConfigsFromConfigTemplate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// copiedFields are the fields of the ConfigMaps and Secrets holding their data, which is filtered by the keys of a CopySource
+var copiedFields = []string{"data", "binaryData"}
+
+// CopiedSource is an object referenced by a CopySource, read once and copied into each selected namespace
+type CopiedSource struct {
+	redhatcopv1alpha1.CopySource
+	// Object is the content of the copied object
+	Object map[string]interface{}
+}
+
+// GetCopySources reads the objects referenced by copyFrom, the missing optional objects are left out
+func GetCopySources(context context.Context, reader client.Reader, copyFrom []redhatcopv1alpha1.CopySource) ([]CopiedSource, error) {
+	sources := []CopiedSource{}
+	for _, copySource := range copyFrom {
+		var obj client.Object = &corev1.ConfigMap{}
+		if copySource.Kind == SecretKind {
+			obj = &corev1.Secret{}
+		}
+		err := reader.Get(context, types.NamespacedName{Namespace: copySource.Namespace, Name: copySource.Name}, obj)
+		if err != nil {
+			if apierrors.IsNotFound(err) && copySource.Optional {
+				continue
+			}
+			return []CopiedSource{}, err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return []CopiedSource{}, err
+		}
+		sources = append(sources, CopiedSource{
+			CopySource: copySource,
+			Object:     content,
+		})
+	}
+	return sources, nil
+}
+
+// GetCopySourceReferences returns the references of the objects referenced by copyFrom, including the missing ones, which are watched so that the copies follow them
+func GetCopySourceReferences(copyFrom []redhatcopv1alpha1.CopySource) []LookupReference {
+	references := []LookupReference{}
+	for _, copySource := range copyFrom {
+		references = append(references, LookupReference{
+			GroupVersionKind: corev1.SchemeGroupVersion.WithKind(getCopyKind(copySource)),
+			Namespace:        copySource.Namespace,
+			Name:             copySource.Name,
+		})
+	}
+	return references
+}
+
+// CopyInto returns the copies of the sources in the given namespace. A source is not copied onto itself.
+// The copies keep only the data, filtered and renamed according to the keys of the sources, and the type of the Secrets.
+func CopyInto(sources []CopiedSource, namespace string) ([]RenderedResource, error) {
+	resources := []RenderedResource{}
+	for _, source := range sources {
+		name := source.TargetName
+		if name == "" {
+			name = source.Name
+		}
+		if namespace == source.Namespace && name == source.Name {
+			continue
+		}
+		copied := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		copied.SetAPIVersion("v1")
+		copied.SetKind(getCopyKind(source.CopySource))
+		copied.SetNamespace(namespace)
+		copied.SetName(name)
+		if secretType, ok := source.Object["type"]; ok {
+			copied.Object["type"] = secretType
+		}
+		err := copyData(source, copied)
+		if err != nil {
+			return []RenderedResource{}, err
+		}
+		resources = append(resources, RenderedResource{
+			LockedResource: lockedresource.LockedResource{
+				Unstructured:  *copied,
+				ExcludedPaths: DefaultExcludedPaths,
+			},
+			AdoptionPolicy: redhatcopv1alpha1.AdoptionPolicyAdopt,
+			Mode:           redhatcopv1alpha1.TemplateModeEnforce,
+		})
+	}
+	return resources, nil
+}
+
+// copyData copies the data of a source into a copy, all of it or only the keys of the source
+func copyData(source CopiedSource, copied *unstructured.Unstructured) error {
+	if len(source.Keys) == 0 {
+		for _, field := range copiedFields {
+			if data, ok := source.Object[field]; ok {
+				copied.Object[field] = runtime.DeepCopyJSONValue(data)
+			}
+		}
+		return nil
+	}
+	for _, key := range source.Keys {
+		to := key.To
+		if to == "" {
+			to = key.Key
+		}
+		found := false
+		for _, field := range copiedFields {
+			data, ok := source.Object[field].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			value, ok := data[key.Key]
+			if !ok {
+				continue
+			}
+			err := unstructured.SetNestedField(copied.Object, runtime.DeepCopyJSONValue(value), field, to)
+			if err != nil {
+				return err
+			}
+			found = true
+			break
+		}
+		if !found {
+			return fmt.Errorf("key %s not found in %s %s/%s", key.Key, getCopyKind(source.CopySource), source.Namespace, source.Name)
+		}
+	}
+	return nil
+}
+
+func getCopyKind(copySource redhatcopv1alpha1.CopySource) string {
+	if copySource.Kind == "" {
+		return ConfigMapKind
+	}
+	return copySource.Kind
+}
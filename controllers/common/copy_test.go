@@ -0,0 +1,166 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCopyInto(t *testing.T) {
+	configMap := map[string]interface{}{
+		"metadata":   map[string]interface{}{"namespace": "shared", "name": "settings", "labels": map[string]interface{}{"app": "a"}},
+		"data":       map[string]interface{}{"a": "1", "b": "2"},
+		"binaryData": map[string]interface{}{"c": "Mw=="},
+	}
+	secret := map[string]interface{}{
+		"metadata": map[string]interface{}{"namespace": "shared", "name": "pull-secret"},
+		"type":     "kubernetes.io/dockerconfigjson",
+		"data":     map[string]interface{}{".dockerconfigjson": "e30="},
+	}
+	tests := []struct {
+		name     string
+		sources  []CopiedSource
+		expected []*unstructured.Unstructured
+		errors   bool
+	}{
+		{
+			name:    "all the data",
+			sources: []CopiedSource{{CopySource: redhatcopv1alpha1.CopySource{Namespace: "shared", Name: "settings"}, Object: configMap}},
+			expected: []*unstructured.Unstructured{
+				newTestObject("v1", "ConfigMap", "team", "settings", map[string]interface{}{
+					"data":       map[string]interface{}{"a": "1", "b": "2"},
+					"binaryData": map[string]interface{}{"c": "Mw=="},
+				}),
+			},
+		},
+		{
+			name:    "selected and renamed keys",
+			sources: []CopiedSource{{CopySource: redhatcopv1alpha1.CopySource{Namespace: "shared", Name: "settings", TargetName: "copy", Keys: []redhatcopv1alpha1.CopiedKey{{Key: "a"}, {Key: "c", To: "d"}}}, Object: configMap}},
+			expected: []*unstructured.Unstructured{
+				newTestObject("v1", "ConfigMap", "team", "copy", map[string]interface{}{
+					"data":       map[string]interface{}{"a": "1"},
+					"binaryData": map[string]interface{}{"d": "Mw=="},
+				}),
+			},
+		},
+		{
+			name:    "secret type",
+			sources: []CopiedSource{{CopySource: redhatcopv1alpha1.CopySource{Kind: SecretKind, Namespace: "shared", Name: "pull-secret"}, Object: secret}},
+			expected: []*unstructured.Unstructured{
+				newTestObject("v1", "Secret", "team", "pull-secret", map[string]interface{}{
+					"type": "kubernetes.io/dockerconfigjson",
+					"data": map[string]interface{}{".dockerconfigjson": "e30="},
+				}),
+			},
+		},
+		{
+			name: "not copied onto itself",
+			sources: []CopiedSource{
+				{CopySource: redhatcopv1alpha1.CopySource{Namespace: "team", Name: "settings"}, Object: configMap},
+				{CopySource: redhatcopv1alpha1.CopySource{Namespace: "team", Name: "settings", TargetName: "copy"}, Object: configMap},
+			},
+			expected: []*unstructured.Unstructured{
+				newTestObject("v1", "ConfigMap", "team", "copy", map[string]interface{}{
+					"data":       map[string]interface{}{"a": "1", "b": "2"},
+					"binaryData": map[string]interface{}{"c": "Mw=="},
+				}),
+			},
+		},
+		{
+			name:    "missing key",
+			sources: []CopiedSource{{CopySource: redhatcopv1alpha1.CopySource{Namespace: "shared", Name: "settings", Keys: []redhatcopv1alpha1.CopiedKey{{Key: "z"}}}, Object: configMap}},
+			errors:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resources, err := CopyInto(test.sources, "team")
+			if test.errors {
+				if err == nil {
+					t.Fatalf("expected an error, found none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			copies := []*unstructured.Unstructured{}
+			for i := range resources {
+				if resources[i].Mode != redhatcopv1alpha1.TemplateModeEnforce || resources[i].AdoptionPolicy != redhatcopv1alpha1.AdoptionPolicyAdopt {
+					t.Errorf("expected the copies to be enforced and adopted, found %s and %s", resources[i].Mode, resources[i].AdoptionPolicy)
+				}
+				copies = append(copies, &resources[i].Unstructured)
+			}
+			if !reflect.DeepEqual(copies, test.expected) {
+				t.Errorf("expected copies %v, found %v", test.expected, copies)
+			}
+		})
+	}
+	if configMap["data"].(map[string]interface{})["a"] != "1" {
+		t.Errorf("the source has been modified")
+	}
+}
+
+func TestGetCopySources(t *testing.T) {
+	reader := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "settings"},
+		Data:       map[string]string{"a": "1"},
+	}).Build()
+	tests := []struct {
+		name     string
+		copyFrom []redhatcopv1alpha1.CopySource
+		expected []string
+		errors   bool
+	}{
+		{
+			name:     "existing source",
+			copyFrom: []redhatcopv1alpha1.CopySource{{Namespace: "shared", Name: "settings"}},
+			expected: []string{"shared/settings"},
+		},
+		{
+			name:     "missing optional source",
+			copyFrom: []redhatcopv1alpha1.CopySource{{Namespace: "shared", Name: "missing", Optional: true}, {Namespace: "shared", Name: "settings"}},
+			expected: []string{"shared/settings"},
+		},
+		{
+			name:     "missing source",
+			copyFrom: []redhatcopv1alpha1.CopySource{{Namespace: "shared", Name: "missing"}},
+			errors:   true,
+		},
+		{
+			name:     "secret with the name of a config map",
+			copyFrom: []redhatcopv1alpha1.CopySource{{Kind: SecretKind, Namespace: "shared", Name: "settings"}},
+			errors:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sources, err := GetCopySources(context.TODO(), reader, test.copyFrom)
+			if test.errors {
+				if err == nil {
+					t.Fatalf("expected an error, found none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			found := []string{}
+			for _, source := range sources {
+				found = append(found, source.Namespace+"/"+source.Name)
+				if source.Object["data"] == nil {
+					t.Errorf("expected the data of %s/%s to be read", source.Namespace, source.Name)
+				}
+			}
+			if !reflect.DeepEqual(found, test.expected) {
+				t.Errorf("expected sources %v, found %v", test.expected, found)
+			}
+		})
+	}
+}
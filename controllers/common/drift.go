@@ -0,0 +1,179 @@
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MaxReportedDrifts is the maximum number of drifted objects reported in the status of a config, to keep it within the size limits of an object
+const MaxReportedDrifts = 100
+
+// maxDiffLines is the maximum number of drifted fields reported for an object
+const maxDiffLines = 10
+
+// maxValueLength is the maximum length of the values reported in a diff
+const maxValueLength = 80
+
+// sensitiveFields are the top level fields whose values are never reported in a diff, because they usually hold credentials
+var sensitiveFields = []string{"data", "stringData", "binaryData"}
+
+// ComputeDrifts compares the resources with the live objects and returns the objects that drifted, sorted by object.
+// A live object drifted when a generated field, except for the excluded paths, is missing or has a different value, fields that are only in the live object are ignored.
+// Objects generated by CreateOnly templates drift only when they do not exist, objects generated by Patch templates drift only when they exist and differ.
+func ComputeDrifts(context context.Context, reader client.Reader, resources []RenderedResource) ([]redhatcopv1alpha1.DriftStatus, error) {
+	drifts := []redhatcopv1alpha1.DriftStatus{}
+	seen := map[objectReference]bool{}
+	for _, resource := range resources {
+		key := getObjectReference(&resource.Unstructured)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		liveObj, err := getLiveObject(context, reader, &resource.Unstructured)
+		if err != nil {
+			return []redhatcopv1alpha1.DriftStatus{}, err
+		}
+		if liveObj == nil {
+			if resource.Mode != redhatcopv1alpha1.TemplateModePatch {
+				drifts = append(drifts, redhatcopv1alpha1.DriftStatus{
+					Object: key.String(),
+					Diff:   "object does not exist",
+				})
+			}
+			continue
+		}
+		if resource.Mode == redhatcopv1alpha1.TemplateModeCreateOnly {
+			continue
+		}
+		desired, err := lockedresource.FilterOutPaths(&resource.Unstructured, resource.ExcludedPaths)
+		if err != nil {
+			return []redhatcopv1alpha1.DriftStatus{}, err
+		}
+		live, err := lockedresource.FilterOutPaths(liveObj, resource.ExcludedPaths)
+		if err != nil {
+			return []redhatcopv1alpha1.DriftStatus{}, err
+		}
+		if isSecret(&resource.Unstructured) {
+			desired = foldStringData(desired)
+		}
+		lines := []string{}
+		compareFields("", desired.Object, live.Object, isSecret(&resource.Unstructured), &lines)
+		if len(lines) > 0 {
+			if len(lines) > maxDiffLines {
+				lines = append(lines[:maxDiffLines], "... "+strconv.Itoa(len(lines)-maxDiffLines)+" more fields")
+			}
+			drifts = append(drifts, redhatcopv1alpha1.DriftStatus{
+				Object: key.String(),
+				Diff:   strings.Join(lines, "\n"),
+			})
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Object < drifts[j].Object })
+	return drifts, nil
+}
+
+// compareFields appends a line to the diff for each field of desired that is missing or different in live. Lists of the same length are compared element by element.
+// The values are not reported when redact is set or when the field is one of the sensitiveFields, the diff only tells that the field differs.
+func compareFields(path string, desired interface{}, live interface{}, redact bool, lines *[]string) {
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		if liveValue, ok := live.(map[string]interface{}); ok {
+			keys := []string{}
+			for key := range desiredValue {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				compareFields(path+"."+key, desiredValue[key], liveValue[key], redact, lines)
+			}
+			return
+		}
+	case []interface{}:
+		if liveValue, ok := live.([]interface{}); ok && len(liveValue) == len(desiredValue) {
+			for i := range desiredValue {
+				compareFields(path+"["+strconv.Itoa(i)+"]", desiredValue[i], liveValue[i], redact, lines)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(desired, live) {
+		if redact || isSensitivePath(path) {
+			*lines = append(*lines, path+": differs")
+			return
+		}
+		*lines = append(*lines, path+": expected "+formatValue(desired)+", found "+formatValue(live))
+	}
+}
+
+// foldStringData returns a copy of a secret whose stringData is merged into its data, base64 encoded, the way the API server stores it, so that it can be compared with the live secret.
+// The stringData values take precedence over the data values, like they do when the secret is written.
+func foldStringData(secret *unstructured.Unstructured) *unstructured.Unstructured {
+	stringData, found, err := unstructured.NestedStringMap(secret.Object, "stringData")
+	if !found || err != nil {
+		return secret
+	}
+	folded := secret.DeepCopy()
+	data, _, _ := unstructured.NestedMap(folded.Object, "data")
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	for key, value := range stringData {
+		data[key] = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	unstructured.RemoveNestedField(folded.Object, "stringData")
+	_ = unstructured.SetNestedMap(folded.Object, data, "data")
+	return folded
+}
+
+func isSecret(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	return gvk.Group == "" && gvk.Kind == SecretKind
+}
+
+// isSensitivePath returns whether a path is one of the sensitiveFields or is under one of them
+func isSensitivePath(path string) bool {
+	for _, field := range sensitiveFields {
+		prefix := "." + field
+		if path == prefix || strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "nothing"
+	}
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return "unknown"
+	}
+	if len(bytes) > maxValueLength {
+		return string(bytes[:maxValueLength]) + "..."
+	}
+	return string(bytes)
+}
+
+// GetReferences returns the references to the objects of the resources, so that they can be watched
+func GetReferences(resources []RenderedResource) []LookupReference {
+	references := []LookupReference{}
+	for _, resource := range resources {
+		references = append(references, LookupReference{
+			GroupVersionKind: resource.GroupVersionKind(),
+			Namespace:        resource.GetNamespace(),
+			Name:             resource.GetName(),
+		})
+	}
+	return references
+}
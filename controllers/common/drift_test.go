@@ -0,0 +1,144 @@
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCompareFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired map[string]interface{}
+		live    map[string]interface{}
+		redact  bool
+		lines   []string
+	}{
+		{
+			name:    "equal",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2), "paused": false}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2), "paused": false}},
+			lines:   []string{},
+		},
+		{
+			name:    "live only fields are ignored",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2), "paused": true}, "status": map[string]interface{}{}},
+			lines:   []string{},
+		},
+		{
+			name:    "different and missing fields in order",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2), "paused": false}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(3)}},
+			lines:   []string{".spec.paused: expected false, found nothing", ".spec.replicas: expected 2, found 3"},
+		},
+		{
+			name:    "lists of the same length are compared by element",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"ports": []interface{}{map[string]interface{}{"port": int64(80)}, map[string]interface{}{"port": int64(443)}}}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{"ports": []interface{}{map[string]interface{}{"port": int64(80), "protocol": "TCP"}, map[string]interface{}{"port": int64(8443)}}}},
+			lines:   []string{".spec.ports[1].port: expected 443, found 8443"},
+		},
+		{
+			name:    "lists of different lengths are compared as a whole",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"items": []interface{}{"a"}}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{"items": []interface{}{"a", "b"}}},
+			lines:   []string{`.spec.items: expected ["a"], found ["a","b"]`},
+		},
+		{
+			name:    "map replaced by a value",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"a": "1"}},
+			live:    map[string]interface{}{"spec": "none"},
+			lines:   []string{`.spec: expected {"a":"1"}, found "none"`},
+		},
+		{
+			name:    "long values are truncated",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"text": strings.Repeat("a", 100)}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{"text": "b"}},
+			lines:   []string{`.spec.text: expected "` + strings.Repeat("a", 79) + `..., found "b"`},
+		},
+		{
+			name:    "sensitive fields are not reported",
+			desired: map[string]interface{}{"data": map[string]interface{}{"password": "secret"}, "stringData": "secret", "binaryData": []interface{}{"secret"}, "metadata": map[string]interface{}{"name": "a"}},
+			live:    map[string]interface{}{"data": map[string]interface{}{"password": "other"}, "metadata": map[string]interface{}{"name": "b"}},
+			lines:   []string{".binaryData: differs", ".data.password: differs", `.metadata.name: expected "a", found "b"`, ".stringData: differs"},
+		},
+		{
+			name:    "fields prefixed with a sensitive field are reported",
+			desired: map[string]interface{}{"dataSource": "a"},
+			live:    map[string]interface{}{"dataSource": "b"},
+			lines:   []string{`.dataSource: expected "a", found "b"`},
+		},
+		{
+			name:    "redacted objects",
+			desired: map[string]interface{}{"type": "Opaque", "metadata": map[string]interface{}{"name": "a"}},
+			live:    map[string]interface{}{"type": "kubernetes.io/tls", "metadata": map[string]interface{}{"name": "a"}},
+			redact:  true,
+			lines:   []string{".type: differs"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines := []string{}
+			compareFields("", test.desired, test.live, test.redact, &lines)
+			if !reflect.DeepEqual(lines, test.lines) {
+				t.Errorf("expected diff %q, found %q", test.lines, lines)
+			}
+		})
+	}
+}
+
+func TestComputeDriftsOfSecrets(t *testing.T) {
+	secret := func(fields map[string]interface{}) RenderedResource {
+		return RenderedResource{LockedResource: lockedresource.LockedResource{Unstructured: *newTestObject("v1", "Secret", "ns", "credentials", fields)}}
+	}
+	liveSecret := newTestObject("v1", "Secret", "ns", "credentials", map[string]interface{}{
+		"data": map[string]interface{}{"username": base64.StdEncoding.EncodeToString([]byte("admin")), "password": base64.StdEncoding.EncodeToString([]byte("s3cr3t"))},
+	})
+	tests := []struct {
+		name     string
+		resource RenderedResource
+		drifts   []redhatcopv1alpha1.DriftStatus
+	}{
+		{
+			name:     "same data",
+			resource: secret(map[string]interface{}{"data": map[string]interface{}{"password": base64.StdEncoding.EncodeToString([]byte("s3cr3t"))}}),
+			drifts:   []redhatcopv1alpha1.DriftStatus{},
+		},
+		{
+			name:     "same string data",
+			resource: secret(map[string]interface{}{"stringData": map[string]interface{}{"username": "admin", "password": "s3cr3t"}}),
+			drifts:   []redhatcopv1alpha1.DriftStatus{},
+		},
+		{
+			name: "string data and data",
+			resource: secret(map[string]interface{}{
+				"data":       map[string]interface{}{"username": base64.StdEncoding.EncodeToString([]byte("root"))},
+				"stringData": map[string]interface{}{"username": "admin"},
+			}),
+			drifts: []redhatcopv1alpha1.DriftStatus{},
+		},
+		{
+			name:     "different string data",
+			resource: secret(map[string]interface{}{"stringData": map[string]interface{}{"password": "changed"}}),
+			drifts:   []redhatcopv1alpha1.DriftStatus{{Object: "Secret/ns/credentials", Diff: ".data.password: differs"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := fake.NewClientBuilder().WithObjects(liveSecret.DeepCopy()).Build()
+			drifts, err := ComputeDrifts(context.TODO(), reader, []RenderedResource{test.resource})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(drifts, test.drifts) {
+				t.Errorf("expected drifts %v, found %v", test.drifts, drifts)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package common
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	apis "github.com/redhat-cop/operator-utils/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedpatch"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// EnforcedConfig is a config whose generated resources are enforced by an Enforcer, implemented by all the config kinds
+type EnforcedConfig interface {
+	client.Object
+	apis.EnforcingReconcileStatusAware
+	GetEnforcementStatus() *redhatcopv1alpha1.EnforcementStatus
+	SetObservedGeneration(generation int64)
+	GetTemplates() []redhatcopv1alpha1.ResourceTemplate
+	GetTemplateRefs() []redhatcopv1alpha1.TemplateReference
+	GetValuesFrom() []redhatcopv1alpha1.ValuesReference
+}
+
+// Target is an object selected by a config, for which the templates of the config are processed
+type Target struct {
+	// Kind is the kind of the selected object
+	Kind string
+	// Object is the selected object, matched by the when clauses of the templates and recorded in the ownership stamp of the generated resources
+	Object client.Object
+	// Data is the data used to process the templates for the selected object, the parameters of each template set are added to it
+	Data TemplateData
+	// Copies are the objects copied for the selected object, enforced together with the generated ones
+	Copies []RenderedResource
+}
+
+// Enforcement describes how the resources generated by a config are enforced
+type Enforcement struct {
+	// Config is the config generating the resources
+	Config EnforcedConfig
+	// Mode decides whether the resources are enforced or only compared with the live objects
+	Mode redhatcopv1alpha1.EnforcementMode
+	// ClaimOptions are the options with which the config claims the ownership of the resources
+	ClaimOptions ClaimOptions
+	// ServerSideApply when set, the resources are applied with server side apply instead of being enforced by the locked resource controllers
+	ServerSideApply *redhatcopv1alpha1.ServerSideApply
+	// Namespace when set, the resources are generated in this namespace only
+	Namespace string
+	// Client is the client reading, creating, stamping and applying the objects, it must not read from a cache. The client and the API reader of the reconciler when nil
+	Client client.Client
+	// RestConfig is the configuration used by the templates to look up objects and by the locked resource controllers enforcing the objects, the one of the reconciler when nil
+	RestConfig *rest.Config
+}
+
+// Enforcer runs the steps shared by all the configs once they have selected their targets: it renders the templates for the targets, then either reports the drifts of the rendered resources or claims them, gates them by wave and enforces them, and finally reports the outcome in the status and the conditions of the config.
+type Enforcer struct {
+	kind          string
+	reconciler    *lockedresourcecontroller.EnforcingReconciler
+	ownership     *OwnershipRegistry
+	applier       *Applier
+	lookupWatcher *LookupWatcher
+	log           logr.Logger
+}
+
+// NewEnforcer creates a new Enforcer for the configs of the given kind reconciled by the given reconciler
+func NewEnforcer(kind string, reconciler *lockedresourcecontroller.EnforcingReconciler, ownership *OwnershipRegistry, applier *Applier, lookupWatcher *LookupWatcher, log logr.Logger) *Enforcer {
+	return &Enforcer{
+		kind:          kind,
+		reconciler:    reconciler,
+		ownership:     ownership,
+		applier:       applier,
+		lookupWatcher: lookupWatcher,
+		log:           log,
+	}
+}
+
+// Render processes the templates of the template sets for each target and returns the rendered resources, stamped with the ownership of the config, together with the objects looked up by the templates
+func (e *Enforcer) Render(context context.Context, enforcement Enforcement, targets []Target, templateSets []TemplateSet) ([]RenderedResource, []LookupReference, error) {
+	name := ConfigName(enforcement.Config)
+	resources := []RenderedResource{}
+	lookups := []LookupReference{}
+	for _, target := range targets {
+		_, span := StartSpan(context, "getResourceList", e.kind, name, TargetKindAttribute.String(target.Kind), TargetNameAttribute.String(target.Object.GetName()))
+		stamp := NewOwnershipStamp(e.kind, enforcement.Config, target.Kind, target.Object)
+		for _, templateSet := range templateSets {
+			templates, indexes, err := SelectTemplates(templateSet.Templates, target.Object)
+			if err != nil {
+				e.log.Error(err, "unable to evaluate the when clauses of", "templates", templateSet.Templates, "for", target.Object.GetName())
+				EndSpan(span, err)
+				TemplateRenderErrors.WithLabelValues(e.kind, name).Inc()
+				return []RenderedResource{}, []LookupReference{}, err
+			}
+			lrs, lrLookups, err := e.processTemplates(enforcement, templates, target.Data.WithParameters(templateSet.Parameters))
+			if err != nil {
+				e.log.Error(err, "unable to process", "templates", templateSet.Templates, "with param", target.Object.GetName())
+				EndSpan(span, err)
+				TemplateRenderErrors.WithLabelValues(e.kind, name).Inc()
+				return []RenderedResource{}, []LookupReference{}, err
+			}
+			StampResources(lrs, stamp, templateSet, indexes)
+			resources = append(resources, lrs...)
+			lookups = append(lookups, lrLookups...)
+		}
+		StampCopies(target.Copies, stamp)
+		resources = append(resources, target.Copies...)
+		EndSpan(span, nil)
+	}
+	return resources, lookups, nil
+}
+
+func (e *Enforcer) processTemplates(enforcement Enforcement, templates []redhatcopv1alpha1.ResourceTemplate, data TemplateData) ([]RenderedResource, []LookupReference, error) {
+	if enforcement.Namespace != "" {
+		return ProcessNamespacedTemplates(templates, e.getRestConfig(enforcement), data, enforcement.Namespace)
+	}
+	return ProcessTemplates(templates, e.getRestConfig(enforcement), data)
+}
+
+// Enforce enforces the rendered resources of a config, or only reports their drifts when the config is in Audit mode, and updates the enforcement status and the conflict condition of the config.
+// It returns the outcome from which the standard conditions of the config are computed or, when it fails, the reason of the failure.
+// The enforcement status is updated up to the failed step, so that for example the kinds applied before a failure are not forgotten.
+func (e *Enforcer) Enforce(context context.Context, enforcement Enforcement, resources []RenderedResource, lookups []LookupReference) (ReconcileOutcome, string, error) {
+	if enforcement.Mode == redhatcopv1alpha1.EnforcementModeAudit {
+		return e.audit(context, enforcement, resources, lookups)
+	}
+	config := enforcement.Config
+	name := ConfigName(config)
+	status := config.GetEnforcementStatus()
+	kubeClient := e.getClient(enforcement)
+	status.DriftedObjects = 0
+	status.Drifts = nil
+	DriftedObjects.DeleteLabelValues(e.kind, name)
+
+	resources, conflicts, reason, err := e.claim(context, enforcement, resources)
+	if err != nil {
+		return ReconcileOutcome{}, reason, err
+	}
+
+	resources, blockedWave, gates, err := GateWaves(context, e.getReader(enforcement), resources)
+	if err != nil {
+		e.log.Error(err, "unable to check the readiness of the resources of", e.kind, config)
+		return ReconcileOutcome{}, ApplyFailedReason, err
+	}
+	if blockedWave != nil {
+		e.log.Info("waiting for resources to become ready", "wave", blockedWave.Wave, "resources", blockedWave.WaitingFor)
+	}
+	status.BlockedWave = blockedWave
+
+	lockedResources, createOnlyResources, patchResources := SplitByMode(resources)
+	err = CreateIfNotExist(context, e.getReader(enforcement), kubeClient, createOnlyResources)
+	if err != nil {
+		e.log.Error(err, "unable to create the create only resources of", e.kind, config)
+		return ReconcileOutcome{}, ApplyFailedReason, err
+	}
+	err = PatchIfExist(context, kubeClient, patchResources)
+	if err != nil {
+		e.log.Error(err, "unable to patch the existing resources with the patch resources of", e.kind, config)
+		return ReconcileOutcome{}, ApplyFailedReason, err
+	}
+
+	applied, applyConflicts, err := e.applier.ApplyAs(context, kubeClient, e.kind, config, status.AppliedKinds, enforcement.ServerSideApply, lockedResources)
+	status.AppliedKinds = e.applier.GetAppliedKinds(config)
+	if err != nil {
+		e.log.Error(err, "unable to apply the resources of", e.kind, config)
+		return ReconcileOutcome{}, ApplyFailedReason, err
+	}
+	if len(applyConflicts) > 0 {
+		e.log.Info("some resources have not been applied because of conflicts with other field managers", "conflicts", applyConflicts)
+		e.reconciler.GetRecorder().Event(config, "Warning", "ApplyConflict", strings.Join(applyConflicts, "; "))
+	}
+	status.ApplyConflicts = applyConflicts
+	if enforcement.ServerSideApply != nil {
+		// applied resources are enforced by watching them and applying them again, instead of by the locked resource controllers
+		lockedResources = []lockedresource.LockedResource{}
+	}
+
+	unservedKinds, err := e.lookupWatcher.Watch(context, config, append(append(lookups, gates...), applied...))
+	if err != nil {
+		e.log.Error(err, "unable to watch objects looked up by the templates of", e.kind, config)
+		return ReconcileOutcome{}, ApplyFailedReason, err
+	}
+
+	updateContext, updateSpan := StartSpan(context, "UpdateLockedResources", e.kind, name, ResourcesAttribute.Int(len(lockedResources)))
+	err = e.reconciler.UpdateLockedResourcesWithRestConfig(updateContext, config, lockedResources, []lockedpatch.LockedPatch{}, e.getRestConfig(enforcement))
+	EndSpan(updateSpan, err)
+	if err != nil {
+		e.log.Error(err, "unable to update locked resources")
+		return ReconcileOutcome{}, ApplyFailedReason, err
+	}
+	SetManagedResources(e.kind, name, resources)
+	targets := GetTargetStatuses(resources)
+	if len(targets) > MaxReportedTargets {
+		targets = targets[:MaxReportedTargets]
+	}
+	status.Targets = targets
+
+	return ReconcileOutcome{
+		BlockedWave:   blockedWave,
+		Conflicts:     append(conflicts, applyConflicts...),
+		UnservedKinds: unservedKinds,
+	}, "", nil
+}
+
+// claim applies the adoption policies of the rendered resources and claims their ownership for the config, then reports the skipped resources in the status and the conflicts with the other configs in the conflict condition of the config.
+// It returns the resources the config can enforce and the conflicts or, when it fails, the reason of the failure.
+func (e *Enforcer) claim(context context.Context, enforcement Enforcement, resources []RenderedResource) ([]RenderedResource, []string, string, error) {
+	config := enforcement.Config
+	resources, skippedResources, err := ApplyAdoptionPolicies(context, e.getReader(enforcement), NewConfigReference(e.kind, config), resources)
+	if err != nil {
+		e.log.Error(err, "unable to apply the adoption policies of", e.kind, config)
+		return []RenderedResource{}, []string{}, ConflictReason, err
+	}
+	if len(skippedResources) > 0 {
+		e.log.Info("leaving alone existing resources", "resources", skippedResources)
+	}
+	config.GetEnforcementStatus().SkippedResources = skippedResources
+
+	resources, conflicts, err := e.ownership.ClaimAs(context, e.getClient(enforcement), e.kind, config, enforcement.ClaimOptions, resources)
+	if err != nil {
+		e.log.Error(err, "unable to claim the ownership of the resources of", e.kind, config)
+		return []RenderedResource{}, []string{}, ApplyFailedReason, err
+	}
+	if len(conflicts) > 0 {
+		e.log.Info("some resources are generated by other configs too", "conflicts", conflicts)
+		e.reconciler.GetRecorder().Event(config, "Warning", "Conflict", strings.Join(conflicts, "; "))
+	}
+	setConflictCondition(config, conflicts)
+	return resources, conflicts, "", nil
+}
+
+// getClient returns the client managing the objects of an enforcement, which like the client of the reconciler does not delete the objects still generated by some config
+func (e *Enforcer) getClient(enforcement Enforcement) client.Client {
+	if enforcement.Client != nil {
+		return e.ownership.WrapClient(enforcement.Client)
+	}
+	return e.reconciler.GetClient()
+}
+
+// getReader returns the reader of the live objects of an enforcement, which bypasses the cache
+func (e *Enforcer) getReader(enforcement Enforcement) client.Reader {
+	if enforcement.Client != nil {
+		return enforcement.Client
+	}
+	return e.reconciler.GetAPIReader()
+}
+
+// getRestConfig returns the configuration with which the objects of an enforcement are looked up and enforced
+func (e *Enforcer) getRestConfig(enforcement Enforcement) *rest.Config {
+	if enforcement.RestConfig != nil {
+		return enforcement.RestConfig
+	}
+	return e.reconciler.GetRestConfig()
+}
+
+// audit compares the rendered resources with the live objects and reports the drifted ones, without modifying anything.
+// The resources enforced so far are left in place, and are watched so that the report is updated when they change.
+func (e *Enforcer) audit(context context.Context, enforcement Enforcement, resources []RenderedResource, lookups []LookupReference) (ReconcileOutcome, string, error) {
+	config := enforcement.Config
+	name := ConfigName(config)
+	e.ownership.Forget(e.kind, config)
+	e.applier.Forget(config)
+	err := e.reconciler.Terminate(config, false)
+	if err != nil {
+		e.log.Error(err, "unable to terminate enforcing reconciler for", "instance", config)
+		return ReconcileOutcome{}, ApplyFailedReason, err
+	}
+
+	drifts, err := ComputeDrifts(context, e.getReader(enforcement), resources)
+	if err != nil {
+		e.log.Error(err, "unable to compute the drifts of", e.kind, config)
+		return ReconcileOutcome{}, ApplyFailedReason, err
+	}
+	DriftedObjects.WithLabelValues(e.kind, name).Set(float64(len(drifts)))
+	SetManagedResources(e.kind, name, []RenderedResource{})
+	driftedObjects := int32(len(drifts))
+	if len(drifts) > MaxReportedDrifts {
+		drifts = drifts[:MaxReportedDrifts]
+	}
+	*config.GetEnforcementStatus() = redhatcopv1alpha1.EnforcementStatus{
+		DriftedObjects: driftedObjects,
+		Drifts:         drifts,
+	}
+	setConflictCondition(config, []string{})
+
+	unservedKinds, err := e.lookupWatcher.Watch(context, config, append(lookups, GetReferences(resources)...))
+	if err != nil {
+		e.log.Error(err, "unable to watch the audited objects of", e.kind, config)
+		return ReconcileOutcome{}, ApplyFailedReason, err
+	}
+	return ReconcileOutcome{UnservedKinds: unservedKinds}, "", nil
+}
+
+// setConflictCondition sets the conflict condition of a config from the conflicts of its resources with the ones of other configs
+func setConflictCondition(config EnforcedConfig, conflicts []string) {
+	reconcileStatus := config.GetEnforcingReconcileStatus()
+	reconcileStatus.Conditions = SetConflictCondition(reconcileStatus.Conditions, conflicts, config.GetGeneration())
+	config.SetEnforcingReconcileStatus(reconcileStatus)
+}
+
+// ManageSuccess sets the standard conditions of a config from the outcome of the reconciliation, including the resources the enforcing controllers failed to enforce, and updates its status
+func (e *Enforcer) ManageSuccess(context context.Context, config EnforcedConfig, outcome ReconcileOutcome) (reconcile.Result, error) {
+	for key := range e.reconciler.GetLockedResourceStatuses(config) {
+		outcome.Failures = append(outcome.Failures, key)
+	}
+	config.SetObservedGeneration(config.GetGeneration())
+	reconcileStatus := config.GetEnforcingReconcileStatus()
+	reconcileStatus.Conditions = SetReconciledConditions(reconcileStatus.Conditions, config.GetGeneration(), outcome)
+	config.SetEnforcingReconcileStatus(reconcileStatus)
+	result, err := e.reconciler.ManageSuccess(context, config)
+	if err == nil && len(outcome.UnservedKinds) > 0 {
+		result.RequeueAfter = UnservedKindRequeueInterval
+	}
+	return result, err
+}
+
+// ManageError sets the standard conditions of a config for a reconciliation failed for the given reason, and updates its status
+func (e *Enforcer) ManageError(context context.Context, config EnforcedConfig, reason string, issue error) (reconcile.Result, error) {
+	config.SetObservedGeneration(config.GetGeneration())
+	reconcileStatus := config.GetEnforcingReconcileStatus()
+	reconcileStatus.Conditions = SetFailedConditions(reconcileStatus.Conditions, config.GetGeneration(), reason, issue)
+	config.SetEnforcingReconcileStatus(reconcileStatus)
+	return e.reconciler.ManageError(context, config, issue)
+}
+
+// Delete deletes the resources generated by a config being deleted and stops enforcing them.
+// The resources are released before being deleted, so that the ones generated by other configs are taken over instead of being deleted.
+func (e *Enforcer) Delete(context context.Context, config EnforcedConfig) error {
+	e.ownership.Forget(e.kind, config)
+	err := e.applier.Delete(context, e.kind, config, config.GetEnforcementStatus().AppliedKinds)
+	if err != nil {
+		e.log.Error(err, "unable to delete the applied resources of", "instance", config)
+		return err
+	}
+	err = e.reconciler.UpdateLockedResources(context, config, []lockedresource.LockedResource{}, []lockedpatch.LockedPatch{})
+	if err != nil {
+		e.log.Error(err, "unable to delete the resources of", "instance", config)
+		return err
+	}
+	return e.stop(config)
+}
+
+// Release stops enforcing the resources of a config, without deleting them, for example because the config moved to the shard of another operator
+func (e *Enforcer) Release(config EnforcedConfig) error {
+	e.ownership.Forget(e.kind, config)
+	e.applier.Forget(config)
+	return e.stop(config)
+}
+
+// stop terminates the enforcing controllers and the watches of a config and deletes its metrics
+func (e *Enforcer) stop(config EnforcedConfig) error {
+	err := e.reconciler.Terminate(config, false)
+	if err != nil {
+		e.log.Error(err, "unable to terminate enforcing reconciler for", "instance", config)
+		return err
+	}
+	e.lookupWatcher.Forget(config)
+	DeleteConfigMetrics(e.kind, ConfigName(config))
+	return nil
+}
@@ -0,0 +1,150 @@
+package common
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ParentAnnotation is set on a namespace to name its parent namespace, the chain of parents of a namespace are its ancestors
+const ParentAnnotation = "redhatcop.redhat.io/parent"
+
+// ParentIndex is the field index of the namespaces by the value of their ParentAnnotation, so that the children of a namespace are found without listing all the namespaces
+const ParentIndex = "metadata.annotations.parent"
+
+// IndexNamespaceParent returns the value of the ParentIndex of a namespace
+func IndexNamespaceParent(obj client.Object) []string {
+	parent := obj.GetAnnotations()[ParentAnnotation]
+	if parent == "" {
+		return []string{}
+	}
+	return []string{parent}
+}
+
+// NamespaceHierarchy follows the chains of parents of the namespaces, reading them from a cached client indexed with ParentIndex.
+// Each namespace is read at most once, so a hierarchy should be created for each reconciliation, to see the namespaces as of the beginning of the reconciliation.
+type NamespaceHierarchy struct {
+	reader     client.Reader
+	namespaces map[string]*corev1.Namespace
+	children   map[string][]corev1.Namespace
+}
+
+// NewNamespaceHierarchy creates a new NamespaceHierarchy reading the namespaces with the given reader
+func NewNamespaceHierarchy(reader client.Reader) *NamespaceHierarchy {
+	return &NamespaceHierarchy{
+		reader:     reader,
+		namespaces: map[string]*corev1.Namespace{},
+		children:   map[string][]corev1.Namespace{},
+	}
+}
+
+// Replace makes the hierarchy use the given version of a namespace instead of the one read by the reader
+func (h *NamespaceHierarchy) Replace(namespace *corev1.Namespace) {
+	h.namespaces[namespace.GetName()] = namespace
+}
+
+// get returns a namespace, or nil if it does not exist
+func (h *NamespaceHierarchy) get(context context.Context, name string) (*corev1.Namespace, error) {
+	namespace, ok := h.namespaces[name]
+	if ok {
+		return namespace, nil
+	}
+	namespace = &corev1.Namespace{}
+	err := h.reader.Get(context, types.NamespacedName{Name: name}, namespace)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		namespace = nil
+	}
+	h.namespaces[name] = namespace
+	return namespace, nil
+}
+
+// getChildren returns the namespaces whose parent is the given namespace
+func (h *NamespaceHierarchy) getChildren(context context.Context, name string) ([]corev1.Namespace, error) {
+	children, ok := h.children[name]
+	if ok {
+		return children, nil
+	}
+	namespaceList := &corev1.NamespaceList{}
+	err := h.reader.List(context, namespaceList, client.MatchingFields{ParentIndex: name})
+	if err != nil {
+		return []corev1.Namespace{}, err
+	}
+	children = namespaceList.Items
+	h.children[name] = children
+	return children, nil
+}
+
+// GetAncestors returns the ancestors of a namespace, from its parent to the root.
+// The chain ends at the first parent that does not exist, and a cycle of parents is broken before a namespace is repeated.
+func (h *NamespaceHierarchy) GetAncestors(context context.Context, namespace *corev1.Namespace) ([]corev1.Namespace, error) {
+	ancestors := []corev1.Namespace{}
+	seen := map[string]bool{namespace.GetName(): true}
+	parentName := namespace.GetAnnotations()[ParentAnnotation]
+	for parentName != "" && !seen[parentName] {
+		parent, err := h.get(context, parentName)
+		if err != nil {
+			return []corev1.Namespace{}, err
+		}
+		if parent == nil {
+			break
+		}
+		ancestors = append(ancestors, *parent)
+		seen[parentName] = true
+		parentName = parent.GetAnnotations()[ParentAnnotation]
+	}
+	return ancestors, nil
+}
+
+// GetDescendants returns the namespaces having the given namespace among their ancestors, sorted by name.
+// A namespace replaced in the hierarchy is a descendant according to its replaced version.
+func (h *NamespaceHierarchy) GetDescendants(context context.Context, name string) ([]corev1.Namespace, error) {
+	descendants := []corev1.Namespace{}
+	seen := map[string]bool{name: true}
+	toVisit := []string{name}
+	for len(toVisit) > 0 {
+		parentName := toVisit[0]
+		toVisit = toVisit[1:]
+		children, err := h.getChildren(context, parentName)
+		if err != nil {
+			return []corev1.Namespace{}, err
+		}
+		for i := range children {
+			child, err := h.get(context, children[i].GetName())
+			if err != nil {
+				return []corev1.Namespace{}, err
+			}
+			if child == nil || seen[child.GetName()] || child.GetAnnotations()[ParentAnnotation] != parentName {
+				continue
+			}
+			seen[child.GetName()] = true
+			descendants = append(descendants, *child)
+			toVisit = append(toVisit, child.GetName())
+		}
+	}
+	sort.Slice(descendants, func(i, j int) bool {
+		return descendants[i].GetName() < descendants[j].GetName()
+	})
+	return descendants, nil
+}
+
+// HasAncestorMatching returns whether the labels of one of the ancestors of a namespace match the given selector
+func (h *NamespaceHierarchy) HasAncestorMatching(context context.Context, namespace *corev1.Namespace, selector labels.Selector) (bool, error) {
+	ancestors, err := h.GetAncestors(context, namespace)
+	if err != nil {
+		return false, err
+	}
+	for _, ancestor := range ancestors {
+		if selector.Matches(labels.Set(ancestor.GetLabels())) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
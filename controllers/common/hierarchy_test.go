@@ -0,0 +1,213 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTestNamespace returns a namespace with the given parent, no parent when empty, and labels
+func newTestNamespace(name string, parent string, namespaceLabels map[string]string) *corev1.Namespace {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: namespaceLabels}}
+	if parent != "" {
+		namespace.Annotations = map[string]string{ParentAnnotation: parent}
+	}
+	return namespace
+}
+
+// newTestHierarchy returns a hierarchy reading the given namespaces from a client indexed like the cache of the manager
+func newTestHierarchy(namespaces ...*corev1.Namespace) *NamespaceHierarchy {
+	objs := []client.Object{}
+	for _, namespace := range namespaces {
+		objs = append(objs, namespace.DeepCopy())
+	}
+	reader := fake.NewClientBuilder().WithObjects(objs...).WithIndex(&corev1.Namespace{}, ParentIndex, IndexNamespaceParent).Build()
+	return NewNamespaceHierarchy(reader)
+}
+
+func getNames(namespaces []corev1.Namespace) []string {
+	names := []string{}
+	for _, namespace := range namespaces {
+		names = append(names, namespace.GetName())
+	}
+	return names
+}
+
+// testNamespaces is a tree rooted at org, with a cycle between loop-a and loop-b and a namespace whose parent does not exist
+var testNamespaces = []*corev1.Namespace{
+	newTestNamespace("org", "", map[string]string{"level": "org"}),
+	newTestNamespace("team-a", "org", map[string]string{"level": "team"}),
+	newTestNamespace("team-b", "org", map[string]string{"level": "team"}),
+	newTestNamespace("app-a1", "team-a", nil),
+	newTestNamespace("app-a2", "team-a", nil),
+	newTestNamespace("app-b1", "team-b", nil),
+	newTestNamespace("orphan", "missing", nil),
+	newTestNamespace("loop-a", "loop-b", nil),
+	newTestNamespace("loop-b", "loop-a", nil),
+}
+
+func TestGetAncestors(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		ancestors []string
+	}{
+		{
+			name:      "root",
+			namespace: "org",
+			ancestors: []string{},
+		},
+		{
+			name:      "from the parent to the root",
+			namespace: "app-a1",
+			ancestors: []string{"team-a", "org"},
+		},
+		{
+			name:      "missing parent",
+			namespace: "orphan",
+			ancestors: []string{},
+		},
+		{
+			name:      "cycle",
+			namespace: "loop-a",
+			ancestors: []string{"loop-b"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hierarchy := newTestHierarchy(testNamespaces...)
+			namespace, err := hierarchy.get(context.TODO(), test.namespace)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ancestors, err := hierarchy.GetAncestors(context.TODO(), namespace)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if names := getNames(ancestors); !reflect.DeepEqual(names, test.ancestors) {
+				t.Errorf("expected ancestors %v, found %v", test.ancestors, names)
+			}
+		})
+	}
+}
+
+func TestGetDescendants(t *testing.T) {
+	tests := []struct {
+		name        string
+		namespace   string
+		replaced    *corev1.Namespace
+		descendants []string
+	}{
+		{
+			name:        "leaf",
+			namespace:   "app-a1",
+			descendants: []string{},
+		},
+		{
+			name:        "sorted descendants of all the levels",
+			namespace:   "org",
+			descendants: []string{"app-a1", "app-a2", "app-b1", "team-a", "team-b"},
+		},
+		{
+			name:        "missing namespace",
+			namespace:   "missing",
+			descendants: []string{"orphan"},
+		},
+		{
+			name:        "cycle",
+			namespace:   "loop-a",
+			descendants: []string{"loop-b"},
+		},
+		{
+			name:        "replaced namespace moved away",
+			namespace:   "org",
+			replaced:    newTestNamespace("team-b", "missing", nil),
+			descendants: []string{"app-a1", "app-a2", "team-a"},
+		},
+		{
+			name:        "replaced namespace still in the index under its previous parent",
+			namespace:   "missing",
+			replaced:    newTestNamespace("team-b", "missing", nil),
+			descendants: []string{"orphan"},
+		},
+		{
+			name:        "replaced namespace moved under another parent",
+			namespace:   "team-a",
+			replaced:    newTestNamespace("app-b1", "team-a", nil),
+			descendants: []string{"app-a1", "app-a2"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hierarchy := newTestHierarchy(testNamespaces...)
+			if test.replaced != nil {
+				hierarchy.Replace(test.replaced)
+			}
+			descendants, err := hierarchy.GetDescendants(context.TODO(), test.namespace)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if names := getNames(descendants); !reflect.DeepEqual(names, test.descendants) {
+				t.Errorf("expected descendants %v, found %v", test.descendants, names)
+			}
+		})
+	}
+}
+
+func TestHasAncestorMatching(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		selector  string
+		matches   bool
+	}{
+		{
+			name:      "parent",
+			namespace: "app-b1",
+			selector:  "level=team",
+			matches:   true,
+		},
+		{
+			name:      "root",
+			namespace: "app-b1",
+			selector:  "level=org",
+			matches:   true,
+		},
+		{
+			name:      "the namespace itself is not an ancestor",
+			namespace: "team-a",
+			selector:  "level=team",
+		},
+		{
+			name:      "no ancestor",
+			namespace: "org",
+			selector:  "level",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hierarchy := newTestHierarchy(testNamespaces...)
+			selector, err := labels.Parse(test.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			namespace, err := hierarchy.get(context.TODO(), test.namespace)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			matches, err := hierarchy.HasAncestorMatching(context.TODO(), namespace, selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matches != test.matches {
+				t.Errorf("expected matches %t, found %t", test.matches, matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"context"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Identity is an identity through which a user authenticates
+type Identity struct {
+	// ProviderName is the name of the identity provider
+	ProviderName string
+	// Extra are the extra fields of the identity
+	Extra map[string]string
+}
+
+// User is a user provided by an IdentitySource
+type User struct {
+	// Object is the object representing the user, whose fields are exposed to the templates
+	Object client.Object
+	// Identities are the identities of the user, a user is selected by a UserConfig through one of its identities
+	Identities []Identity
+}
+
+// UserWatch is a kind of objects watched to detect the changes of the users
+type UserWatch struct {
+	// Kind is the kind of the watched objects
+	Kind string
+	// Object is an empty object of the watched kind
+	Object client.Object
+	// MapToUsers returns the users affected by the change of a watched object, with the identities affected by the change
+	MapToUsers func(context context.Context, obj client.Object) ([]User, error)
+}
+
+// IdentitySource provides the users and the groups selected by the UserConfigs and the GroupConfigs, so that the controllers do not depend on a specific user API.
+type IdentitySource interface {
+	// UserKind is the kind of the objects representing the users
+	UserKind() string
+	// GroupKind is the kind of the objects representing the groups
+	GroupKind() string
+	// ListUsers returns all the users, with their identities
+	ListUsers(context context.Context) ([]User, error)
+	// ListGroups returns all the groups
+	ListGroups(context context.Context) ([]client.Object, error)
+	// UserWatches returns the kinds of objects to watch to detect the changes of the users
+	UserWatches() []UserWatch
+	// NewGroup returns an empty object representing a group, to watch the changes of the groups
+	NewGroup() client.Object
+}
+
+// SwitchableIdentitySource delegates to an identity source which can be replaced while the controllers are running.
+// In Auto mode the controllers are started with the Tenant resources when the OpenShift user API is missing, and switch to it when it appears.
+type SwitchableIdentitySource struct {
+	mutex  sync.RWMutex
+	source IdentitySource
+}
+
+// NewSwitchableIdentitySource creates a new SwitchableIdentitySource delegating to the given identity source
+func NewSwitchableIdentitySource(source IdentitySource) *SwitchableIdentitySource {
+	return &SwitchableIdentitySource{
+		mutex:  sync.RWMutex{},
+		source: source,
+	}
+}
+
+// Switch replaces the identity source the calls are delegated to
+func (s *SwitchableIdentitySource) Switch(source IdentitySource) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.source = source
+}
+
+func (s *SwitchableIdentitySource) get() IdentitySource {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.source
+}
+
+// UserKind returns the kind of the users of the current identity source
+func (s *SwitchableIdentitySource) UserKind() string {
+	return s.get().UserKind()
+}
+
+// GroupKind returns the kind of the groups of the current identity source
+func (s *SwitchableIdentitySource) GroupKind() string {
+	return s.get().GroupKind()
+}
+
+// ListUsers returns the users of the current identity source
+func (s *SwitchableIdentitySource) ListUsers(context context.Context) ([]User, error) {
+	return s.get().ListUsers(context)
+}
+
+// ListGroups returns the groups of the current identity source
+func (s *SwitchableIdentitySource) ListGroups(context context.Context) ([]client.Object, error) {
+	return s.get().ListGroups(context)
+}
+
+// UserWatches returns the kinds of objects to watch to detect the changes of the users of the current identity source
+func (s *SwitchableIdentitySource) UserWatches() []UserWatch {
+	return s.get().UserWatches()
+}
+
+// NewGroup returns an empty group of the current identity source
+func (s *SwitchableIdentitySource) NewGroup() client.Object {
+	return s.get().NewGroup()
+}
@@ -0,0 +1,151 @@
+package common
+
+import (
+	"context"
+
+	userv1 "github.com/openshift/api/user/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// OpenShiftIdentitySource provides the users, identities and groups of the OpenShift user API
+type OpenShiftIdentitySource struct {
+	client client.Client
+}
+
+var _ IdentitySource = &OpenShiftIdentitySource{}
+
+// NewOpenShiftIdentitySource creates a new OpenShiftIdentitySource reading the users with the given client
+func NewOpenShiftIdentitySource(kubeClient client.Client) *OpenShiftIdentitySource {
+	return &OpenShiftIdentitySource{
+		client: kubeClient,
+	}
+}
+
+// UserKind is the kind of the OpenShift users
+func (s *OpenShiftIdentitySource) UserKind() string {
+	return "User"
+}
+
+// GroupKind is the kind of the OpenShift groups
+func (s *OpenShiftIdentitySource) GroupKind() string {
+	return "Group"
+}
+
+// ListUsers returns the OpenShift users with their identities, the users without identities are left out because no UserConfig can select them
+func (s *OpenShiftIdentitySource) ListUsers(context context.Context) ([]User, error) {
+	userList := &userv1.UserList{}
+	err := s.client.List(context, userList, &client.ListOptions{})
+	if err != nil {
+		return []User{}, err
+	}
+	identities, err := s.getIdentitiesByUser(context)
+	if err != nil {
+		return []User{}, err
+	}
+	users := []User{}
+	for i := range userList.Items {
+		user := &userList.Items[i]
+		if len(identities[user.GetUID()]) == 0 {
+			continue
+		}
+		users = append(users, User{
+			Object:     user,
+			Identities: identities[user.GetUID()],
+		})
+	}
+	return users, nil
+}
+
+// ListGroups returns the OpenShift groups
+func (s *OpenShiftIdentitySource) ListGroups(context context.Context) ([]client.Object, error) {
+	groupList := &userv1.GroupList{}
+	err := s.client.List(context, groupList, &client.ListOptions{})
+	if err != nil {
+		return []client.Object{}, err
+	}
+	groups := []client.Object{}
+	for i := range groupList.Items {
+		groups = append(groups, &groupList.Items[i])
+	}
+	return groups, nil
+}
+
+// UserWatches returns the OpenShift users and identities, a change of an identity affects only its user
+func (s *OpenShiftIdentitySource) UserWatches() []UserWatch {
+	return []UserWatch{
+		{
+			Kind: "User",
+			Object: &userv1.User{
+				TypeMeta: metav1.TypeMeta{
+					Kind: "User",
+				},
+			},
+			MapToUsers: func(context context.Context, obj client.Object) ([]User, error) {
+				identities, err := s.getIdentitiesByUser(context)
+				if err != nil {
+					return []User{}, err
+				}
+				return []User{{
+					Object:     obj,
+					Identities: identities[obj.GetUID()],
+				}}, nil
+			},
+		},
+		{
+			Kind: "Identity",
+			Object: &userv1.Identity{
+				TypeMeta: metav1.TypeMeta{
+					Kind: "Identity",
+				},
+			},
+			MapToUsers: func(context context.Context, obj client.Object) ([]User, error) {
+				identity := obj.(*userv1.Identity)
+				user := &userv1.User{}
+				err := s.client.Get(context, types.NamespacedName{Name: identity.User.Name}, user)
+				if err != nil {
+					return []User{}, client.IgnoreNotFound(err)
+				}
+				if user.GetUID() != identity.User.UID {
+					return []User{}, nil
+				}
+				return []User{{
+					Object:     user,
+					Identities: []Identity{toIdentity(identity)},
+				}}, nil
+			},
+		},
+	}
+}
+
+// NewGroup returns an empty OpenShift group
+func (s *OpenShiftIdentitySource) NewGroup() client.Object {
+	return &userv1.Group{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "Group",
+		},
+	}
+}
+
+// getIdentitiesByUser returns the identities of the OpenShift users, by uid of the user
+func (s *OpenShiftIdentitySource) getIdentitiesByUser(context context.Context) (map[types.UID][]Identity, error) {
+	identityList := &userv1.IdentityList{}
+	err := s.client.List(context, identityList, &client.ListOptions{})
+	if err != nil {
+		return map[types.UID][]Identity{}, err
+	}
+	identities := map[types.UID][]Identity{}
+	for i := range identityList.Items {
+		identity := &identityList.Items[i]
+		identities[identity.User.UID] = append(identities[identity.User.UID], toIdentity(identity))
+	}
+	return identities, nil
+}
+
+func toIdentity(identity *userv1.Identity) Identity {
+	return Identity{
+		ProviderName: identity.ProviderName,
+		Extra:        identity.Extra,
+	}
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"context"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=tenants;tenantgroups,verbs=get;list;watch
+
+// TenantIdentitySource provides the users and groups defined by the Tenant and TenantGroup resources of the operator, for the clusters without the OpenShift user API
+type TenantIdentitySource struct {
+	client client.Client
+}
+
+var _ IdentitySource = &TenantIdentitySource{}
+
+// NewTenantIdentitySource creates a new TenantIdentitySource reading the tenants with the given client
+func NewTenantIdentitySource(kubeClient client.Client) *TenantIdentitySource {
+	return &TenantIdentitySource{
+		client: kubeClient,
+	}
+}
+
+// UserKind is the kind of the tenants
+func (s *TenantIdentitySource) UserKind() string {
+	return "Tenant"
+}
+
+// GroupKind is the kind of the tenant groups
+func (s *TenantIdentitySource) GroupKind() string {
+	return "TenantGroup"
+}
+
+// ListUsers returns the tenants with their identities
+func (s *TenantIdentitySource) ListUsers(context context.Context) ([]User, error) {
+	tenantList := &redhatcopv1alpha1.TenantList{}
+	err := s.client.List(context, tenantList, &client.ListOptions{})
+	if err != nil {
+		return []User{}, err
+	}
+	users := []User{}
+	for i := range tenantList.Items {
+		users = append(users, tenantToUser(&tenantList.Items[i]))
+	}
+	return users, nil
+}
+
+// ListGroups returns the tenant groups
+func (s *TenantIdentitySource) ListGroups(context context.Context) ([]client.Object, error) {
+	groupList := &redhatcopv1alpha1.TenantGroupList{}
+	err := s.client.List(context, groupList, &client.ListOptions{})
+	if err != nil {
+		return []client.Object{}, err
+	}
+	groups := []client.Object{}
+	for i := range groupList.Items {
+		groups = append(groups, &groupList.Items[i])
+	}
+	return groups, nil
+}
+
+// UserWatches returns the tenants, which carry their own identities
+func (s *TenantIdentitySource) UserWatches() []UserWatch {
+	return []UserWatch{
+		{
+			Kind: "Tenant",
+			Object: &redhatcopv1alpha1.Tenant{
+				TypeMeta: metav1.TypeMeta{
+					Kind: "Tenant",
+				},
+			},
+			MapToUsers: func(context context.Context, obj client.Object) ([]User, error) {
+				return []User{tenantToUser(obj.(*redhatcopv1alpha1.Tenant))}, nil
+			},
+		},
+	}
+}
+
+// NewGroup returns an empty tenant group
+func (s *TenantIdentitySource) NewGroup() client.Object {
+	return &redhatcopv1alpha1.TenantGroup{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "TenantGroup",
+		},
+	}
+}
+
+// tenantToUser returns the user represented by a tenant.
+// A tenant without identities is given an identity without provider name nor extra fields, so that it can be selected by the UserConfigs that do not filter on the identities.
+func tenantToUser(tenant *redhatcopv1alpha1.Tenant) User {
+	identities := []Identity{}
+	for _, identity := range tenant.Identities {
+		identities = append(identities, Identity{
+			ProviderName: identity.ProviderName,
+			Extra:        identity.Extra,
+		})
+	}
+	if len(identities) == 0 {
+		identities = append(identities, Identity{})
+	}
+	return User{
+		Object:     tenant,
+		Identities: identities,
+	}
+}
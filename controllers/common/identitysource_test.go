@@ -0,0 +1,91 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTenantIdentitySource(t *testing.T) {
+	tests := []struct {
+		name       string
+		tenant     *redhatcopv1alpha1.Tenant
+		identities []Identity
+	}{
+		{
+			name:       "tenant without identities",
+			tenant:     &redhatcopv1alpha1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: "alice"}},
+			identities: []Identity{{}},
+		},
+		{
+			name: "tenant with identities",
+			tenant: &redhatcopv1alpha1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{Name: "bob"},
+				Identities: []redhatcopv1alpha1.TenantIdentity{
+					{ProviderName: "ldap", ProviderUserName: "bob", Extra: map[string]string{"team": "a"}},
+					{ProviderName: "github"},
+				},
+			},
+			identities: []Identity{
+				{ProviderName: "ldap", Extra: map[string]string{"team": "a"}},
+				{ProviderName: "github"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identitySource := NewTenantIdentitySource(fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(tt.tenant).Build())
+			users, err := identitySource.ListUsers(context.TODO())
+			if err != nil {
+				t.Fatalf("ListUsers() error = %v", err)
+			}
+			if len(users) != 1 || users[0].Object.GetName() != tt.tenant.Name {
+				t.Fatalf("ListUsers() = %v, want the user %v", users, tt.tenant.Name)
+			}
+			if !reflect.DeepEqual(users[0].Identities, tt.identities) {
+				t.Errorf("ListUsers() identities = %v, want %v", users[0].Identities, tt.identities)
+			}
+			watches := identitySource.UserWatches()
+			if len(watches) != 1 || watches[0].Kind != identitySource.UserKind() {
+				t.Fatalf("UserWatches() = %v, want a watch of the %v", watches, identitySource.UserKind())
+			}
+			watchedUsers, err := watches[0].MapToUsers(context.TODO(), tt.tenant)
+			if err != nil {
+				t.Fatalf("MapToUsers() error = %v", err)
+			}
+			if !reflect.DeepEqual(watchedUsers, users) {
+				t.Errorf("MapToUsers() = %v, want %v", watchedUsers, users)
+			}
+		})
+	}
+}
+
+func TestSwitchableIdentitySource(t *testing.T) {
+	groups := []client.Object{
+		&redhatcopv1alpha1.TenantGroup{ObjectMeta: metav1.ObjectMeta{Name: "admins"}, Users: []string{"alice"}},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(groups...).Build()
+	identitySource := NewSwitchableIdentitySource(NewTenantIdentitySource(kubeClient))
+	if identitySource.UserKind() != "Tenant" || identitySource.GroupKind() != "TenantGroup" {
+		t.Errorf("kinds = %v, %v, want %v, %v", identitySource.UserKind(), identitySource.GroupKind(), "Tenant", "TenantGroup")
+	}
+	if _, ok := identitySource.NewGroup().(*redhatcopv1alpha1.TenantGroup); !ok {
+		t.Errorf("NewGroup() = %T, want a TenantGroup", identitySource.NewGroup())
+	}
+	listedGroups, err := identitySource.ListGroups(context.TODO())
+	if err != nil {
+		t.Fatalf("ListGroups() error = %v", err)
+	}
+	if len(listedGroups) != 1 || listedGroups[0].GetName() != "admins" {
+		t.Errorf("ListGroups() = %v, want the group admins", listedGroups)
+	}
+	identitySource.Switch(NewOpenShiftIdentitySource(kubeClient))
+	if identitySource.UserKind() != "User" || identitySource.GroupKind() != "Group" {
+		t.Errorf("kinds after the switch = %v, %v, want %v, %v", identitySource.UserKind(), identitySource.GroupKind(), "User", "Group")
+	}
+}
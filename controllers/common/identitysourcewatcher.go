@@ -0,0 +1,186 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/config/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/discoveryclient"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IdentitySourceDiscoveryInterval is the period after which the APIs of the identity source are discovered again, while they are not available or while the Tenant resources are used in Auto mode
+const IdentitySourceDiscoveryInterval = 30 * time.Second
+
+// IdentitySourceFallbackDiscoveries is the number of consecutive discoveries missing the OpenShift user API after which the Tenant resources are used in Auto mode,
+// so that a user API briefly unavailable at startup does not make the controllers replace the resources generated for the OpenShift users and groups
+const IdentitySourceFallbackDiscoveries = 3
+
+// IdentitySourceUnavailableReason is the reason of the events recorded on the configs while the identity source is not available
+const IdentitySourceUnavailableReason = "IdentitySourceUnavailable"
+
+// openShiftUserKinds are the kinds of the OpenShift user API required by the OpenShift identity source
+var openShiftUserKinds = []string{"User", "Identity", "Group"}
+
+// IdentitySourceWatcher chooses the source of the users and groups from the APIs discovered in the cluster, then sets up the controllers using it.
+// Discovery is retried in the background until it succeeds, so that the controllers are started when the OpenShift user API appears after the operator, or when discovery fails at startup.
+// Meanwhile the operator is reported as not ready and a warning event is recorded on each config.
+// In Auto mode the controllers fall back to the Tenant resources once the OpenShift user API has been missing for IdentitySourceFallbackDiscoveries discoveries, and discovery goes on so that they switch to the OpenShift user API when it appears.
+type IdentitySourceWatcher struct {
+	source     configv1alpha1.IdentitySource
+	restConfig *rest.Config
+	client     client.Client
+	reader     client.Reader
+	recorder   record.EventRecorder
+	configs    []client.ObjectList
+	setup      func(IdentitySource) error
+	switched   func(context.Context, IdentitySource) error
+	current    *SwitchableIdentitySource
+	log        logr.Logger
+	mutex      sync.Mutex
+	pending    error
+	notified   map[types.UID]bool
+	missing    int
+}
+
+// NewIdentitySourceWatcher creates a new IdentitySourceWatcher for the given kind of identity source, which reads the users and groups with the given client.
+// The setup function is called once with the identity source, when it becomes available. Until then, the configs listed with the given lists are notified with an event.
+// The switched function is called when the identity source given to the setup function switches from the Tenant resources to the OpenShift user API, after the switch, so that the controllers watch the new kinds and reconcile all the configs.
+func NewIdentitySourceWatcher(source configv1alpha1.IdentitySource, restConfig *rest.Config, kubeClient client.Client, reader client.Reader, recorder record.EventRecorder, configs []client.ObjectList, setup func(IdentitySource) error, switched func(context.Context, IdentitySource) error, log logr.Logger) *IdentitySourceWatcher {
+	return &IdentitySourceWatcher{
+		source:     source,
+		restConfig: restConfig,
+		client:     kubeClient,
+		reader:     reader,
+		recorder:   recorder,
+		configs:    configs,
+		setup:      setup,
+		switched:   switched,
+		log:        log,
+		mutex:      sync.Mutex{},
+		pending:    fmt.Errorf("the identity source has not been discovered yet"),
+		notified:   map[types.UID]bool{},
+	}
+}
+
+// Start discovers the identity source until it is available and sets up the controllers, then in Auto mode keeps discovering the OpenShift user API while the Tenant resources are used.
+// It implements the Runnable interface of the manager.
+func (w *IdentitySourceWatcher) Start(ctx context.Context) error {
+	for {
+		identitySource, final, err := w.discover(ctx)
+		switch {
+		case err != nil && w.current == nil:
+			w.log.Info("waiting for the identity source", "reason", err.Error())
+			w.setPending(err)
+			w.notifyConfigs(ctx, err)
+		case err != nil:
+			w.log.Info("unable to discover the OpenShift user API, the Tenant resources are still used", "reason", err.Error())
+		case w.current == nil:
+			w.log.Info("users and groups read from", "kind", identitySource.UserKind()+", "+identitySource.GroupKind())
+			w.current = NewSwitchableIdentitySource(identitySource)
+			err = w.setup(w.current)
+			if err != nil {
+				return err
+			}
+			w.setPending(nil)
+		case final:
+			w.log.Info("switching the identity source", "kind", identitySource.UserKind()+", "+identitySource.GroupKind())
+			w.current.Switch(identitySource)
+			err = w.switched(ctx, identitySource)
+			if err != nil {
+				return err
+			}
+		}
+		if err == nil && final {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(IdentitySourceDiscoveryInterval):
+		}
+	}
+}
+
+// NeedLeaderElection tells the manager to run the watcher on all the replicas, so that all of them report their readiness
+func (w *IdentitySourceWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// ReadyzCheck fails while the identity source is not available, it implements the healthz.Checker function of the manager
+func (w *IdentitySourceWatcher) ReadyzCheck(_ *http.Request) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.pending
+}
+
+func (w *IdentitySourceWatcher) setPending(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.pending = err
+}
+
+// discover returns the identity source to use and whether it is final, or an error if it is not available yet.
+// In Auto mode the OpenShift user API is used if it is discovered, and the Tenant resources once it has been missing for IdentitySourceFallbackDiscoveries consecutive discoveries, until it appears.
+func (w *IdentitySourceWatcher) discover(ctx context.Context) (IdentitySource, bool, error) {
+	if w.source == configv1alpha1.IdentitySourceTenant {
+		return NewTenantIdentitySource(w.client), true, nil
+	}
+	discoveryContext := context.WithValue(ctx, "restConfig", w.restConfig)
+	for _, kind := range openShiftUserKinds {
+		ok, err := discoveryclient.IsGVKDefined(discoveryContext, schema.GroupVersionKind{
+			Group:   "user.openshift.io",
+			Version: "v1",
+			Kind:    kind,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to check whether resource %s.user.openshift.io exists: %w", kind, err)
+		}
+		if !ok {
+			if w.source == configv1alpha1.IdentitySourceOpenShift {
+				return nil, false, fmt.Errorf("resource %s.user.openshift.io does not exist, it is required by the %s identity source", kind, w.source)
+			}
+			w.missing++
+			if w.current == nil && w.missing < IdentitySourceFallbackDiscoveries {
+				return nil, false, fmt.Errorf("resource %s.user.openshift.io does not exist, the Tenant resources are used if it is still missing after %d discoveries", kind, IdentitySourceFallbackDiscoveries)
+			}
+			return NewTenantIdentitySource(w.client), false, nil
+		}
+	}
+	w.missing = 0
+	return NewOpenShiftIdentitySource(w.client), true, nil
+}
+
+// notifyConfigs records a warning event on the configs that have not been notified yet, telling why they are not reconciled
+func (w *IdentitySourceWatcher) notifyConfigs(ctx context.Context, reason error) {
+	for _, configList := range w.configs {
+		list := configList.DeepCopyObject().(client.ObjectList)
+		err := w.reader.List(ctx, list, &client.ListOptions{})
+		if err != nil {
+			w.log.Error(err, "unable to list the configs to notify")
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			w.log.Error(err, "unable to list the configs to notify")
+			continue
+		}
+		for _, item := range items {
+			config, ok := item.(client.Object)
+			if !ok || w.notified[config.GetUID()] {
+				continue
+			}
+			w.notified[config.GetUID()] = true
+			w.recorder.Event(config, "Warning", IdentitySourceUnavailableReason, fmt.Sprintf("not reconciled until the identity source is available: %s", reason))
+		}
+	}
+}
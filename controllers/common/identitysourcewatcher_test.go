@@ -0,0 +1,136 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-logr/logr"
+	configv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/config/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// newTestUserAPIServer returns a server answering the discovery of the OpenShift user API, which is served while available returns true
+func newTestUserAPIServer(available func() bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/user.openshift.io/v1" || !available() {
+			http.NotFound(w, r)
+			return
+		}
+		resources := &metav1.APIResourceList{
+			TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: "user.openshift.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "users", Kind: "User"},
+				{Name: "identities", Kind: "Identity"},
+				{Name: "groups", Kind: "Group"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resources)
+	}))
+}
+
+func TestIdentitySourceWatcherDiscover(t *testing.T) {
+	type discovery struct {
+		available bool
+		userKind  string
+		final     bool
+		wantErr   bool
+	}
+	tests := []struct {
+		name        string
+		source      configv1alpha1.IdentitySource
+		discoveries []discovery
+	}{
+		{
+			name:   "tenant",
+			source: configv1alpha1.IdentitySourceTenant,
+			discoveries: []discovery{
+				{available: true, userKind: "Tenant", final: true},
+			},
+		},
+		{
+			name:   "openshift",
+			source: configv1alpha1.IdentitySourceOpenShift,
+			discoveries: []discovery{
+				{available: true, userKind: "User", final: true},
+			},
+		},
+		{
+			name:   "openshift missing",
+			source: configv1alpha1.IdentitySourceOpenShift,
+			discoveries: []discovery{
+				{available: false, wantErr: true},
+				{available: false, wantErr: true},
+				{available: false, wantErr: true},
+				{available: false, wantErr: true},
+			},
+		},
+		{
+			name:   "auto with openshift",
+			source: configv1alpha1.IdentitySourceAuto,
+			discoveries: []discovery{
+				{available: true, userKind: "User", final: true},
+			},
+		},
+		{
+			name:   "auto with openshift missing at startup",
+			source: configv1alpha1.IdentitySourceAuto,
+			discoveries: []discovery{
+				{available: false, wantErr: true},
+				{available: true, userKind: "User", final: true},
+			},
+		},
+		{
+			name:   "auto without openshift",
+			source: configv1alpha1.IdentitySourceAuto,
+			discoveries: []discovery{
+				{available: false, wantErr: true},
+				{available: false, wantErr: true},
+				{available: false, userKind: "Tenant", final: false},
+				{available: false, userKind: "Tenant", final: false},
+			},
+		},
+		{
+			name:   "auto switching to openshift",
+			source: configv1alpha1.IdentitySourceAuto,
+			discoveries: []discovery{
+				{available: false, wantErr: true},
+				{available: false, wantErr: true},
+				{available: false, userKind: "Tenant", final: false},
+				{available: true, userKind: "User", final: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			available := atomic.Bool{}
+			server := newTestUserAPIServer(available.Load)
+			defer server.Close()
+			watcher := NewIdentitySourceWatcher(tt.source, &rest.Config{Host: server.URL}, nil, nil, nil, nil, nil, nil, logr.Discard())
+			for i, d := range tt.discoveries {
+				available.Store(d.available)
+				identitySource, final, err := watcher.discover(context.TODO())
+				if (err != nil) != d.wantErr {
+					t.Fatalf("discovery %d: discover() error = %v, wantErr %v", i, err, d.wantErr)
+				}
+				if d.wantErr {
+					continue
+				}
+				if identitySource.UserKind() != d.userKind || final != d.final {
+					t.Fatalf("discovery %d: discover() = %v, %v, want %v, %v", i, identitySource.UserKind(), final, d.userKind, d.final)
+				}
+				if watcher.current == nil {
+					watcher.current = NewSwitchableIdentitySource(identitySource)
+				} else {
+					watcher.current.Switch(identitySource)
+				}
+			}
+		})
+	}
+}
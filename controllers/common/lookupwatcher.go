@@ -0,0 +1,245 @@
+package common
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// LookupEventsBufferSize is the number of notifications buffered while the controllers are busy, so that the informers are not blocked
+const LookupEventsBufferSize = 100
+
+// UnservedKindRequeueInterval is the period after which a config watching kinds that are not served yet, like the kinds of a CRD that is not installed yet, is reconciled again
+const UnservedKindRequeueInterval = 30 * time.Second
+
+// LookupWatcher keeps track of the objects looked up by the templates of each config and notifies the configs when those objects change.
+// Informers are started on demand, the first time an object is looked up, and stopped when no config looks it up anymore.
+// Each informer watches only the looked up object, or the listed objects in the looked up namespace, so that a lookup does not cache all the objects of its kind in the cluster.
+// They are not shared with the manager cache, whose informers cannot be removed and are not scoped.
+type LookupWatcher struct {
+	client     dynamic.Interface
+	restMapper meta.RESTMapper
+	events     chan event.GenericEvent
+	mutex      sync.Mutex
+	configs    map[types.NamespacedName]client.Object
+	references map[types.NamespacedName][]LookupReference
+	// the informers are guarded by their own mutex, taken before the other one, to avoid blocking the notifications
+	watchedMutex sync.Mutex
+	watched      map[LookupReference]context.CancelFunc
+	log          logr.Logger
+}
+
+// NewLookupWatcher creates a new LookupWatcher watching the looked up objects with the given configuration
+func NewLookupWatcher(restConfig *rest.Config, restMapper meta.RESTMapper, log logr.Logger) (*LookupWatcher, error) {
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newLookupWatcher(dynamicClient, restMapper, log), nil
+}
+
+func newLookupWatcher(dynamicClient dynamic.Interface, restMapper meta.RESTMapper, log logr.Logger) *LookupWatcher {
+	return &LookupWatcher{
+		client:       dynamicClient,
+		restMapper:   restMapper,
+		events:       make(chan event.GenericEvent, LookupEventsBufferSize),
+		mutex:        sync.Mutex{},
+		configs:      map[types.NamespacedName]client.Object{},
+		references:   map[types.NamespacedName][]LookupReference{},
+		watchedMutex: sync.Mutex{},
+		watched:      map[LookupReference]context.CancelFunc{},
+		log:          log.WithName("lookup-watcher"),
+	}
+}
+
+// GetEventChannel returns the channel through which the configs to be reconciled are notified
+func (w *LookupWatcher) GetEventChannel() <-chan event.GenericEvent {
+	return w.events
+}
+
+// Watch replaces the objects looked up by the templates of a config and makes sure they are watched.
+// It returns the kinds that cannot be watched because they are not served yet, the config should be reconciled again after UnservedKindRequeueInterval.
+func (w *LookupWatcher) Watch(context context.Context, config client.Object, lookups []LookupReference) ([]string, error) {
+	references := []LookupReference{}
+	seen := map[LookupReference]bool{}
+	for _, reference := range lookups {
+		if !seen[reference] {
+			seen[reference] = true
+			references = append(references, reference)
+		}
+	}
+	w.watchedMutex.Lock()
+	defer w.watchedMutex.Unlock()
+	unserved := map[string]bool{}
+	for _, reference := range references {
+		served, err := w.ensureInformer(reference)
+		if err != nil {
+			w.log.Error(err, "unable to watch", "kind", reference.GroupVersionKind)
+			w.stopUnreferenced()
+			return []string{}, err
+		}
+		if !served {
+			unserved[reference.GroupVersionKind.String()] = true
+		}
+	}
+	w.setReferences(config, references)
+	w.stopUnreferenced()
+	kinds := []string{}
+	for kind := range unserved {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds, nil
+}
+
+// Forget stops notifying a config, it should be called when the config is deleted
+func (w *LookupWatcher) Forget(config client.Object) {
+	w.watchedMutex.Lock()
+	defer w.watchedMutex.Unlock()
+	w.setReferences(config, []LookupReference{})
+	w.stopUnreferenced()
+}
+
+func (w *LookupWatcher) setReferences(config client.Object, references []LookupReference) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	key := client.ObjectKeyFromObject(config)
+	if len(references) == 0 {
+		delete(w.configs, key)
+		delete(w.references, key)
+		return
+	}
+	w.configs[key] = config.DeepCopyObject().(client.Object)
+	w.references[key] = references
+}
+
+// stopUnreferenced must be called holding the watchedMutex, it stops the informers of the objects no config looks up anymore
+func (w *LookupWatcher) stopUnreferenced() {
+	w.mutex.Lock()
+	referenced := map[LookupReference]bool{}
+	for _, references := range w.references {
+		for _, reference := range references {
+			referenced[reference] = true
+		}
+	}
+	w.mutex.Unlock()
+	for reference, cancel := range w.watched {
+		if !referenced[reference] {
+			cancel()
+			delete(w.watched, reference)
+		}
+	}
+}
+
+// ensureInformer must be called holding the watchedMutex, it starts the informer of a looked up object if needed and returns whether its kind is served.
+// The informer lists the objects of the looked up namespace only, and only the looked up object when the lookup has a name.
+func (w *LookupWatcher) ensureInformer(reference LookupReference) (bool, error) {
+	if _, ok := w.watched[reference]; ok {
+		return true, nil
+	}
+	gvk := reference.GroupVersionKind
+	mapping, err := w.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	namespace := reference.Namespace
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		namespace = metav1.NamespaceAll
+	}
+	tweakListOptions := func(options *metav1.ListOptions) {
+		if reference.Name != "" {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", reference.Name).String()
+		}
+	}
+	informer := dynamicinformer.NewFilteredDynamicInformer(w.client, mapping.Resource, namespace, 0, toolscache.Indexers{}, tweakListOptions).Informer()
+	_, err = informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.notify(gvk, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldMeta, oldOk := oldObj.(client.Object)
+			newMeta, newOk := newObj.(client.Object)
+			if oldOk && newOk && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+			w.notify(gvk, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			w.notify(gvk, obj)
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	informerContext, cancel := context.WithCancel(context.Background())
+	go informer.Run(informerContext.Done())
+	w.watched[reference] = cancel
+	return true, nil
+}
+
+func (w *LookupWatcher) notify(gvk schema.GroupVersionKind, obj interface{}) {
+	object, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+	for _, config := range w.getConfigsLookingUp(gvk, object) {
+		notification := event.GenericEvent{
+			Object: config,
+		}
+		// the informer must not be blocked when the buffer is full while the controllers are busy
+		select {
+		case w.events <- notification:
+		default:
+			go func() {
+				w.events <- notification
+			}()
+		}
+	}
+}
+
+func (w *LookupWatcher) getConfigsLookingUp(gvk schema.GroupVersionKind, object client.Object) []client.Object {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	configs := []client.Object{}
+	for key, references := range w.references {
+		for _, reference := range references {
+			if reference.matches(gvk, object) {
+				configs = append(configs, w.configs[key])
+				break
+			}
+		}
+	}
+	return configs
+}
+
+// matches returns whether the object is the looked up one or, for list lookups, one of the listed ones.
+// The namespace is ignored for cluster level objects and when it was not specified in the lookup.
+func (r LookupReference) matches(gvk schema.GroupVersionKind, object client.Object) bool {
+	if r.GroupVersionKind != gvk {
+		return false
+	}
+	if r.Namespace != "" && object.GetNamespace() != "" && r.Namespace != object.GetNamespace() {
+		return false
+	}
+	return r.Name == "" || r.Name == object.GetName()
+}
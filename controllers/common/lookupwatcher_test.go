@@ -0,0 +1,106 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestLookupWatcher(t *testing.T) {
+	configMapKind := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	settings := LookupReference{GroupVersionKind: configMapKind, Namespace: "team-a", Name: "settings"}
+	allSettings := LookupReference{GroupVersionKind: configMapKind, Namespace: "team-a"}
+	widgets := LookupReference{GroupVersionKind: schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}, Namespace: "team-a", Name: "widget"}
+	tenant := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}
+	quota := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "quota"}}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme)
+	watcher := newLookupWatcher(dynamicClient, newTestRESTMapper(), logr.Discard())
+	steps := []struct {
+		name     string
+		config   client.Object
+		lookups  []LookupReference
+		unserved []string
+		watched  []LookupReference
+	}{
+		{
+			name:    "watch a looked up object",
+			config:  tenant,
+			lookups: []LookupReference{settings, settings},
+			watched: []LookupReference{settings},
+		},
+		{
+			name:    "watch the objects looked up by another config",
+			config:  quota,
+			lookups: []LookupReference{settings, allSettings},
+			watched: []LookupReference{settings, allSettings},
+		},
+		{
+			name:     "kind not served",
+			config:   tenant,
+			lookups:  []LookupReference{widgets},
+			unserved: []string{widgets.GroupVersionKind.String()},
+			watched:  []LookupReference{settings, allSettings},
+		},
+		{
+			name:    "stop watching the objects not looked up anymore",
+			config:  quota,
+			lookups: []LookupReference{allSettings},
+			watched: []LookupReference{allSettings},
+		},
+	}
+	for _, step := range steps {
+		unserved, err := watcher.Watch(context.TODO(), step.config, step.lookups)
+		if err != nil {
+			t.Fatalf("%s: Watch() error = %v", step.name, err)
+		}
+		if len(unserved) != len(step.unserved) || (len(unserved) > 0 && !reflect.DeepEqual(unserved, step.unserved)) {
+			t.Errorf("%s: Watch() = %v, want %v", step.name, unserved, step.unserved)
+		}
+		if len(watcher.watched) != len(step.watched) {
+			t.Errorf("%s: %d objects watched, want %v", step.name, len(watcher.watched), step.watched)
+		}
+		for _, reference := range step.watched {
+			if _, ok := watcher.watched[reference]; !ok {
+				t.Errorf("%s: %v is not watched", step.name, reference)
+			}
+		}
+	}
+
+	_, err := dynamicClient.Resource(configMaps).Namespace("team-a").Create(context.TODO(), newTestObject("v1", "ConfigMap", "team-a", "settings", nil), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("unable to create the looked up object: %v", err)
+	}
+	select {
+	case notification := <-watcher.GetEventChannel():
+		if notification.Object.GetName() != quota.Name {
+			t.Errorf("notified %v, want %v", notification.Object.GetName(), quota.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the config looking up the created object has not been notified")
+	}
+
+	watcher.Forget(quota)
+	if len(watcher.watched) != 0 {
+		t.Errorf("%d objects watched after forgetting the configs, want none", len(watcher.watched))
+	}
+	_, err = dynamicClient.Resource(configMaps).Namespace("team-a").Create(context.TODO(), newTestObject("v1", "ConfigMap", "team-a", "other", nil), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("unable to create the object: %v", err)
+	}
+	select {
+	case notification := <-watcher.GetEventChannel():
+		t.Errorf("notified %v after forgetting the configs", notification.Object.GetName())
+	case <-time.After(200 * time.Millisecond):
+	}
+}
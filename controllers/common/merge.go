@@ -0,0 +1,51 @@
+package common
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// MergeObjects merges the objects in order, so that the fields of the later objects take precedence over the fields of the earlier ones.
+// The built-in types are merged with a strategic merge, so that for example containers are merged by name, any other type is merged with a json merge.
+func MergeObjects(objs ...*unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if len(objs) == 0 {
+		return &unstructured.Unstructured{}, nil
+	}
+	result := objs[0].DeepCopy()
+	var dataStruct runtime.Object
+	if scheme.Scheme.Recognizes(result.GroupVersionKind()) {
+		var err error
+		dataStruct, err = scheme.Scheme.New(result.GroupVersionKind())
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, obj := range objs[1:] {
+		if dataStruct != nil {
+			merged, err := strategicpatch.StrategicMergeMapPatch(result.Object, obj.DeepCopy().Object, dataStruct)
+			if err != nil {
+				return nil, err
+			}
+			result.Object = merged
+			continue
+		}
+		result.Object = mergeMaps(result.Object, obj.DeepCopy().Object)
+	}
+	return result, nil
+}
+
+// mergeMaps merges the overlay onto the base as a json merge patch would, but without deleting the null fields
+func mergeMaps(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	for key, value := range overlay {
+		valueMap, ok := value.(map[string]interface{})
+		baseMap, baseOk := base[key].(map[string]interface{})
+		if ok && baseOk {
+			base[key] = mergeMaps(baseMap, valueMap)
+			continue
+		}
+		base[key] = value
+	}
+	return base
+}
@@ -0,0 +1,112 @@
+package common
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newTestObject returns an object of the given kind with the given fields besides the metadata
+func newTestObject(apiVersion string, kind string, namespace string, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for key, value := range fields {
+		obj.Object[key] = value
+	}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestMergeObjects(t *testing.T) {
+	tests := []struct {
+		name     string
+		objs     []*unstructured.Unstructured
+		expected *unstructured.Unstructured
+	}{
+		{
+			name:     "no object",
+			objs:     []*unstructured.Unstructured{},
+			expected: &unstructured.Unstructured{},
+		},
+		{
+			name: "one object",
+			objs: []*unstructured.Unstructured{
+				newTestObject("v1", "ConfigMap", "ns", "cm", map[string]interface{}{"data": map[string]interface{}{"a": "1"}}),
+			},
+			expected: newTestObject("v1", "ConfigMap", "ns", "cm", map[string]interface{}{"data": map[string]interface{}{"a": "1"}}),
+		},
+		{
+			name: "later objects take precedence",
+			objs: []*unstructured.Unstructured{
+				newTestObject("v1", "ConfigMap", "ns", "cm", map[string]interface{}{"data": map[string]interface{}{"a": "1", "b": "1"}}),
+				newTestObject("v1", "ConfigMap", "ns", "cm", map[string]interface{}{"data": map[string]interface{}{"b": "2", "c": "2"}}),
+				newTestObject("v1", "ConfigMap", "ns", "cm", map[string]interface{}{"data": map[string]interface{}{"c": "3"}}),
+			},
+			expected: newTestObject("v1", "ConfigMap", "ns", "cm", map[string]interface{}{"data": map[string]interface{}{"a": "1", "b": "2", "c": "3"}}),
+		},
+		{
+			name: "built-in lists are merged by key",
+			objs: []*unstructured.Unstructured{
+				newTestObject("v1", "Pod", "ns", "pod", map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "app:1"},
+					map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+				}}}),
+				newTestObject("v1", "Pod", "ns", "pod", map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "app:2"},
+				}}}),
+			},
+			expected: newTestObject("v1", "Pod", "ns", "pod", map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "app:2"},
+				map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+			}}}),
+		},
+		{
+			name: "other lists are replaced and maps are merged",
+			objs: []*unstructured.Unstructured{
+				newTestObject("example.com/v1", "Widget", "ns", "widget", map[string]interface{}{"spec": map[string]interface{}{
+					"items":   []interface{}{"a", "b"},
+					"options": map[string]interface{}{"x": int64(1), "y": int64(1)},
+				}}),
+				newTestObject("example.com/v1", "Widget", "ns", "widget", map[string]interface{}{"spec": map[string]interface{}{
+					"items":   []interface{}{"c"},
+					"options": map[string]interface{}{"y": int64(2)},
+				}}),
+			},
+			expected: newTestObject("example.com/v1", "Widget", "ns", "widget", map[string]interface{}{"spec": map[string]interface{}{
+				"items":   []interface{}{"c"},
+				"options": map[string]interface{}{"x": int64(1), "y": int64(2)},
+			}}),
+		},
+		{
+			name: "null fields are kept",
+			objs: []*unstructured.Unstructured{
+				newTestObject("example.com/v1", "Widget", "ns", "widget", map[string]interface{}{"spec": map[string]interface{}{"a": "1"}}),
+				newTestObject("example.com/v1", "Widget", "ns", "widget", map[string]interface{}{"spec": map[string]interface{}{"a": nil}}),
+			},
+			expected: newTestObject("example.com/v1", "Widget", "ns", "widget", map[string]interface{}{"spec": map[string]interface{}{"a": nil}}),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			originals := []*unstructured.Unstructured{}
+			for _, obj := range test.objs {
+				originals = append(originals, obj.DeepCopy())
+			}
+			merged, err := MergeObjects(test.objs...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !equality.Semantic.DeepEqual(merged.Object, test.expected.Object) {
+				t.Errorf("expected %v, found %v", test.expected.Object, merged.Object)
+			}
+			for i := range test.objs {
+				if !equality.Semantic.DeepEqual(test.objs[i].Object, originals[i].Object) {
+					t.Errorf("the object %d has been modified", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var (
+	// DriftedObjects is the number of generated objects whose live state deviates from the generated one, for the configs in Audit mode
+	DriftedObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "namespace_configuration_operator_drifted_objects",
+			Help: "Number of generated objects whose live state deviates from the generated one, for the configs in Audit mode",
+		},
+		[]string{"config_kind", "config_name"},
+	)
+
+	// SelectedObjects is the number of objects selected by each config
+	SelectedObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "namespace_configuration_operator_selected_objects",
+			Help: "Number of objects selected by each config",
+		},
+		[]string{"config_kind", "config_name"},
+	)
+
+	// ManagedResources is the number of resources managed by each config, by kind of the resources
+	ManagedResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "namespace_configuration_operator_managed_resources",
+			Help: "Number of resources managed by each config, by kind of the resources",
+		},
+		[]string{"config_kind", "config_name", "group", "version", "kind"},
+	)
+
+	// GeneratedResources is the number of resources managed by each config, by kind of the selected objects the resources are generated for.
+	// The selected objects themselves are reported in the status of the configs, labeling the metric with them would make its cardinality unbounded.
+	GeneratedResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "namespace_configuration_operator_generated_resources",
+			Help: "Number of resources managed by each config, by kind of the selected objects the resources are generated for",
+		},
+		[]string{"config_kind", "config_name", "target_kind"},
+	)
+
+	// TemplateRenderErrors is the number of failed template processings of each config
+	TemplateRenderErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "namespace_configuration_operator_template_render_errors_total",
+			Help: "Number of failed template processings of each config",
+		},
+		[]string{"config_kind", "config_name"},
+	)
+
+	// EnforcementCorrections is the number of drifted resources that have been reverted to their generated state, by kind of the resources
+	EnforcementCorrections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "namespace_configuration_operator_enforcement_corrections_total",
+			Help: "Number of drifted resources that have been reverted to their generated state, by kind of the resources",
+		},
+		[]string{"group", "version", "kind"},
+	)
+
+	// WatchMappingDuration is the time taken to map a watched object to the configs to be reconciled
+	WatchMappingDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "namespace_configuration_operator_watch_mapping_duration_seconds",
+			Help:    "Time taken to map a watched object to the configs to be reconciled",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+		},
+		[]string{"controller", "source_kind"},
+	)
+)
+
+// RegisterMetrics registers the metrics of the operator with the given registerer
+func RegisterMetrics(registerer prometheus.Registerer) {
+	registerer.MustRegister(
+		DriftedObjects,
+		SelectedObjects,
+		ManagedResources,
+		GeneratedResources,
+		TemplateRenderErrors,
+		EnforcementCorrections,
+		WatchMappingDuration,
+	)
+}
+
+// SetManagedResources sets the number of resources managed by a config, by kind of the resources and by kind of the selected objects the resources are generated for
+func SetManagedResources(kind string, name string, resources []RenderedResource) {
+	labels := prometheus.Labels{"config_kind": kind, "config_name": name}
+	ManagedResources.DeletePartialMatch(labels)
+	GeneratedResources.DeletePartialMatch(labels)
+	counts := map[schema.GroupVersionKind]int{}
+	targetCounts := map[string]int{}
+	for _, resource := range resources {
+		counts[resource.GroupVersionKind()]++
+		if targetKind, ok := resource.GetAnnotations()[TargetKindLabel]; ok {
+			targetCounts[targetKind]++
+		}
+	}
+	for gvk, count := range counts {
+		ManagedResources.WithLabelValues(kind, name, gvk.Group, gvk.Version, gvk.Kind).Set(float64(count))
+	}
+	for targetKind, count := range targetCounts {
+		GeneratedResources.WithLabelValues(kind, name, targetKind).Set(float64(count))
+	}
+}
+
+// DeleteConfigMetrics deletes the metrics of a config, it should be called when the config is deleted
+func DeleteConfigMetrics(kind string, name string) {
+	labels := prometheus.Labels{"config_kind": kind, "config_name": name}
+	DriftedObjects.DeletePartialMatch(labels)
+	SelectedObjects.DeletePartialMatch(labels)
+	ManagedResources.DeletePartialMatch(labels)
+	GeneratedResources.DeletePartialMatch(labels)
+	TemplateRenderErrors.DeletePartialMatch(labels)
+}
+
+// TimeMapFunc wraps a map function, observing the time it takes in the WatchMappingDuration metric
+func TimeMapFunc(controller string, sourceKind string, mapFunc handler.MapFunc) handler.MapFunc {
+	return func(context context.Context, obj client.Object) []reconcile.Request {
+		start := time.Now()
+		defer func() {
+			WatchMappingDuration.WithLabelValues(controller, sourceKind).Observe(time.Since(start).Seconds())
+		}()
+		return mapFunc(context, obj)
+	}
+}
+
+// WithCorrectionsCounter returns a copy of the rest config whose successful merge patches are counted in the EnforcementCorrections metric.
+// The locked resource controllers patch a resource only when it drifted from its generated state, so this config must be used by the enforcing reconcilers only.
+func WithCorrectionsCounter(config *rest.Config, mapper meta.RESTMapper) *rest.Config {
+	countingConfig := rest.CopyConfig(config)
+	countingConfig.Wrap(func(roundTripper http.RoundTripper) http.RoundTripper {
+		return &correctionsCounter{
+			roundTripper: roundTripper,
+			mapper:       mapper,
+		}
+	})
+	return countingConfig
+}
+
+type correctionsCounter struct {
+	roundTripper http.RoundTripper
+	mapper       meta.RESTMapper
+}
+
+func (c *correctionsCounter) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := c.roundTripper.RoundTrip(request)
+	if err == nil && request.Method == http.MethodPatch && request.Header.Get("Content-Type") == string(types.MergePatchType) && response.StatusCode < 300 {
+		if gvr, ok := parseResourcePath(request.URL.Path); ok {
+			gvk, err := c.mapper.KindFor(gvr)
+			if err == nil {
+				EnforcementCorrections.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Inc()
+			}
+		}
+	}
+	return response, err
+}
+
+// parseResourcePath returns the resource of an API path like /api/v1/namespaces/ns/configmaps/name or /apis/group/version/resources/name
+func parseResourcePath(path string) (schema.GroupVersionResource, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	var gvr schema.GroupVersionResource
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		gvr.Version = parts[1]
+		parts = parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		gvr.Group = parts[1]
+		gvr.Version = parts[2]
+		parts = parts[3:]
+	default:
+		return schema.GroupVersionResource{}, false
+	}
+	if len(parts) >= 3 && parts[0] == "namespaces" {
+		parts = parts[2:]
+	}
+	gvr.Resource = parts[0]
+	return gvr, true
+}
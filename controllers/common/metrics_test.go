@@ -0,0 +1,166 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestMetricsResource(apiVersion string, kind string, targetKind string) RenderedResource {
+	obj := newTestObject(apiVersion, kind, "tenant", "generated", nil)
+	if targetKind != "" {
+		obj.SetAnnotations(map[string]string{TargetKindLabel: targetKind})
+	}
+	return RenderedResource{LockedResource: lockedresource.LockedResource{Unstructured: *obj}}
+}
+
+func TestSetManagedResources(t *testing.T) {
+	SetManagedResources("NamespaceConfig", "metrics", []RenderedResource{
+		newTestMetricsResource("v1", "ConfigMap", "Namespace"),
+		newTestMetricsResource("v1", "ConfigMap", "Namespace"),
+		newTestMetricsResource("rbac.authorization.k8s.io/v1", "Role", "Namespace"),
+		newTestMetricsResource("v1", "Secret", ""),
+	})
+	tests := []struct {
+		name   string
+		labels []string
+		want   float64
+	}{
+		{name: "core kind", labels: []string{"NamespaceConfig", "metrics", "", "v1", "ConfigMap"}, want: 2},
+		{name: "grouped kind", labels: []string{"NamespaceConfig", "metrics", "rbac.authorization.k8s.io", "v1", "Role"}, want: 1},
+		{name: "resource without target", labels: []string{"NamespaceConfig", "metrics", "", "v1", "Secret"}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testutil.ToFloat64(ManagedResources.WithLabelValues(tt.labels...)); got != tt.want {
+				t.Errorf("ManagedResources%v = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+	if got := testutil.ToFloat64(GeneratedResources.WithLabelValues("NamespaceConfig", "metrics", "Namespace")); got != 3 {
+		t.Errorf("GeneratedResources = %v, want %v", got, 3)
+	}
+
+	SetManagedResources("NamespaceConfig", "metrics", []RenderedResource{newTestMetricsResource("v1", "ConfigMap", "")})
+	if count := testutil.CollectAndCount(ManagedResources); count != 1 {
+		t.Errorf("%d ManagedResources series after the resources changed, want 1", count)
+	}
+	if count := testutil.CollectAndCount(GeneratedResources); count != 0 {
+		t.Errorf("%d GeneratedResources series after the resources changed, want none", count)
+	}
+
+	DeleteConfigMetrics("NamespaceConfig", "metrics")
+	if count := testutil.CollectAndCount(ManagedResources); count != 0 {
+		t.Errorf("%d ManagedResources series after the config was deleted, want none", count)
+	}
+}
+
+func TestDeleteConfigMetrics(t *testing.T) {
+	DriftedObjects.WithLabelValues("ResourceConfig", "deleted").Set(1)
+	SelectedObjects.WithLabelValues("ResourceConfig", "deleted").Set(2)
+	TemplateRenderErrors.WithLabelValues("ResourceConfig", "deleted").Inc()
+	SelectedObjects.WithLabelValues("ResourceConfig", "kept").Set(3)
+	DeleteConfigMetrics("ResourceConfig", "deleted")
+	if count := testutil.CollectAndCount(DriftedObjects); count != 0 {
+		t.Errorf("%d DriftedObjects series, want none", count)
+	}
+	if count := testutil.CollectAndCount(TemplateRenderErrors); count != 0 {
+		t.Errorf("%d TemplateRenderErrors series, want none", count)
+	}
+	if got := testutil.ToFloat64(SelectedObjects.WithLabelValues("ResourceConfig", "kept")); got != 3 {
+		t.Errorf("SelectedObjects of the other config = %v, want %v", got, 3)
+	}
+	DeleteConfigMetrics("ResourceConfig", "kept")
+}
+
+func TestParseResourcePath(t *testing.T) {
+	tests := []struct {
+		path string
+		gvr  schema.GroupVersionResource
+		ok   bool
+	}{
+		{path: "/api/v1/namespaces/tenant/configmaps/settings", gvr: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, ok: true},
+		{path: "/api/v1/namespaces/tenant", gvr: schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, ok: true},
+		{path: "/apis/rbac.authorization.k8s.io/v1/clusterroles/admin", gvr: schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, ok: true},
+		{path: "/apis/rbac.authorization.k8s.io/v1/namespaces/tenant/roles/admin", gvr: schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"}, ok: true},
+		{path: "/healthz"},
+		{path: "/apis/apps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			gvr, ok := parseResourcePath(tt.path)
+			if gvr != tt.gvr || ok != tt.ok {
+				t.Errorf("parseResourcePath() = %v, %v, want %v, %v", gvr, ok, tt.gvr, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWithCorrectionsCounter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/namespaces/tenant/configmaps/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	config := WithCorrectionsCounter(&rest.Config{Host: server.URL}, newTestRESTMapper())
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		t.Fatalf("unable to create the client: %v", err)
+	}
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType types.PatchType
+		want        float64
+	}{
+		{name: "merge patch", method: http.MethodPatch, path: "/api/v1/namespaces/tenant/configmaps/settings", contentType: types.MergePatchType, want: 1},
+		{name: "apply patch", method: http.MethodPatch, path: "/api/v1/namespaces/tenant/configmaps/settings", contentType: types.ApplyPatchType},
+		{name: "failed patch", method: http.MethodPatch, path: "/api/v1/namespaces/tenant/configmaps/missing", contentType: types.MergePatchType},
+		{name: "update", method: http.MethodPut, path: "/api/v1/namespaces/tenant/configmaps/settings", contentType: types.MergePatchType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			EnforcementCorrections.Reset()
+			request, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("unable to create the request: %v", err)
+			}
+			request.Header.Set("Content-Type", string(tt.contentType))
+			response, err := httpClient.Do(request)
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			response.Body.Close()
+			if got := testutil.ToFloat64(EnforcementCorrections.WithLabelValues("", "v1", "ConfigMap")); got != tt.want {
+				t.Errorf("EnforcementCorrections = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	EnforcementCorrections.Reset()
+}
+
+func TestTimeMapFunc(t *testing.T) {
+	mapFunc := TimeMapFunc("namespaceconfig", "Namespace", func(context context.Context, obj client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetName()}}}
+	})
+	requests := mapFunc(context.TODO(), newTestObject("v1", "Namespace", "", "tenant", nil))
+	if len(requests) != 1 || requests[0].Name != "tenant" {
+		t.Errorf("TimeMapFunc() = %v, want the requests of the wrapped function", requests)
+	}
+	if count := testutil.CollectAndCount(WatchMappingDuration); count != 1 {
+		t.Errorf("%d WatchMappingDuration series, want 1", count)
+	}
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SplitByMode splits the resources according to the mode of the template they have been generated from, resources without a mode are enforced
+func SplitByMode(resources []RenderedResource) (enforced []lockedresource.LockedResource, createOnly []RenderedResource, patch []RenderedResource) {
+	enforced = []lockedresource.LockedResource{}
+	createOnly = []RenderedResource{}
+	patch = []RenderedResource{}
+	for _, resource := range resources {
+		switch resource.Mode {
+		case redhatcopv1alpha1.TemplateModeCreateOnly:
+			createOnly = append(createOnly, resource)
+		case redhatcopv1alpha1.TemplateModePatch:
+			patch = append(patch, resource)
+		default:
+			enforced = append(enforced, resource.LockedResource)
+		}
+	}
+	return enforced, createOnly, patch
+}
+
+// CreateIfNotExist creates the objects that do not exist, the existing objects are left untouched.
+// The objects are looked up with the given reader first, so that the existing ones are not created again at every reconcile.
+func CreateIfNotExist(context context.Context, reader client.Reader, kubeClient client.Client, resources []RenderedResource) error {
+	for _, resource := range resources {
+		liveObj, err := getLiveObject(context, reader, &resource.Unstructured)
+		if err != nil {
+			renderLog.Error(err, "unable to get", "object", getObjectReference(&resource.Unstructured))
+			return err
+		}
+		if liveObj != nil {
+			continue
+		}
+		obj := resource.Unstructured.DeepCopy()
+		err = kubeClient.Create(context, obj)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			renderLog.Error(err, "unable to create", "object", getObjectReference(obj))
+			return err
+		}
+	}
+	return nil
+}
+
+// PatchIfExist merges the generated fields, except the excluded paths, into the existing objects. The objects that do not exist are ignored.
+func PatchIfExist(context context.Context, kubeClient client.Client, resources []RenderedResource) error {
+	for _, resource := range resources {
+		patch, err := lockedresource.FilterOutPaths(&resource.Unstructured, resource.ExcludedPaths)
+		if err != nil {
+			renderLog.Error(err, "unable to filter out", "excluded paths", resource.ExcludedPaths, "from object", getObjectReference(&resource.Unstructured))
+			return err
+		}
+		patchBytes, err := json.Marshal(patch)
+		if err != nil {
+			return err
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(resource.GroupVersionKind())
+		obj.SetNamespace(resource.GetNamespace())
+		obj.SetName(resource.GetName())
+		err = kubeClient.Patch(context, obj, client.RawPatch(types.MergePatchType, patchBytes))
+		if err != nil && !errors.IsNotFound(err) {
+			renderLog.Error(err, "unable to patch", "object", getObjectReference(obj), "with patch", string(patchBytes))
+			return err
+		}
+	}
+	return nil
+}
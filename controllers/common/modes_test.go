@@ -0,0 +1,175 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newTestModeResource(mode redhatcopv1alpha1.TemplateMode, name string, data map[string]interface{}, excludedPaths ...string) RenderedResource {
+	obj := newTestObject("v1", "ConfigMap", "ns", name, map[string]interface{}{"data": data})
+	return RenderedResource{
+		LockedResource: lockedresource.LockedResource{Unstructured: *obj, ExcludedPaths: excludedPaths},
+		Mode:           mode,
+	}
+}
+
+func TestSplitByMode(t *testing.T) {
+	resources := []RenderedResource{
+		newTestModeResource("", "default", nil),
+		newTestModeResource(redhatcopv1alpha1.TemplateModeEnforce, "enforced", nil),
+		newTestModeResource(redhatcopv1alpha1.TemplateModeCreateOnly, "created", nil),
+		newTestModeResource(redhatcopv1alpha1.TemplateModePatch, "patched", nil),
+	}
+	enforced, createOnly, patch := SplitByMode(resources)
+	enforcedNames := []string{}
+	for _, resource := range enforced {
+		enforcedNames = append(enforcedNames, resource.GetName())
+	}
+	createOnlyNames := []string{}
+	for _, resource := range createOnly {
+		createOnlyNames = append(createOnlyNames, resource.GetName())
+	}
+	patchNames := []string{}
+	for _, resource := range patch {
+		patchNames = append(patchNames, resource.GetName())
+	}
+	if !reflect.DeepEqual(enforcedNames, []string{"default", "enforced"}) {
+		t.Errorf("enforced = %v, want %v", enforcedNames, []string{"default", "enforced"})
+	}
+	if !reflect.DeepEqual(createOnlyNames, []string{"created"}) {
+		t.Errorf("createOnly = %v, want %v", createOnlyNames, []string{"created"})
+	}
+	if !reflect.DeepEqual(patchNames, []string{"patched"}) {
+		t.Errorf("patch = %v, want %v", patchNames, []string{"patched"})
+	}
+}
+
+func TestCreateIfNotExist(t *testing.T) {
+	tests := []struct {
+		name    string
+		cached  []*unstructured.Unstructured
+		live    []*unstructured.Unstructured
+		created []string
+		data    map[string]interface{}
+	}{
+		{
+			name:    "missing object",
+			created: []string{"settings"},
+			data:    map[string]interface{}{"mode": "generated"},
+		},
+		{
+			name:   "existing object",
+			cached: []*unstructured.Unstructured{newTestObject("v1", "ConfigMap", "ns", "settings", map[string]interface{}{"data": map[string]interface{}{"mode": "edited"}})},
+			live:   []*unstructured.Unstructured{newTestObject("v1", "ConfigMap", "ns", "settings", map[string]interface{}{"data": map[string]interface{}{"mode": "edited"}})},
+			data:   map[string]interface{}{"mode": "edited"},
+		},
+		{
+			name:    "object not read yet",
+			live:    []*unstructured.Unstructured{newTestObject("v1", "ConfigMap", "ns", "settings", map[string]interface{}{"data": map[string]interface{}{"mode": "edited"}})},
+			created: []string{"settings"},
+			data:    map[string]interface{}{"mode": "edited"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached := []client.Object{}
+			for _, obj := range tt.cached {
+				cached = append(cached, obj)
+			}
+			live := []client.Object{}
+			for _, obj := range tt.live {
+				live = append(live, obj)
+			}
+			created := []string{}
+			reader := fake.NewClientBuilder().WithObjects(cached...).Build()
+			kubeClient := fake.NewClientBuilder().WithObjects(live...).WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					created = append(created, obj.GetName())
+					return c.Create(ctx, obj, opts...)
+				},
+			}).Build()
+			resources := []RenderedResource{newTestModeResource(redhatcopv1alpha1.TemplateModeCreateOnly, "settings", map[string]interface{}{"mode": "generated"})}
+			err := CreateIfNotExist(context.TODO(), reader, kubeClient, resources)
+			if err != nil {
+				t.Fatalf("CreateIfNotExist() error = %v", err)
+			}
+			if len(created) != len(tt.created) || (len(created) > 0 && !reflect.DeepEqual(created, tt.created)) {
+				t.Errorf("created = %v, want %v", created, tt.created)
+			}
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion("v1")
+			obj.SetKind("ConfigMap")
+			err = kubeClient.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "settings"}, obj)
+			if err != nil {
+				t.Fatalf("unable to get the object: %v", err)
+			}
+			if data, _, _ := unstructured.NestedMap(obj.Object, "data"); !reflect.DeepEqual(data, tt.data) {
+				t.Errorf("data = %v, want %v", data, tt.data)
+			}
+		})
+	}
+}
+
+func TestPatchIfExist(t *testing.T) {
+	tests := []struct {
+		name  string
+		live  []*unstructured.Unstructured
+		patch RenderedResource
+		data  map[string]interface{}
+	}{
+		{
+			name:  "missing object",
+			patch: newTestModeResource(redhatcopv1alpha1.TemplateModePatch, "settings", map[string]interface{}{"mode": "generated"}),
+		},
+		{
+			name:  "existing object",
+			live:  []*unstructured.Unstructured{newTestObject("v1", "ConfigMap", "ns", "settings", map[string]interface{}{"data": map[string]interface{}{"mode": "edited", "owner": "team-a"}})},
+			patch: newTestModeResource(redhatcopv1alpha1.TemplateModePatch, "settings", map[string]interface{}{"mode": "generated"}),
+			data:  map[string]interface{}{"mode": "generated", "owner": "team-a"},
+		},
+		{
+			name:  "excluded paths",
+			live:  []*unstructured.Unstructured{newTestObject("v1", "ConfigMap", "ns", "settings", map[string]interface{}{"data": map[string]interface{}{"mode": "edited", "owner": "team-a"}})},
+			patch: newTestModeResource(redhatcopv1alpha1.TemplateModePatch, "settings", map[string]interface{}{"mode": "generated", "owner": "team-b"}, ".data.owner"),
+			data:  map[string]interface{}{"mode": "generated", "owner": "team-a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := []client.Object{}
+			for _, obj := range tt.live {
+				live = append(live, obj)
+			}
+			kubeClient := fake.NewClientBuilder().WithObjects(live...).Build()
+			err := PatchIfExist(context.TODO(), kubeClient, []RenderedResource{tt.patch})
+			if err != nil {
+				t.Fatalf("PatchIfExist() error = %v", err)
+			}
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion("v1")
+			obj.SetKind("ConfigMap")
+			err = kubeClient.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "settings"}, obj)
+			if tt.data == nil {
+				if err == nil {
+					t.Errorf("PatchIfExist() created %v", obj)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to get the object: %v", err)
+			}
+			if data, _, _ := unstructured.NestedMap(obj.Object, "data"); !reflect.DeepEqual(data, tt.data) {
+				t.Errorf("data = %v, want %v", data, tt.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckNamespacedValues verifies that the objects referenced by the valuesFrom of a namespaced config are in its namespace
+func CheckNamespacedValues(valuesFrom []redhatcopv1alpha1.ValuesReference, namespace string) error {
+	for _, reference := range valuesFrom {
+		if reference.Namespace != namespace {
+			return fmt.Errorf("unable to read values from %s/%s, the values can only be read from namespace %s", reference.Namespace, reference.Name, namespace)
+		}
+	}
+	return nil
+}
+
+// CheckNamespacedResources verifies that the resources generated by a namespaced config are namespaced objects in its namespace.
+// The resources without namespace are moved to the namespace of the config.
+// The objects are then created and enforced impersonating the service account of the config, which must be allowed to manage them.
+func CheckNamespacedResources(restMapper meta.RESTMapper, namespace string, resources []RenderedResource) error {
+	for i := range resources {
+		resource := &resources[i].Unstructured
+		gvk := resource.GroupVersionKind()
+		mapping, err := restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return err
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			return fmt.Errorf("unable to generate %s %s, only namespaced objects can be generated", gvk.Kind, resource.GetName())
+		}
+		if resource.GetNamespace() == "" {
+			resource.SetNamespace(namespace)
+		}
+		if resource.GetNamespace() != namespace {
+			return fmt.Errorf("unable to generate %s %s/%s, the objects can only be generated in namespace %s", gvk.Kind, resource.GetNamespace(), resource.GetName(), namespace)
+		}
+	}
+	return nil
+}
+
+// ImpersonateServiceAccount returns a copy of the configuration impersonating the given service account
+func ImpersonateServiceAccount(restConfig *rest.Config, namespace string, serviceAccountName string) *rest.Config {
+	impersonatingConfig := rest.CopyConfig(restConfig)
+	// the API server adds the groups of the service accounts to the impersonated service account
+	impersonatingConfig.Impersonate = rest.ImpersonationConfig{
+		UserName: "system:serviceaccount:" + namespace + ":" + serviceAccountName,
+	}
+	return impersonatingConfig
+}
+
+// ImpersonatingClients creates and caches the clients impersonating the service accounts of the namespaced configs, so that the API server authorizes every object they generate
+type ImpersonatingClients struct {
+	restConfig *rest.Config
+	scheme     *runtime.Scheme
+	restMapper meta.RESTMapper
+	mutex      sync.Mutex
+	clients    map[types.NamespacedName]client.Client
+}
+
+// NewImpersonatingClients creates a new ImpersonatingClients deriving the clients from the given configuration
+func NewImpersonatingClients(restConfig *rest.Config, scheme *runtime.Scheme, restMapper meta.RESTMapper) *ImpersonatingClients {
+	return &ImpersonatingClients{
+		restConfig: restConfig,
+		scheme:     scheme,
+		restMapper: restMapper,
+		mutex:      sync.Mutex{},
+		clients:    map[types.NamespacedName]client.Client{},
+	}
+}
+
+// GetClient returns the client impersonating the given service account
+func (c *ImpersonatingClients) GetClient(namespace string, serviceAccountName string) (client.Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := types.NamespacedName{Namespace: namespace, Name: serviceAccountName}
+	if kubeClient, ok := c.clients[key]; ok {
+		return kubeClient, nil
+	}
+	kubeClient, err := client.New(ImpersonateServiceAccount(c.restConfig, namespace, serviceAccountName), client.Options{Scheme: c.scheme, Mapper: c.restMapper})
+	if err != nil {
+		return nil, err
+	}
+	c.clients[key] = kubeClient
+	return kubeClient, nil
+}
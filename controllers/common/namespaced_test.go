@@ -0,0 +1,166 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTestRESTMapper returns a mapper knowing ConfigMaps and Roles as namespaced kinds and Namespaces and ClusterRoles as cluster kinds
+func newTestRESTMapper() meta.RESTMapper {
+	restMapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	restMapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	restMapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"}, meta.RESTScopeNamespace)
+	restMapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	restMapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+	return restMapper
+}
+
+func TestCheckNamespacedValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		valuesFrom []redhatcopv1alpha1.ValuesReference
+		wantErr    bool
+	}{
+		{
+			name: "no values",
+		},
+		{
+			name: "values in the namespace of the config",
+			valuesFrom: []redhatcopv1alpha1.ValuesReference{
+				{Name: "values", Namespace: "team-a"},
+				{Kind: SecretKind, Name: "credentials", Namespace: "team-a"},
+			},
+		},
+		{
+			name: "values in another namespace",
+			valuesFrom: []redhatcopv1alpha1.ValuesReference{
+				{Name: "values", Namespace: "team-a"},
+				{Kind: SecretKind, Name: "credentials", Namespace: "team-b"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckNamespacedValues(tt.valuesFrom, "team-a"); (err != nil) != tt.wantErr {
+				t.Errorf("CheckNamespacedValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckNamespacedResources(t *testing.T) {
+	tests := []struct {
+		name       string
+		resources  []RenderedResource
+		namespaces []string
+		wantErr    bool
+	}{
+		{
+			name: "objects in the namespace of the config",
+			resources: []RenderedResource{
+				{LockedResource: lockedresource.LockedResource{Unstructured: *newTestObject("v1", "ConfigMap", "team-a", "settings", nil)}},
+				{LockedResource: lockedresource.LockedResource{Unstructured: *newTestObject("rbac.authorization.k8s.io/v1", "Role", "team-a", "viewer", nil)}},
+			},
+			namespaces: []string{"team-a", "team-a"},
+		},
+		{
+			name: "objects without namespace",
+			resources: []RenderedResource{
+				{LockedResource: lockedresource.LockedResource{Unstructured: *newTestObject("v1", "ConfigMap", "", "settings", nil)}},
+			},
+			namespaces: []string{"team-a"},
+		},
+		{
+			name: "object in another namespace",
+			resources: []RenderedResource{
+				{LockedResource: lockedresource.LockedResource{Unstructured: *newTestObject("v1", "ConfigMap", "team-a", "settings", nil)}},
+				{LockedResource: lockedresource.LockedResource{Unstructured: *newTestObject("v1", "ConfigMap", "team-b", "settings", nil)}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "namespace",
+			resources: []RenderedResource{
+				{LockedResource: lockedresource.LockedResource{Unstructured: *newTestObject("v1", "Namespace", "", "team-a-dev", nil)}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cluster object",
+			resources: []RenderedResource{
+				{LockedResource: lockedresource.LockedResource{Unstructured: *newTestObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "admin", nil)}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown kind",
+			resources: []RenderedResource{
+				{LockedResource: lockedresource.LockedResource{Unstructured: *newTestObject("example.com/v1", "Widget", "team-a", "widget", nil)}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckNamespacedResources(newTestRESTMapper(), "team-a", tt.resources)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckNamespacedResources() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			for i, resource := range tt.resources {
+				if resource.GetNamespace() != tt.namespaces[i] {
+					t.Errorf("namespace of %s = %v, want %v", resource.GetName(), resource.GetNamespace(), tt.namespaces[i])
+				}
+			}
+		})
+	}
+}
+
+func TestImpersonateServiceAccount(t *testing.T) {
+	restConfig := &rest.Config{Host: "https://api.example.com:6443", BearerToken: "operator-token"}
+	impersonatingConfig := ImpersonateServiceAccount(restConfig, "team-a", "deployer")
+	if impersonatingConfig.Impersonate.UserName != "system:serviceaccount:team-a:deployer" {
+		t.Errorf("Impersonate.UserName = %v, want %v", impersonatingConfig.Impersonate.UserName, "system:serviceaccount:team-a:deployer")
+	}
+	if impersonatingConfig.Host != restConfig.Host || impersonatingConfig.BearerToken != restConfig.BearerToken {
+		t.Errorf("ImpersonateServiceAccount() = %v, want the host and the credentials of %v", impersonatingConfig, restConfig)
+	}
+	if restConfig.Impersonate.UserName != "" {
+		t.Errorf("ImpersonateServiceAccount() modified the given configuration, Impersonate.UserName = %v", restConfig.Impersonate.UserName)
+	}
+}
+
+func TestEnforcerImpersonation(t *testing.T) {
+	impersonatingClient := fake.NewClientBuilder().Build()
+	impersonatingConfig := &rest.Config{Host: "https://api.example.com:6443"}
+	enforcer := NewEnforcer("NamespacedResourceConfig", nil, NewOwnershipRegistry(nil, logr.Discard()), nil, nil, logr.Discard())
+	enforcement := Enforcement{
+		Namespace:  "team-a",
+		Client:     impersonatingClient,
+		RestConfig: impersonatingConfig,
+	}
+	kubeClient, ok := enforcer.getClient(enforcement).(*ownershipAwareClient)
+	if !ok {
+		t.Fatalf("getClient() = %T, want a client not deleting the objects still generated by some config", enforcer.getClient(enforcement))
+	}
+	if kubeClient.Client != impersonatingClient {
+		t.Errorf("getClient() wraps %v, want the impersonating client", kubeClient.Client)
+	}
+	if reader := enforcer.getReader(enforcement); reader != impersonatingClient {
+		t.Errorf("getReader() = %v, want the impersonating client", reader)
+	}
+	if restConfig := enforcer.getRestConfig(enforcement); restConfig != impersonatingConfig {
+		t.Errorf("getRestConfig() = %v, want the impersonating configuration", restConfig)
+	}
+}
@@ -0,0 +1,536 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// ManagedByAnnotation is set on the generated objects and names the config managing them, in the <kind>/<name> format, or <kind>/<namespace>/<name> for the namespaced configs
+const ManagedByAnnotation = "redhatcop.redhat.io/managed-by"
+
+// ConflictCondition is the condition reporting that some objects generated by a config are also generated by other configs
+const ConflictCondition = "Conflict"
+
+// ConfigReference identifies a config, the namespace is empty for the cluster level configs
+type ConfigReference struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// NewConfigReference returns the reference to a config of the given kind
+func NewConfigReference(kind string, config client.Object) ConfigReference {
+	return ConfigReference{Kind: kind, Namespace: config.GetNamespace(), Name: config.GetName()}
+}
+
+func (c ConfigReference) String() string {
+	if c.Namespace == "" {
+		return c.Kind + "/" + c.Name
+	}
+	return c.Kind + "/" + c.Namespace + "/" + c.Name
+}
+
+func parseConfigReference(value string) (ConfigReference, bool) {
+	parts := strings.Split(value, "/")
+	for _, part := range parts {
+		if part == "" {
+			return ConfigReference{}, false
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return ConfigReference{Kind: parts[0], Name: parts[1]}, true
+	case 3:
+		return ConfigReference{Kind: parts[0], Namespace: parts[1], Name: parts[2]}, true
+	}
+	return ConfigReference{}, false
+}
+
+// objectReference identifies a generated object, the version is ignored because the same object can be generated with different versions
+type objectReference struct {
+	schema.GroupKind
+	Namespace string
+	Name      string
+}
+
+func (o objectReference) String() string {
+	return o.Kind + "/" + o.Namespace + "/" + o.Name
+}
+
+func getObjectReference(obj *unstructured.Unstructured) objectReference {
+	return objectReference{
+		GroupKind: obj.GroupVersionKind().GroupKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+}
+
+// ClaimOptions are the options of a config claiming the ownership of the objects it generates
+type ClaimOptions struct {
+	// Priority the config with the highest priority owns the objects, configs with the same priority are in conflict
+	Priority int32
+	// MergeMode when Merge, the objects generated by the configs with a lower priority are merged under the ones generated by the owning config
+	MergeMode redhatcopv1alpha1.MergeMode
+}
+
+// candidate is a config generating an object
+type candidate struct {
+	ClaimOptions
+	object *unstructured.Unstructured
+	// sequence orders the candidates with the same priority, the first one claiming the object wins
+	sequence uint64
+}
+
+// OwnershipRegistry keeps track of the config owning each generated object. It is shared by all the controllers, so that two configs of any kind never enforce the same object.
+// The config with the highest priority generating an object owns it, among configs with the same priority the first one claiming the object owns it and the others are in conflict.
+// The cluster level configs always outrank the namespaced configs, whatever their priorities, and the objects generated by the namespaced configs are never merged into the objects owned by the cluster level configs, so that the tenants cannot take over or alter them.
+// Configs are notified when the ownership of their objects changes, and owners merging their objects are notified when the objects generated by the other configs change.
+type OwnershipRegistry struct {
+	client     client.Client
+	mutex      sync.Mutex
+	owners     map[objectReference]ConfigReference
+	candidates map[objectReference]map[ConfigReference]*candidate
+	claims     map[ConfigReference][]objectReference
+	configs    map[ConfigReference]client.Object
+	stamps     map[objectReference]string
+	channels   map[string]chan event.GenericEvent
+	sequence   uint64
+	log        logr.Logger
+}
+
+// NewOwnershipRegistry creates a new OwnershipRegistry using the given client to check and stamp the ownership of the live objects
+func NewOwnershipRegistry(kubeClient client.Client, log logr.Logger) *OwnershipRegistry {
+	return &OwnershipRegistry{
+		client:     kubeClient,
+		mutex:      sync.Mutex{},
+		owners:     map[objectReference]ConfigReference{},
+		candidates: map[objectReference]map[ConfigReference]*candidate{},
+		claims:     map[ConfigReference][]objectReference{},
+		configs:    map[ConfigReference]client.Object{},
+		stamps:     map[objectReference]string{},
+		channels:   map[string]chan event.GenericEvent{},
+		log:        log.WithName("ownership-registry"),
+	}
+}
+
+// GetEventChannel returns the channel through which the configs of the given kind are notified when they need to be reconciled because the ownership of some of their objects changed
+func (o *OwnershipRegistry) GetEventChannel(kind string) <-chan event.GenericEvent {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	channel, ok := o.channels[kind]
+	if !ok {
+		channel = make(chan event.GenericEvent)
+		o.channels[kind] = channel
+	}
+	return channel
+}
+
+// Claim claims the ownership of the resources generated by a config and returns the ones the config can enforce, together with the description of the conflicts with other configs.
+// The resources the config can enforce are annotated with the ManagedByAnnotation and, when the config merges its objects, merged with the ones generated by the configs with a lower priority.
+// Existing objects missing the ManagedByAnnotation or the ownership stamp of the config are stamped, and stamped again whenever the stamp changes, for example when another config takes them over.
+// Objects generated by CreateOnly templates are never stamped after their creation.
+// Objects that were claimed by the config and are no longer generated are released.
+func (o *OwnershipRegistry) Claim(context context.Context, kind string, config client.Object, options ClaimOptions, resources []RenderedResource) ([]RenderedResource, []string, error) {
+	return o.ClaimAs(context, o.client, kind, config, options, resources)
+}
+
+// ClaimAs is like Claim, but stamps the existing objects with the given client, so that the namespaced configs stamp only the objects their service account is allowed to modify
+func (o *OwnershipRegistry) ClaimAs(context context.Context, kubeClient client.Client, kind string, config client.Object, options ClaimOptions, resources []RenderedResource) ([]RenderedResource, []string, error) {
+	ref := NewConfigReference(kind, config)
+
+	// objects that are not owned yet are checked against the live objects, which requires calling the API server without holding the lock
+	liveOwners := map[objectReference]ConfigReference{}
+	checked := map[objectReference]bool{}
+	liveStamped := map[objectReference]bool{}
+	for _, resource := range resources {
+		key := getObjectReference(&resource.Unstructured)
+		if o.isOwned(key) || checked[key] {
+			continue
+		}
+		checked[key] = true
+		liveObj, err := getLiveObject(context, o.client, &resource.Unstructured)
+		if err != nil {
+			o.log.Error(err, "unable to lookup", "object", key)
+			return []RenderedResource{}, []string{}, err
+		}
+		if liveObj == nil {
+			// the object is created with the stamp
+			liveStamped[key] = true
+			continue
+		}
+		annotation := liveObj.GetAnnotations()[ManagedByAnnotation]
+		if annotation == ref.String() && isStamped(liveObj, &resource.Unstructured) {
+			liveStamped[key] = true
+			continue
+		}
+		if liveOwner, ok := parseConfigReference(annotation); ok && annotation != ref.String() {
+			exists, err := o.configExists(context, liveOwner)
+			if err != nil {
+				return []RenderedResource{}, []string{}, err
+			}
+			if exists {
+				liveOwners[key] = liveOwner
+			}
+		}
+	}
+
+	allowed, conflicts, err := o.claim(ref, config, options, resources, liveOwners)
+	if err != nil {
+		o.log.Error(err, "unable to merge the objects generated by", "config", ref)
+		return []RenderedResource{}, []string{}, err
+	}
+
+	for _, resource := range allowed {
+		if resource.Mode == redhatcopv1alpha1.TemplateModeCreateOnly {
+			// objects generated by CreateOnly templates are left alone once they exist, they are stamped only when they are created
+			continue
+		}
+		key := getObjectReference(&resource.Unstructured)
+		patch, err := getOwnershipPatch(&resource.Unstructured, ref)
+		if err != nil {
+			return []RenderedResource{}, []string{}, err
+		}
+		if o.getLastStamp(key) == string(patch) {
+			continue
+		}
+		if !liveStamped[key] {
+			err = o.stampOwnership(context, kubeClient, &resource.Unstructured, patch)
+			if err != nil {
+				return []RenderedResource{}, []string{}, err
+			}
+		}
+		o.setLastStamp(key, string(patch))
+	}
+	return allowed, conflicts, nil
+}
+
+// getLastStamp returns the ownership patch last applied to an object, or the empty string if the object has not been stamped since the operator started
+func (o *OwnershipRegistry) getLastStamp(key objectReference) string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.stamps[key]
+}
+
+func (o *OwnershipRegistry) setLastStamp(key objectReference, patch string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.stamps[key] = patch
+}
+
+func (o *OwnershipRegistry) isOwned(key objectReference) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	_, ok := o.owners[key]
+	return ok
+}
+
+// claim updates the candidacies of a config and resolves the owners of its objects, it returns the resources the config can enforce and the conflicts
+func (o *OwnershipRegistry) claim(ref ConfigReference, config client.Object, options ClaimOptions, resources []RenderedResource, liveOwners map[objectReference]ConfigReference) ([]RenderedResource, []string, error) {
+	toNotify := map[ConfigReference]bool{}
+	defer func() {
+		o.notify(toNotify)
+	}()
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.configs[ref] = config.DeepCopyObject().(client.Object)
+
+	keys := []objectReference{}
+	rendered := map[objectReference]RenderedResource{}
+	for _, resource := range resources {
+		key := getObjectReference(&resource.Unstructured)
+		if _, ok := rendered[key]; !ok {
+			keys = append(keys, key)
+			rendered[key] = resource
+		}
+	}
+	for _, key := range o.claims[ref] {
+		if _, ok := rendered[key]; !ok {
+			o.removeCandidate(key, ref, toNotify)
+		}
+	}
+	o.claims[ref] = keys
+
+	allowed := []RenderedResource{}
+	conflicts := []string{}
+	for _, key := range keys {
+		resource := rendered[key]
+		changed := o.setCandidate(key, ref, options, &resource.Unstructured)
+		if liveOwner, ok := liveOwners[key]; ok && o.configs[liveOwner] == nil {
+			// the live object is managed by a config that exists but has not been reconciled yet since the operator started
+			conflicts = append(conflicts, key.String()+" is managed by "+liveOwner.String())
+			continue
+		}
+		owner := o.resolveOwner(key)
+		previousOwner, hadOwner := o.owners[key]
+		o.owners[key] = owner
+		if hadOwner && previousOwner != owner {
+			toNotify[previousOwner] = true
+		}
+		ownerCandidate := o.candidates[key][owner]
+		if owner != ref {
+			// a namespaced config outranked by a cluster level config cannot enforce the object, whatever their priorities
+			if isClusterLevel(owner) != isClusterLevel(ref) || ownerCandidate.Priority == options.Priority {
+				conflicts = append(conflicts, key.String()+" is managed by "+owner.String())
+			}
+			if changed && isClusterLevel(owner) == isClusterLevel(ref) && (ownerCandidate.Priority == options.Priority || ownerCandidate.MergeMode == redhatcopv1alpha1.MergeModeMerge) {
+				toNotify[owner] = true
+			}
+			continue
+		}
+		for other, otherCandidate := range o.candidates[key] {
+			if other != ref && isClusterLevel(other) == isClusterLevel(ref) && otherCandidate.Priority == options.Priority {
+				conflicts = append(conflicts, key.String()+" is also generated by "+other.String())
+			}
+		}
+		if options.MergeMode == redhatcopv1alpha1.MergeModeMerge {
+			merged, err := MergeObjects(o.getMergeOrder(key, ref)...)
+			if err != nil {
+				return []RenderedResource{}, []string{}, err
+			}
+			setStamp(merged, &resource.Unstructured)
+			resource.Unstructured = *merged
+		}
+		annotations := resource.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[ManagedByAnnotation] = ref.String()
+		resource.SetAnnotations(annotations)
+		allowed = append(allowed, resource)
+	}
+	delete(toNotify, ref)
+	sort.Strings(conflicts)
+	return allowed, conflicts, nil
+}
+
+// setCandidate must be called holding the lock, it returns whether the candidacy is new or changed
+func (o *OwnershipRegistry) setCandidate(key objectReference, ref ConfigReference, options ClaimOptions, obj *unstructured.Unstructured) bool {
+	if o.candidates[key] == nil {
+		o.candidates[key] = map[ConfigReference]*candidate{}
+	}
+	current, ok := o.candidates[key][ref]
+	if ok && current.ClaimOptions == options && equality.Semantic.DeepEqual(current.object.Object, obj.Object) {
+		return false
+	}
+	sequence := o.sequence
+	if ok {
+		sequence = current.sequence
+	} else {
+		o.sequence++
+	}
+	o.candidates[key][ref] = &candidate{
+		ClaimOptions: options,
+		object:       obj.DeepCopy(),
+		sequence:     sequence,
+	}
+	return true
+}
+
+// removeCandidate must be called holding the lock, the configs affected by the removal are notified so that they can take over the object or recompute it
+func (o *OwnershipRegistry) removeCandidate(key objectReference, ref ConfigReference, toNotify map[ConfigReference]bool) {
+	delete(o.candidates[key], ref)
+	owner, ok := o.owners[key]
+	if ok && owner == ref {
+		delete(o.owners, key)
+		for other := range o.candidates[key] {
+			toNotify[other] = true
+		}
+	} else if ok {
+		toNotify[owner] = true
+	}
+	if len(o.candidates[key]) == 0 {
+		delete(o.candidates, key)
+		delete(o.stamps, key)
+	}
+}
+
+// isClusterLevel returns whether a config is a cluster level config, the namespaced configs have a namespace
+func isClusterLevel(ref ConfigReference) bool {
+	return ref.Namespace == ""
+}
+
+// resolveOwner must be called holding the lock, it returns the candidate with the highest priority and, among those, the first one that claimed the object.
+// The cluster level candidates outrank the namespaced ones.
+func (o *OwnershipRegistry) resolveOwner(key objectReference) ConfigReference {
+	var owner ConfigReference
+	var ownerCandidate *candidate
+	for ref, candidate := range o.candidates[key] {
+		if ownerCandidate != nil && isClusterLevel(ref) != isClusterLevel(owner) {
+			if isClusterLevel(ref) {
+				owner, ownerCandidate = ref, candidate
+			}
+			continue
+		}
+		if ownerCandidate == nil || candidate.Priority > ownerCandidate.Priority || (candidate.Priority == ownerCandidate.Priority && candidate.sequence < ownerCandidate.sequence) {
+			owner, ownerCandidate = ref, candidate
+		}
+	}
+	return owner
+}
+
+// getMergeOrder must be called holding the lock, it returns the objects generated by the candidates with a lower priority than the owner, in increasing order of priority, followed by the object generated by the owner.
+// The objects generated by the namespaced candidates are not merged into the objects owned by a cluster level config.
+func (o *OwnershipRegistry) getMergeOrder(key objectReference, owner ConfigReference) []*unstructured.Unstructured {
+	ownerCandidate := o.candidates[key][owner]
+	candidates := []*candidate{}
+	for ref, candidate := range o.candidates[key] {
+		if isClusterLevel(owner) && !isClusterLevel(ref) {
+			continue
+		}
+		if candidate.Priority < ownerCandidate.Priority {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Priority != candidates[j].Priority {
+			return candidates[i].Priority < candidates[j].Priority
+		}
+		return candidates[i].sequence > candidates[j].sequence
+	})
+	objs := []*unstructured.Unstructured{}
+	for _, candidate := range candidates {
+		objs = append(objs, candidate.object)
+	}
+	return append(objs, ownerCandidate.object)
+}
+
+// Forget releases all the objects claimed by a config, it should be called when the config is deleted
+func (o *OwnershipRegistry) Forget(kind string, config client.Object) {
+	ref := NewConfigReference(kind, config)
+	toNotify := map[ConfigReference]bool{}
+	defer func() {
+		o.notify(toNotify)
+	}()
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for _, key := range o.claims[ref] {
+		o.removeCandidate(key, ref, toNotify)
+	}
+	delete(o.claims, ref)
+	delete(o.configs, ref)
+	delete(toNotify, ref)
+}
+
+func (o *OwnershipRegistry) notify(refs map[ConfigReference]bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for ref := range refs {
+		config, ok := o.configs[ref]
+		if !ok {
+			continue
+		}
+		channel, ok := o.channels[ref.Kind]
+		if !ok {
+			continue
+		}
+		// the controllers may be busy reconciling the config that is notifying, so the notification must not block
+		go func(config client.Object) {
+			channel <- event.GenericEvent{
+				Object: config,
+			}
+		}(config)
+	}
+}
+
+func (o *OwnershipRegistry) configExists(context context.Context, ref ConfigReference) (bool, error) {
+	config := &unstructured.Unstructured{}
+	config.SetGroupVersionKind(redhatcopv1alpha1.GroupVersion.WithKind(ref.Kind))
+	err := o.client.Get(context, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, config)
+	if err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		o.log.Error(err, "unable to lookup", "config", ref)
+		return false, err
+	}
+	return true, nil
+}
+
+// getOwnershipPatch returns the merge patch setting the ManagedByAnnotation and the ownership stamp of the generated object
+func getOwnershipPatch(obj *unstructured.Unstructured, ref ConfigReference) ([]byte, error) {
+	labels, annotations := getStamp(obj)
+	annotations[ManagedByAnnotation] = ref.String()
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      labels,
+			"annotations": annotations,
+		},
+	})
+}
+
+// stampOwnership applies the ownership patch to an existing object, metadata is not enforced so existing objects taken over by a config, or whose stamp changed, need to be stamped explicitly
+func (o *OwnershipRegistry) stampOwnership(context context.Context, kubeClient client.Client, obj *unstructured.Unstructured, patch []byte) error {
+	liveObj := &unstructured.Unstructured{}
+	liveObj.SetGroupVersionKind(obj.GroupVersionKind())
+	liveObj.SetNamespace(obj.GetNamespace())
+	liveObj.SetName(obj.GetName())
+	err := kubeClient.Patch(context, liveObj, client.RawPatch(types.MergePatchType, patch))
+	if err != nil && !errors.IsNotFound(err) {
+		o.log.Error(err, "unable to annotate", "object", obj)
+		return err
+	}
+	return nil
+}
+
+// WrapClient returns a client that does not delete the objects still generated by some config, so that the objects passing from a config to another are never deleted.
+// It must be used by the reconcilers enforcing the resources, which delete the resources that are no longer generated by a config.
+func (o *OwnershipRegistry) WrapClient(kubeClient client.Client) client.Client {
+	return &ownershipAwareClient{
+		Client:   kubeClient,
+		registry: o,
+	}
+}
+
+func (o *OwnershipRegistry) isGenerated(key objectReference) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.candidates[key]) > 0
+}
+
+type ownershipAwareClient struct {
+	client.Client
+	registry *OwnershipRegistry
+}
+
+func (c *ownershipAwareClient) Delete(context context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if unstructuredObj, ok := obj.(*unstructured.Unstructured); ok {
+		key := getObjectReference(unstructuredObj)
+		if c.registry.isGenerated(key) {
+			c.registry.log.V(1).Info("not deleting object still generated by another config", "object", key)
+			return nil
+		}
+	}
+	return c.Client.Delete(context, obj, opts...)
+}
+
+// SetConflictCondition returns the conditions with the ConflictCondition set according to the given conflicts
+func SetConflictCondition(conditions []metav1.Condition, conflicts []string, generation int64) []metav1.Condition {
+	condition := metav1.Condition{
+		Type:               ConflictCondition,
+		ObservedGeneration: generation,
+		Reason:             "NoConflict",
+		Status:             metav1.ConditionFalse,
+	}
+	if len(conflicts) > 0 {
+		condition.Reason = "ConflictingConfigs"
+		condition.Status = metav1.ConditionTrue
+		condition.Message = strings.Join(conflicts, "; ")
+	}
+	meta.SetStatusCondition(&conditions, condition)
+	return conditions
+}
@@ -0,0 +1,427 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var (
+	clusterA    = ConfigReference{Kind: "NamespaceConfig", Name: "a"}
+	clusterB    = ConfigReference{Kind: "NamespaceConfig", Name: "b"}
+	clusterC    = ConfigReference{Kind: "ResourceConfig", Name: "c"}
+	namespacedA = ConfigReference{Kind: "NamespacedResourceConfig", Namespace: "tenant", Name: "a"}
+	namespacedB = ConfigReference{Kind: "NamespacedResourceConfig", Namespace: "tenant", Name: "b"}
+)
+
+var testObjectReference = objectReference{Namespace: "tenant", Name: "cm"}
+
+// testCandidacy is a config claiming testObjectReference, the object it generates has its name in the data
+type testCandidacy struct {
+	ref      ConfigReference
+	priority int32
+}
+
+// newTestRegistry returns a registry in which the candidacies have been set in order
+func newTestRegistry(candidacies []testCandidacy) *OwnershipRegistry {
+	registry := NewOwnershipRegistry(nil, logr.Discard())
+	for _, candidacy := range candidacies {
+		obj := newTestObject("v1", "ConfigMap", "tenant", "cm", map[string]interface{}{"data": map[string]interface{}{"from": candidacy.ref.String()}})
+		registry.setCandidate(testObjectReference, candidacy.ref, ClaimOptions{Priority: candidacy.priority, MergeMode: redhatcopv1alpha1.MergeModeMerge}, obj)
+	}
+	return registry
+}
+
+func TestResolveOwner(t *testing.T) {
+	tests := []struct {
+		name        string
+		candidacies []testCandidacy
+		owner       ConfigReference
+	}{
+		{
+			name:        "single candidate",
+			candidacies: []testCandidacy{{ref: clusterA}},
+			owner:       clusterA,
+		},
+		{
+			name:        "highest priority",
+			candidacies: []testCandidacy{{ref: clusterA, priority: 1}, {ref: clusterB, priority: 5}, {ref: clusterC, priority: 3}},
+			owner:       clusterB,
+		},
+		{
+			name:        "first claim among the same priority",
+			candidacies: []testCandidacy{{ref: clusterB, priority: 2}, {ref: clusterA, priority: 2}, {ref: clusterC, priority: 1}},
+			owner:       clusterB,
+		},
+		{
+			name:        "cluster level outranks namespaced with a higher priority",
+			candidacies: []testCandidacy{{ref: namespacedA, priority: 100}, {ref: clusterA, priority: -1}},
+			owner:       clusterA,
+		},
+		{
+			name:        "highest priority among the cluster level candidates",
+			candidacies: []testCandidacy{{ref: namespacedA, priority: 100}, {ref: clusterA, priority: 1}, {ref: namespacedB, priority: 50}, {ref: clusterB, priority: 2}},
+			owner:       clusterB,
+		},
+		{
+			name:        "highest priority among the namespaced candidates",
+			candidacies: []testCandidacy{{ref: namespacedA, priority: 1}, {ref: namespacedB, priority: 2}},
+			owner:       namespacedB,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := newTestRegistry(test.candidacies)
+			// the candidates are kept in a map, resolving several times exercises different iteration orders
+			for i := 0; i < 20; i++ {
+				owner := registry.resolveOwner(testObjectReference)
+				if owner != test.owner {
+					t.Fatalf("expected owner %s, found %s", test.owner, owner)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMergeOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		candidacies []testCandidacy
+		owner       ConfigReference
+		order       []ConfigReference
+	}{
+		{
+			name:        "owner only",
+			candidacies: []testCandidacy{{ref: clusterA}},
+			owner:       clusterA,
+			order:       []ConfigReference{clusterA},
+		},
+		{
+			name:        "increasing priority",
+			candidacies: []testCandidacy{{ref: clusterA, priority: 5}, {ref: clusterB, priority: 1}, {ref: clusterC, priority: 3}},
+			owner:       clusterA,
+			order:       []ConfigReference{clusterB, clusterC, clusterA},
+		},
+		{
+			name:        "first claim takes precedence among the same priority",
+			candidacies: []testCandidacy{{ref: clusterA, priority: 5}, {ref: clusterB, priority: 1}, {ref: clusterC, priority: 1}},
+			owner:       clusterA,
+			order:       []ConfigReference{clusterC, clusterB, clusterA},
+		},
+		{
+			name:        "same priority as the owner is not merged",
+			candidacies: []testCandidacy{{ref: clusterA, priority: 5}, {ref: clusterB, priority: 5}},
+			owner:       clusterA,
+			order:       []ConfigReference{clusterA},
+		},
+		{
+			name:        "namespaced candidates are not merged into cluster level objects",
+			candidacies: []testCandidacy{{ref: clusterA, priority: 5}, {ref: namespacedA, priority: 1}, {ref: clusterB, priority: 2}},
+			owner:       clusterA,
+			order:       []ConfigReference{clusterB, clusterA},
+		},
+		{
+			name:        "namespaced candidates are merged into namespaced objects",
+			candidacies: []testCandidacy{{ref: namespacedA, priority: 5}, {ref: namespacedB, priority: 1}},
+			owner:       namespacedA,
+			order:       []ConfigReference{namespacedB, namespacedA},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := newTestRegistry(test.candidacies)
+			order := []ConfigReference{}
+			for _, obj := range registry.getMergeOrder(testObjectReference, test.owner) {
+				ref, _ := parseConfigReference(obj.Object["data"].(map[string]interface{})["from"].(string))
+				order = append(order, ref)
+			}
+			if !reflect.DeepEqual(order, test.order) {
+				t.Errorf("expected merge order %v, found %v", test.order, order)
+			}
+		})
+	}
+}
+
+func TestClaimAs(t *testing.T) {
+	type claim struct {
+		ref       ConfigReference
+		priority  int32
+		data      map[string]interface{}
+		allowed   bool
+		merged    map[string]interface{}
+		conflicts []string
+	}
+	tests := []struct {
+		name   string
+		claims []claim
+	}{
+		{
+			name: "first claim wins among the same priority",
+			claims: []claim{
+				{ref: clusterA, data: map[string]interface{}{"a": "1"}, allowed: true, merged: map[string]interface{}{"a": "1"}},
+				{ref: clusterB, data: map[string]interface{}{"b": "1"}, conflicts: []string{"ConfigMap/tenant/cm is managed by NamespaceConfig/a"}},
+			},
+		},
+		{
+			name: "higher priority merges the lower ones",
+			claims: []claim{
+				{ref: clusterA, priority: 1, data: map[string]interface{}{"a": "1", "x": "a"}, allowed: true, merged: map[string]interface{}{"a": "1", "x": "a"}},
+				{ref: clusterB, priority: 2, data: map[string]interface{}{"b": "2", "x": "b"}, allowed: true, merged: map[string]interface{}{"a": "1", "b": "2", "x": "b"}},
+			},
+		},
+		{
+			name: "namespaced configs neither take over nor are merged into a cluster level object",
+			claims: []claim{
+				{ref: clusterA, data: map[string]interface{}{"a": "1"}, allowed: true, merged: map[string]interface{}{"a": "1"}},
+				{ref: namespacedA, priority: 100, data: map[string]interface{}{"a": "2"}, conflicts: []string{"ConfigMap/tenant/cm is managed by NamespaceConfig/a"}},
+				{ref: namespacedB, priority: -1, data: map[string]interface{}{"b": "1"}, conflicts: []string{"ConfigMap/tenant/cm is managed by NamespaceConfig/a"}},
+				{ref: clusterA, data: map[string]interface{}{"a": "1"}, allowed: true, merged: map[string]interface{}{"a": "1"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := fake.NewClientBuilder().Build()
+			registry := NewOwnershipRegistry(kubeClient, logr.Discard())
+			for i, claim := range test.claims {
+				config := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: claim.ref.Namespace, Name: claim.ref.Name}}
+				obj := newTestObject("v1", "ConfigMap", "tenant", "cm", map[string]interface{}{"data": claim.data})
+				resources := []RenderedResource{{LockedResource: lockedresource.LockedResource{Unstructured: *obj}}}
+				allowed, conflicts, err := registry.ClaimAs(context.TODO(), kubeClient, claim.ref.Kind, config, ClaimOptions{Priority: claim.priority, MergeMode: redhatcopv1alpha1.MergeModeMerge}, resources)
+				if err != nil {
+					t.Fatalf("claim %d: unexpected error: %v", i, err)
+				}
+				if !reflect.DeepEqual(conflicts, append([]string{}, claim.conflicts...)) {
+					t.Errorf("claim %d: expected conflicts %v, found %v", i, claim.conflicts, conflicts)
+				}
+				if !claim.allowed {
+					if len(allowed) != 0 {
+						t.Errorf("claim %d: expected no allowed resource, found %v", i, allowed)
+					}
+					continue
+				}
+				if len(allowed) != 1 {
+					t.Fatalf("claim %d: expected one allowed resource, found %v", i, allowed)
+				}
+				if managedBy := allowed[0].GetAnnotations()[ManagedByAnnotation]; managedBy != claim.ref.String() {
+					t.Errorf("claim %d: expected to be managed by %s, found %s", i, claim.ref, managedBy)
+				}
+				data, _, _ := unstructured.NestedMap(allowed[0].Object, "data")
+				if !reflect.DeepEqual(data, claim.merged) {
+					t.Errorf("claim %d: expected data %v, found %v", i, claim.merged, data)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConfigReference(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		ref   ConfigReference
+		ok    bool
+	}{
+		{
+			name:  "cluster level config",
+			value: "NamespaceConfig/a",
+			ref:   clusterA,
+			ok:    true,
+		},
+		{
+			name:  "namespaced config",
+			value: "NamespacedResourceConfig/tenant/a",
+			ref:   namespacedA,
+			ok:    true,
+		},
+		{
+			name:  "missing name",
+			value: "NamespaceConfig/",
+		},
+		{
+			name:  "not a config",
+			value: "operator",
+		},
+		{
+			name:  "too many parts",
+			value: "NamespacedResourceConfig/tenant/a/b",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref, ok := parseConfigReference(test.value)
+			if ok != test.ok || ref != test.ref {
+				t.Errorf("expected %v %t, found %v %t", test.ref, test.ok, ref, ok)
+			}
+			if ok && ref.String() != test.value {
+				t.Errorf("expected %s, found %s", test.value, ref.String())
+			}
+		})
+	}
+}
+
+func TestClaimAsLiveOwner(t *testing.T) {
+	tests := []struct {
+		name      string
+		managedBy string
+		configs   []client.Object
+		allowed   bool
+		conflicts []string
+	}{
+		{
+			name:    "object not managed",
+			allowed: true,
+		},
+		{
+			name:      "object managed by the config",
+			managedBy: clusterA.String(),
+			allowed:   true,
+		},
+		{
+			name:      "object managed by a deleted config",
+			managedBy: clusterB.String(),
+			allowed:   true,
+		},
+		{
+			name:      "object managed by a config not reconciled yet",
+			managedBy: clusterB.String(),
+			configs:   []client.Object{&redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "b"}}},
+			conflicts: []string{"ConfigMap/tenant/cm is managed by NamespaceConfig/b"},
+		},
+		{
+			name:      "object managed by something else",
+			managedBy: "operator",
+			allowed:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			live := newTestObject("v1", "ConfigMap", "tenant", "cm", map[string]interface{}{"data": map[string]interface{}{"from": "live"}})
+			if test.managedBy != "" {
+				live.SetAnnotations(map[string]string{ManagedByAnnotation: test.managedBy})
+			}
+			kubeClient := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(append(test.configs, live)...).Build()
+			registry := NewOwnershipRegistry(kubeClient, logr.Discard())
+			config := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: clusterA.Name}}
+			obj := newTestObject("v1", "ConfigMap", "tenant", "cm", map[string]interface{}{"data": map[string]interface{}{"from": "a"}})
+			resources := []RenderedResource{{LockedResource: lockedresource.LockedResource{Unstructured: *obj}}}
+			allowed, conflicts, err := registry.ClaimAs(context.TODO(), kubeClient, clusterA.Kind, config, ClaimOptions{}, resources)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(conflicts, append([]string{}, test.conflicts...)) {
+				t.Errorf("expected conflicts %v, found %v", test.conflicts, conflicts)
+			}
+			if (len(allowed) == 1) != test.allowed {
+				t.Fatalf("expected allowed %t, found %v", test.allowed, allowed)
+			}
+			if !test.allowed {
+				return
+			}
+			err = kubeClient.Get(context.TODO(), client.ObjectKeyFromObject(live), live)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if managedBy := live.GetAnnotations()[ManagedByAnnotation]; managedBy != clusterA.String() {
+				t.Errorf("expected the live object to be managed by %s, found %s", clusterA, managedBy)
+			}
+		})
+	}
+}
+
+func TestForget(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+	registry := NewOwnershipRegistry(kubeClient, logr.Discard())
+	claim := func(ref ConfigReference) ([]RenderedResource, []string) {
+		config := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: ref.Namespace, Name: ref.Name}}
+		obj := newTestObject("v1", "ConfigMap", "tenant", "cm", map[string]interface{}{"data": map[string]interface{}{"from": ref.String()}})
+		resources := []RenderedResource{{LockedResource: lockedresource.LockedResource{Unstructured: *obj}}}
+		allowed, conflicts, err := registry.ClaimAs(context.TODO(), kubeClient, ref.Kind, config, ClaimOptions{}, resources)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return allowed, conflicts
+	}
+	claim(clusterA)
+	allowed, conflicts := claim(clusterB)
+	if len(allowed) != 0 || !reflect.DeepEqual(conflicts, []string{"ConfigMap/tenant/cm is managed by NamespaceConfig/a"}) {
+		t.Fatalf("expected a conflict with %s, found allowed %v and conflicts %v", clusterA, allowed, conflicts)
+	}
+	allowed, conflicts = claim(clusterA)
+	if len(allowed) != 1 || !reflect.DeepEqual(conflicts, []string{"ConfigMap/tenant/cm is also generated by NamespaceConfig/b"}) {
+		t.Fatalf("expected the owner to report the conflict with %s, found allowed %v and conflicts %v", clusterB, allowed, conflicts)
+	}
+
+	recorder := &deleteRecordingClient{Client: kubeClient}
+	wrapped := registry.WrapClient(recorder)
+	obj := newTestObject("v1", "ConfigMap", "tenant", "cm", nil)
+	registry.Forget(clusterA.Kind, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: clusterA.Name}})
+	_ = wrapped.Delete(context.TODO(), obj)
+	if deleted := recorder.deleted; len(deleted) != 0 {
+		t.Errorf("expected the object still generated by %s not to be deleted, found deleted %v", clusterB, deleted)
+	}
+	allowed, conflicts = claim(clusterB)
+	if len(allowed) != 1 || len(conflicts) != 0 {
+		t.Errorf("expected %s to take over the object, found allowed %v and conflicts %v", clusterB, allowed, conflicts)
+	}
+	registry.Forget(clusterB.Kind, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: clusterB.Name}})
+	_ = wrapped.Delete(context.TODO(), obj)
+	if deleted := recorder.deleted; len(deleted) != 1 {
+		t.Errorf("expected the object no longer generated to be deleted, found deleted %v", deleted)
+	}
+}
+
+// deleteRecordingClient records the objects it deletes
+type deleteRecordingClient struct {
+	client.Client
+	deleted []string
+}
+
+func (c *deleteRecordingClient) Delete(context context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.deleted = append(c.deleted, obj.GetName())
+	return nil
+}
+
+func TestSetConflictCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		conflicts []string
+		status    metav1.ConditionStatus
+		reason    string
+		message   string
+	}{
+		{
+			name:   "no conflict",
+			status: metav1.ConditionFalse,
+			reason: "NoConflict",
+		},
+		{
+			name:      "conflicts",
+			conflicts: []string{"ConfigMap/tenant/cm is managed by NamespaceConfig/a", "ConfigMap/tenant/other is managed by NamespaceConfig/b"},
+			status:    metav1.ConditionTrue,
+			reason:    "ConflictingConfigs",
+			message:   "ConfigMap/tenant/cm is managed by NamespaceConfig/a; ConfigMap/tenant/other is managed by NamespaceConfig/b",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conditions := SetConflictCondition([]metav1.Condition{}, test.conflicts, 3)
+			if len(conditions) != 1 {
+				t.Fatalf("expected one condition, found %v", conditions)
+			}
+			condition := conditions[0]
+			if condition.Type != ConflictCondition || condition.Status != test.status || condition.Reason != test.reason || condition.Message != test.message || condition.ObservedGeneration != 3 {
+				t.Errorf("unexpected condition %+v", condition)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"context"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigMapKind is the kind of a ValuesReference pointing to a ConfigMap
+	ConfigMapKind = "ConfigMap"
+	// SecretKind is the kind of a ValuesReference pointing to a Secret
+	SecretKind = "Secret"
+)
+
+// GetParameters returns the parameters of a config, obtained merging the data of the objects referenced by valuesFrom, in order, with the inline parameters.
+// Inline parameters take precedence.
+func GetParameters(context context.Context, reader client.Reader, parameters map[string]string, valuesFrom []redhatcopv1alpha1.ValuesReference) (map[string]string, error) {
+	result := map[string]string{}
+	for _, reference := range valuesFrom {
+		values, err := getValues(context, reader, reference)
+		if err != nil {
+			if apierrors.IsNotFound(err) && reference.Optional {
+				continue
+			}
+			return map[string]string{}, err
+		}
+		for key, value := range values {
+			result[key] = value
+		}
+	}
+	for key, value := range parameters {
+		result[key] = value
+	}
+	return result, nil
+}
+
+func getValues(context context.Context, reader client.Reader, reference redhatcopv1alpha1.ValuesReference) (map[string]string, error) {
+	key := types.NamespacedName{Name: reference.Name, Namespace: reference.Namespace}
+	if reference.Kind == SecretKind {
+		secret := &corev1.Secret{}
+		err := reader.Get(context, key, secret)
+		if err != nil {
+			return map[string]string{}, err
+		}
+		values := map[string]string{}
+		for name, value := range secret.Data {
+			values[name] = string(value)
+		}
+		return values, nil
+	}
+	configMap := &corev1.ConfigMap{}
+	err := reader.Get(context, key, configMap)
+	if err != nil {
+		return map[string]string{}, err
+	}
+	values := map[string]string{}
+	for name, value := range configMap.Data {
+		values[name] = value
+	}
+	return values, nil
+}
+
+// IsReferenced returns whether the ConfigMap or Secret identified by kind, namespace and name is one of the valuesFrom references
+func IsReferenced(valuesFrom []redhatcopv1alpha1.ValuesReference, kind string, object client.Object) bool {
+	for _, reference := range valuesFrom {
+		referenceKind := reference.Kind
+		if referenceKind == "" {
+			referenceKind = ConfigMapKind
+		}
+		if referenceKind == kind && reference.Name == object.GetName() && reference.Namespace == object.GetNamespace() {
+			return true
+		}
+	}
+	return false
+}
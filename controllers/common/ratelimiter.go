@@ -0,0 +1,18 @@
+package common
+
+import (
+	configv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/config/v1alpha1"
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+)
+
+// NewRateLimiter returns the rate limiter of the work queue of a controller, the maximum of an exponential backoff per config and of an overall token bucket, like the default one of controller-runtime.
+// Options that are not set fall back to the defaults of controller-runtime.
+func NewRateLimiter(options configv1alpha1.RateLimiter) ratelimiter.RateLimiter {
+	options.Default()
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(options.BaseDelay.Duration, options.MaxDelay.Duration),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(options.QPS), options.Burst)},
+	)
+}
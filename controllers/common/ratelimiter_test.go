@@ -0,0 +1,66 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	configv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/config/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		options configv1alpha1.RateLimiter
+		delays  []time.Duration
+	}{
+		{
+			name:   "defaults",
+			delays: []time.Duration{configv1alpha1.DefaultRateLimiterBaseDelay, 2 * configv1alpha1.DefaultRateLimiterBaseDelay, 4 * configv1alpha1.DefaultRateLimiterBaseDelay},
+		},
+		{
+			name: "custom backoff",
+			options: configv1alpha1.RateLimiter{
+				BaseDelay: &metav1.Duration{Duration: 10 * time.Millisecond},
+				MaxDelay:  &metav1.Duration{Duration: 40 * time.Millisecond},
+			},
+			delays: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter := NewRateLimiter(tt.options)
+			delays := []time.Duration{}
+			for range tt.delays {
+				delays = append(delays, rateLimiter.When("tenant"))
+			}
+			if !reflect.DeepEqual(delays, tt.delays) {
+				t.Errorf("When() = %v, want %v", delays, tt.delays)
+			}
+			if requeues := rateLimiter.NumRequeues("tenant"); requeues != len(tt.delays) {
+				t.Errorf("NumRequeues() = %v, want %v", requeues, len(tt.delays))
+			}
+			rateLimiter.Forget("tenant")
+			if delay := rateLimiter.When("tenant"); delay != tt.delays[0] {
+				t.Errorf("When() after Forget() = %v, want %v", delay, tt.delays[0])
+			}
+		})
+	}
+}
+
+func TestNewRateLimiterBucket(t *testing.T) {
+	rateLimiter := NewRateLimiter(configv1alpha1.RateLimiter{
+		BaseDelay: &metav1.Duration{Duration: time.Millisecond},
+		QPS:       1,
+		Burst:     2,
+	})
+	for _, config := range []string{"a", "b"} {
+		if delay := rateLimiter.When(config); delay != time.Millisecond {
+			t.Errorf("When(%v) within the burst = %v, want %v", config, delay, time.Millisecond)
+		}
+	}
+	if delay := rateLimiter.When("c"); delay < 500*time.Millisecond {
+		t.Errorf("When() beyond the burst = %v, want about a second", delay)
+	}
+}
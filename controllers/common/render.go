@@ -0,0 +1,111 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"text/template"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	utilstemplates "github.com/redhat-cop/operator-utils/pkg/util/templates"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/lru"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var renderLog = ctrl.Log.WithName("template-renderer")
+
+// LookupReference identifies the object, or the list of objects when Name is empty, read by a template with the lookup function
+type LookupReference struct {
+	schema.GroupVersionKind
+	Namespace string
+	Name      string
+}
+
+// RenderedResource is a resource generated by processing a template, together with the options of the template it has been generated from
+type RenderedResource struct {
+	lockedresource.LockedResource
+	// Wave of the template the resource has been generated from
+	Wave int32
+	// AdoptionPolicy of the template the resource has been generated from
+	AdoptionPolicy redhatcopv1alpha1.AdoptionPolicy
+	// Mode of the template the resource has been generated from
+	Mode redhatcopv1alpha1.TemplateMode
+	// TemplateIndex is the position of the template the resource has been generated from, among the processed templates
+	TemplateIndex int
+}
+
+// MaxParsedTemplates is the number of parsed templates kept in the cache, the least recently used ones are parsed again when needed
+const MaxParsedTemplates = 1000
+
+// parsedTemplates caches the parsed templates by their text, parsing is relatively expensive and the same templates are processed for every selected object.
+// The cache is bounded, so that the templates of the configs that have been modified or deleted do not accumulate.
+var parsedTemplates = lru.New(MaxParsedTemplates)
+
+// ProcessTemplates processes the templates with the given data and returns the resulting resources, together with the objects that have been looked up while processing them
+func ProcessTemplates(templates []redhatcopv1alpha1.ResourceTemplate, config *rest.Config, data TemplateData) ([]RenderedResource, []LookupReference, error) {
+	return ProcessNamespacedTemplates(templates, config, data, "")
+}
+
+// ProcessNamespacedTemplates processes the templates like ProcessTemplates, but the templates can look up only the objects in the given namespace.
+// It is used for the namespaced configs, whose authors must not read the objects of the other namespaces. There is no restriction if the namespace is empty.
+func ProcessNamespacedTemplates(templates []redhatcopv1alpha1.ResourceTemplate, config *rest.Config, data TemplateData, namespace string) ([]RenderedResource, []LookupReference, error) {
+	renderedResources := []RenderedResource{}
+	lookups := []LookupReference{}
+	ctx := context.WithValue(context.TODO(), "restConfig", config)
+	ctx = log.IntoContext(ctx, renderLog)
+	for i, resource := range templates {
+		parsedTemplate, err := getTemplate(resource.ObjectTemplate, config)
+		if err != nil {
+			renderLog.Error(err, "unable to parse", "template", resource.ObjectTemplate)
+			return []RenderedResource{}, []LookupReference{}, err
+		}
+		lookup := utilstemplates.NewLookupFunction(config, renderLog)
+		parsedTemplate = parsedTemplate.Funcs(data.funcs()).Funcs(template.FuncMap{
+			"lookup": func(apiversion string, kind string, lookupNamespace string, name string) (map[string]interface{}, error) {
+				if namespace != "" && lookupNamespace != namespace {
+					return nil, fmt.Errorf("unable to look up %s %s/%s, the templates can only look up objects in namespace %s", kind, lookupNamespace, name, namespace)
+				}
+				lookups = append(lookups, LookupReference{
+					GroupVersionKind: schema.FromAPIVersionAndKind(apiversion, kind),
+					Namespace:        lookupNamespace,
+					Name:             name,
+				})
+				return lookup(apiversion, kind, lookupNamespace, name)
+			},
+		})
+		objs, err := utilstemplates.ProcessTemplateArray(ctx, data.Object, parsedTemplate)
+		if err != nil {
+			renderLog.Error(err, "unable to process", "template", resource.ObjectTemplate)
+			return []RenderedResource{}, []LookupReference{}, err
+		}
+		for _, obj := range objs {
+			renderedResources = append(renderedResources, RenderedResource{
+				LockedResource: lockedresource.LockedResource{
+					Unstructured:  obj,
+					ExcludedPaths: resource.ExcludedPaths,
+				},
+				Wave:           resource.Wave,
+				AdoptionPolicy: resource.AdoptionPolicy,
+				Mode:           resource.Mode,
+				TemplateIndex:  i,
+			})
+		}
+	}
+	return renderedResources, lookups, nil
+}
+
+// getTemplate returns a private copy of the parsed template, so that its functions can be replaced safely
+func getTemplate(text string, config *rest.Config) (*template.Template, error) {
+	if cached, ok := parsedTemplates.Get(text); ok {
+		return cached.(*template.Template).Clone()
+	}
+	parsedTemplate, err := template.New(text).Funcs(utilstemplates.AdvancedTemplateFuncMap(config, renderLog)).Funcs(TemplateData{}.funcs()).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	parsedTemplates.Add(text, parsedTemplate)
+	return parsedTemplate.Clone()
+}
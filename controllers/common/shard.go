@@ -0,0 +1,33 @@
+package common
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// InShard tells whether a config belongs to the shard of this instance of the operator, that is whether its labels match the selector of the shard.
+// A nil selector selects all the configs.
+func InShard(selector labels.Selector, config client.Object) bool {
+	return selector == nil || selector.Matches(labels.Set(config.GetLabels()))
+}
+
+// ShardPredicate filters out the events of the configs that do not belong to the shard of this instance of the operator.
+// The updates of the configs leaving the shard are let through, so that the configs are released.
+func ShardPredicate(selector labels.Selector) predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return InShard(selector, e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return InShard(selector, e.ObjectOld) || InShard(selector, e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return InShard(selector, e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return InShard(selector, e.Object)
+		},
+	}
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestShardPredicate(t *testing.T) {
+	selector, err := labels.Parse("shard=a")
+	if err != nil {
+		t.Fatalf("unable to parse the selector: %v", err)
+	}
+	inShard := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "in", Labels: map[string]string{"shard": "a"}}}
+	otherShard := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"shard": "b"}}}
+	unlabeled := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}}
+	tests := []struct {
+		name     string
+		selector labels.Selector
+		create   *redhatcopv1alpha1.NamespaceConfig
+		old      *redhatcopv1alpha1.NamespaceConfig
+		new      *redhatcopv1alpha1.NamespaceConfig
+		want     bool
+	}{
+		{name: "config in the shard", selector: selector, create: inShard, old: inShard, new: inShard, want: true},
+		{name: "config in another shard", selector: selector, create: otherShard, old: otherShard, new: otherShard},
+		{name: "config without shard", selector: selector, create: unlabeled, old: unlabeled, new: unlabeled},
+		{name: "config joining the shard", selector: selector, create: inShard, old: otherShard, new: inShard, want: true},
+		{name: "config leaving the shard", selector: selector, create: inShard, old: inShard, new: otherShard, want: true},
+		{name: "no shard", create: unlabeled, old: otherShard, new: unlabeled, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := ShardPredicate(tt.selector)
+			if got := p.Create(event.CreateEvent{Object: tt.create}); got != InShard(tt.selector, tt.create) {
+				t.Errorf("Create() = %v, want %v", got, InShard(tt.selector, tt.create))
+			}
+			if got := p.Update(event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}); got != tt.want {
+				t.Errorf("Update() = %v, want %v", got, tt.want)
+			}
+			if got := p.Delete(event.DeleteEvent{Object: tt.new}); got != InShard(tt.selector, tt.new) {
+				t.Errorf("Delete() = %v, want %v", got, InShard(tt.selector, tt.new))
+			}
+			if got := p.Generic(event.GenericEvent{Object: tt.new}); got != InShard(tt.selector, tt.new) {
+				t.Errorf("Generic() = %v, want %v", got, InShard(tt.selector, tt.new))
+			}
+		})
+	}
+}
+
+func TestInShard(t *testing.T) {
+	selector, err := labels.Parse("shard in (a, b)")
+	if err != nil {
+		t.Fatalf("unable to parse the selector: %v", err)
+	}
+	tests := []struct {
+		name     string
+		selector labels.Selector
+		labels   map[string]string
+		want     bool
+	}{
+		{name: "matching label", selector: selector, labels: map[string]string{"shard": "b"}, want: true},
+		{name: "other label value", selector: selector, labels: map[string]string{"shard": "c"}},
+		{name: "no label", selector: selector},
+		{name: "nil selector", labels: map[string]string{"shard": "c"}, want: true},
+		{name: "empty selector", selector: labels.Everything(), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &redhatcopv1alpha1.ResourceConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", Labels: tt.labels}}
+			if got := InShard(tt.selector, config); got != tt.want {
+				t.Errorf("InShard() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package common
+
+import (
+	"sort"
+	"strconv"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigKindLabel is set on the generated objects to the kind of the config generating them
+	ConfigKindLabel = "redhatcop.redhat.io/config-kind"
+	// ConfigNamespaceLabel is set on the objects generated by the namespaced configs to the namespace of the config generating them
+	ConfigNamespaceLabel = "redhatcop.redhat.io/config-namespace"
+	// ConfigNameLabel is set on the generated objects to the name of the config generating them
+	ConfigNameLabel = "redhatcop.redhat.io/config-name"
+	// TargetKindLabel is set on the generated objects to the kind of the selected object they are generated for
+	TargetKindLabel = "redhatcop.redhat.io/target-kind"
+	// TargetNamespaceLabel is set on the generated objects to the namespace of the selected object they are generated for, when it is namespaced
+	TargetNamespaceLabel = "redhatcop.redhat.io/target-namespace"
+	// TargetNameLabel is set on the generated objects to the name of the selected object they are generated for
+	TargetNameLabel = "redhatcop.redhat.io/target-name"
+	// TargetUIDLabel is set on the generated objects to the uid of the selected object they are generated for
+	TargetUIDLabel = "redhatcop.redhat.io/target-uid"
+	// TemplateAnnotation is set on the generated objects to the template they are generated from, its index among the inline templates or <configtemplate>/<index> for the templates of a ConfigTemplate
+	TemplateAnnotation = "redhatcop.redhat.io/template"
+)
+
+// CopyFromTemplate is the value of the TemplateAnnotation on the objects copied with copyFrom
+const CopyFromTemplate = "copyFrom"
+
+// MaxReportedTargets is the maximum number of selected objects reported in the status of a config, to keep it within the size limits of an object
+const MaxReportedTargets = 100
+
+// OwnershipStamp identifies the config and the selected object a resource is generated for
+type OwnershipStamp struct {
+	Config     ConfigReference
+	TargetKind string
+	Target     client.Object
+}
+
+// NewOwnershipStamp returns the stamp of the resources generated by a config of the given kind for a selected object
+func NewOwnershipStamp(kind string, config client.Object, targetKind string, target client.Object) OwnershipStamp {
+	return OwnershipStamp{
+		Config:     NewConfigReference(kind, config),
+		TargetKind: targetKind,
+		Target:     target,
+	}
+}
+
+// getValues returns the values of the stamp, by label key
+func (s OwnershipStamp) getValues() map[string]string {
+	values := map[string]string{
+		ConfigKindLabel: s.Config.Kind,
+		ConfigNameLabel: s.Config.Name,
+		TargetKindLabel: s.TargetKind,
+		TargetNameLabel: s.Target.GetName(),
+		TargetUIDLabel:  string(s.Target.GetUID()),
+	}
+	if s.Config.Namespace != "" {
+		values[ConfigNamespaceLabel] = s.Config.Namespace
+	}
+	if s.Target.GetNamespace() != "" {
+		values[TargetNamespaceLabel] = s.Target.GetNamespace()
+	}
+	return values
+}
+
+// StampResources stamps the resources generated from the selected templates of a template set, indexes are the positions of the selected templates in the template set
+func StampResources(resources []RenderedResource, stamp OwnershipStamp, templateSet TemplateSet, indexes []int) {
+	for i := range resources {
+		index := strconv.Itoa(indexes[resources[i].TemplateIndex])
+		if templateSet.Name != "" {
+			index = templateSet.Name + "/" + index
+		}
+		stampResource(&resources[i], stamp, index)
+	}
+}
+
+// StampCopies stamps the objects copied with copyFrom
+func StampCopies(resources []RenderedResource, stamp OwnershipStamp) {
+	for i := range resources {
+		stampResource(&resources[i], stamp, CopyFromTemplate)
+	}
+}
+
+// stampResource sets the values of the stamp as annotations and, when they are valid label values, as labels, so that the generated objects can be listed with a label selector.
+// Names that are not valid label values, like the names of the users including an @, are set as annotations only, the uid of the selected object is always a valid label value.
+func stampResource(resource *RenderedResource, stamp OwnershipStamp, template string) {
+	labels := resource.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	annotations := resource.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for key, value := range stamp.getValues() {
+		annotations[key] = value
+		if len(validation.IsValidLabelValue(value)) == 0 {
+			labels[key] = value
+		}
+	}
+	annotations[TemplateAnnotation] = template
+	resource.SetLabels(labels)
+	resource.SetAnnotations(annotations)
+}
+
+// stampKeys are the keys of the labels and annotations of the ownership stamp
+var stampKeys = []string{ConfigKindLabel, ConfigNamespaceLabel, ConfigNameLabel, TargetKindLabel, TargetNamespaceLabel, TargetNameLabel, TargetUIDLabel, TemplateAnnotation}
+
+// getStamp returns the labels and annotations of the ownership stamp of an object
+func getStamp(obj *unstructured.Unstructured) (map[string]string, map[string]string) {
+	labels := map[string]string{}
+	annotations := map[string]string{}
+	for _, key := range stampKeys {
+		if value, ok := obj.GetLabels()[key]; ok {
+			labels[key] = value
+		}
+		if value, ok := obj.GetAnnotations()[key]; ok {
+			annotations[key] = value
+		}
+	}
+	return labels, annotations
+}
+
+// setStamp replaces the ownership stamp of an object with the one of another object, so that an object merged from the objects generated by several configs carries only the stamp of the owner
+func setStamp(obj *unstructured.Unstructured, from *unstructured.Unstructured) {
+	labels, annotations := getStamp(from)
+	objLabels := obj.GetLabels()
+	objAnnotations := obj.GetAnnotations()
+	for _, key := range stampKeys {
+		delete(objLabels, key)
+		delete(objAnnotations, key)
+	}
+	if len(labels) > 0 && objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	for key, value := range labels {
+		objLabels[key] = value
+	}
+	if len(annotations) > 0 && objAnnotations == nil {
+		objAnnotations = map[string]string{}
+	}
+	for key, value := range annotations {
+		objAnnotations[key] = value
+	}
+	obj.SetLabels(objLabels)
+	obj.SetAnnotations(objAnnotations)
+}
+
+// isStamped returns whether a live object has the ownership stamp of the generated object
+func isStamped(liveObj *unstructured.Unstructured, obj *unstructured.Unstructured) bool {
+	labels, annotations := getStamp(obj)
+	for key, value := range labels {
+		if liveObj.GetLabels()[key] != value {
+			return false
+		}
+	}
+	for key, value := range annotations {
+		if liveObj.GetAnnotations()[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// getTarget returns the selected object a resource has been generated for, in the kind/namespace/name format, and its uid, as stamped on the resource
+func getTarget(resource *RenderedResource) (string, string, bool) {
+	annotations := resource.GetAnnotations()
+	kind, ok := annotations[TargetKindLabel]
+	if !ok {
+		return "", "", false
+	}
+	return kind + "/" + annotations[TargetNamespaceLabel] + "/" + annotations[TargetNameLabel], annotations[TargetUIDLabel], true
+}
+
+// GetTargetStatuses returns the number of resources generated for each selected object, sorted by object
+func GetTargetStatuses(resources []RenderedResource) []redhatcopv1alpha1.TargetStatus {
+	counts := map[string]*redhatcopv1alpha1.TargetStatus{}
+	for i := range resources {
+		target, uid, ok := getTarget(&resources[i])
+		if !ok {
+			continue
+		}
+		status, ok := counts[target]
+		if !ok {
+			status = &redhatcopv1alpha1.TargetStatus{Target: target, UID: uid}
+			counts[target] = status
+		}
+		status.Resources++
+	}
+	statuses := []redhatcopv1alpha1.TargetStatus{}
+	for _, status := range counts {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Target < statuses[j].Target })
+	return statuses
+}
@@ -0,0 +1,197 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestStampedResource(templateIndex int) RenderedResource {
+	obj := newTestObject("v1", "ConfigMap", "team-a", "settings", nil)
+	obj.SetLabels(map[string]string{"app": "settings"})
+	return RenderedResource{LockedResource: lockedresource.LockedResource{Unstructured: *obj}, TemplateIndex: templateIndex}
+}
+
+func TestStampResources(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", UID: "1234"}}
+	user := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "alice@example.com", UID: "5678"}}
+	tests := []struct {
+		name        string
+		stamp       OwnershipStamp
+		templateSet TemplateSet
+		labels      map[string]string
+		annotations map[string]string
+	}{
+		{
+			name:        "inline template",
+			stamp:       NewOwnershipStamp("NamespaceConfig", &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}, "Namespace", namespace),
+			templateSet: TemplateSet{},
+			labels: map[string]string{
+				"app":           "settings",
+				ConfigKindLabel: "NamespaceConfig",
+				ConfigNameLabel: "tenant",
+				TargetKindLabel: "Namespace",
+				TargetNameLabel: "team-a",
+				TargetUIDLabel:  "1234",
+			},
+			annotations: map[string]string{
+				ConfigKindLabel:    "NamespaceConfig",
+				ConfigNameLabel:    "tenant",
+				TargetKindLabel:    "Namespace",
+				TargetNameLabel:    "team-a",
+				TargetUIDLabel:     "1234",
+				TemplateAnnotation: "2",
+			},
+		},
+		{
+			name:        "template of a ConfigTemplate generated by a namespaced config",
+			stamp:       NewOwnershipStamp("NamespacedResourceConfig", &redhatcopv1alpha1.NamespacedResourceConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "tenant"}}, "ConfigMap", &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "source", UID: "9012"}}),
+			templateSet: TemplateSet{Name: "quotas"},
+			labels: map[string]string{
+				"app":                "settings",
+				ConfigKindLabel:      "NamespacedResourceConfig",
+				ConfigNamespaceLabel: "team-a",
+				ConfigNameLabel:      "tenant",
+				TargetKindLabel:      "ConfigMap",
+				TargetNamespaceLabel: "team-a",
+				TargetNameLabel:      "source",
+				TargetUIDLabel:       "9012",
+			},
+			annotations: map[string]string{
+				ConfigKindLabel:      "NamespacedResourceConfig",
+				ConfigNamespaceLabel: "team-a",
+				ConfigNameLabel:      "tenant",
+				TargetKindLabel:      "ConfigMap",
+				TargetNamespaceLabel: "team-a",
+				TargetNameLabel:      "source",
+				TargetUIDLabel:       "9012",
+				TemplateAnnotation:   "quotas/2",
+			},
+		},
+		{
+			name:        "name that is not a valid label value",
+			stamp:       NewOwnershipStamp("UserConfig", &redhatcopv1alpha1.UserConfig{ObjectMeta: metav1.ObjectMeta{Name: "users"}}, "User", user),
+			templateSet: TemplateSet{},
+			labels: map[string]string{
+				"app":           "settings",
+				ConfigKindLabel: "UserConfig",
+				ConfigNameLabel: "users",
+				TargetKindLabel: "User",
+				TargetUIDLabel:  "5678",
+			},
+			annotations: map[string]string{
+				ConfigKindLabel:    "UserConfig",
+				ConfigNameLabel:    "users",
+				TargetKindLabel:    "User",
+				TargetNameLabel:    "alice@example.com",
+				TargetUIDLabel:     "5678",
+				TemplateAnnotation: "2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the resource is generated from the second selected template, which is the third of the template set
+			resources := []RenderedResource{newTestStampedResource(1)}
+			StampResources(resources, tt.stamp, tt.templateSet, []int{0, 2})
+			if !reflect.DeepEqual(resources[0].GetLabels(), tt.labels) {
+				t.Errorf("labels = %v, want %v", resources[0].GetLabels(), tt.labels)
+			}
+			if !reflect.DeepEqual(resources[0].GetAnnotations(), tt.annotations) {
+				t.Errorf("annotations = %v, want %v", resources[0].GetAnnotations(), tt.annotations)
+			}
+		})
+	}
+}
+
+func TestStampCopies(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", UID: "1234"}}
+	stamp := NewOwnershipStamp("NamespaceConfig", &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}, "Namespace", namespace)
+	resources := []RenderedResource{newTestStampedResource(0), newTestStampedResource(0)}
+	StampCopies(resources, stamp)
+	for _, resource := range resources {
+		if template := resource.GetAnnotations()[TemplateAnnotation]; template != CopyFromTemplate {
+			t.Errorf("template = %v, want %v", template, CopyFromTemplate)
+		}
+		if name := resource.GetLabels()[ConfigNameLabel]; name != "tenant" {
+			t.Errorf("config name = %v, want %v", name, "tenant")
+		}
+	}
+}
+
+func TestIsStamped(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", UID: "1234"}}
+	stamp := NewOwnershipStamp("NamespaceConfig", &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}, "Namespace", namespace)
+	resources := []RenderedResource{newTestStampedResource(0)}
+	StampCopies(resources, stamp)
+	obj := &resources[0].Unstructured
+	tests := []struct {
+		name   string
+		update func(labels map[string]string, annotations map[string]string)
+		want   bool
+	}{
+		{
+			name: "same stamp",
+			update: func(labels map[string]string, annotations map[string]string) {
+			},
+			want: true,
+		},
+		{
+			name: "additional labels and annotations",
+			update: func(labels map[string]string, annotations map[string]string) {
+				labels["team"] = "a"
+				annotations["note"] = "edited"
+			},
+			want: true,
+		},
+		{
+			name: "label of another target",
+			update: func(labels map[string]string, annotations map[string]string) {
+				labels[TargetUIDLabel] = "5678"
+			},
+		},
+		{
+			name: "annotation removed",
+			update: func(labels map[string]string, annotations map[string]string) {
+				delete(annotations, TemplateAnnotation)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			liveObj := obj.DeepCopy()
+			labels := liveObj.GetLabels()
+			annotations := liveObj.GetAnnotations()
+			tt.update(labels, annotations)
+			liveObj.SetLabels(labels)
+			liveObj.SetAnnotations(annotations)
+			if got := isStamped(liveObj, obj); got != tt.want {
+				t.Errorf("isStamped() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTargetStatuses(t *testing.T) {
+	teamA := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", UID: "1234"}}
+	teamB := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", UID: "5678"}}
+	config := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}
+	resources := []RenderedResource{newTestStampedResource(0), newTestStampedResource(0), newTestStampedResource(0), newTestStampedResource(0)}
+	StampCopies(resources[0:1], NewOwnershipStamp("NamespaceConfig", config, "Namespace", teamB))
+	StampCopies(resources[1:3], NewOwnershipStamp("NamespaceConfig", config, "Namespace", teamA))
+	statuses := GetTargetStatuses(resources)
+	want := []redhatcopv1alpha1.TargetStatus{
+		{Target: "Namespace//team-a", UID: "1234", Resources: 2},
+		{Target: "Namespace//team-b", UID: "5678", Resources: 1},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("GetTargetStatuses() = %v, want %v", statuses, want)
+	}
+	if statuses := GetTargetStatuses([]RenderedResource{}); len(statuses) != 0 {
+		t.Errorf("GetTargetStatuses() = %v without resources, want none", statuses)
+	}
+}
@@ -0,0 +1,76 @@
+package common
+
+import (
+	"fmt"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TemplateData is the data used to process the templates for a selected object.
+// The selected object is the root of the templates, as it has always been, so that templates can refer to .Name, .Labels and so on and `toJson .` serializes the object only.
+// The other data are exposed through template functions: parameters and parameter, config, cluster and parents.
+type TemplateData struct {
+	// Object is the selected object
+	Object interface{}
+	// Parameters are the parameters of the config, exposed by parameters and parameter
+	Parameters map[string]string
+	// Config describes the config processing the templates, exposed by config
+	Config map[string]interface{}
+	// Cluster describes the cluster, exposed by cluster
+	Cluster ClusterInfo
+	// Parents are the ancestors of a selected namespace, from its parent to the root, exposed by parents
+	Parents []corev1.Namespace
+}
+
+// NewTemplateData returns the data used by a config of the given kind to process the templates for a selected object
+func NewTemplateData(object interface{}, kind string, config client.Object, cluster ClusterInfo) TemplateData {
+	return TemplateData{
+		Object:     object,
+		Parameters: map[string]string{},
+		Config: map[string]interface{}{
+			"Kind":        kind,
+			"Name":        config.GetName(),
+			"Namespace":   config.GetNamespace(),
+			"Labels":      config.GetLabels(),
+			"Annotations": config.GetAnnotations(),
+		},
+		Cluster: cluster,
+		Parents: []corev1.Namespace{},
+	}
+}
+
+// WithParameters returns a copy of the data with the given parameters
+func (d TemplateData) WithParameters(parameters map[string]string) TemplateData {
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+	d.Parameters = parameters
+	return d
+}
+
+// funcs returns the template functions exposing the data
+func (d TemplateData) funcs() template.FuncMap {
+	return template.FuncMap{
+		"parameters": func() map[string]string {
+			return d.Parameters
+		},
+		"parameter": func(name string) (string, error) {
+			value, ok := d.Parameters[name]
+			if !ok {
+				return "", fmt.Errorf("parameter %s is not defined", name)
+			}
+			return value, nil
+		},
+		"config": func() map[string]interface{} {
+			return d.Config
+		},
+		"cluster": func() ClusterInfo {
+			return d.Cluster
+		},
+		"parents": func() []corev1.Namespace {
+			return d.Parents
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+)
+
+func TestTemplateDataFunctions(t *testing.T) {
+	namespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a-dev", Labels: map[string]string{"team": "a"}}}
+	config := &redhatcopv1alpha1.NamespaceConfig{ObjectMeta: metav1.ObjectMeta{Name: "tenant", Labels: map[string]string{"tier": "gold"}}}
+	cluster := ClusterInfo{
+		BaseDomain:         "example.com",
+		APIServerURL:       "https://api.example.com:6443",
+		InfrastructureName: "prod-x7k2p",
+	}
+	data := NewTemplateData(namespace, "NamespaceConfig", config, cluster).WithParameters(map[string]string{"quota": "10"})
+	data.Parents = []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}}
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "selected object",
+			template: "{{ .Name }}-{{ .Labels.team }}",
+			want:     "team-a-dev-a",
+		},
+		{
+			name:     "config",
+			template: "{{ (config).Kind }}/{{ (config).Name }}/{{ (config).Labels.tier }}",
+			want:     "NamespaceConfig/tenant/gold",
+		},
+		{
+			name:     "cluster",
+			template: "{{ (cluster).BaseDomain }} {{ (cluster).APIServerURL }} {{ (cluster).InfrastructureName }}",
+			want:     "example.com https://api.example.com:6443 prod-x7k2p",
+		},
+		{
+			name:     "parameters",
+			template: `{{ parameter "quota" }}/{{ (parameters).quota }}`,
+			want:     "10/10",
+		},
+		{
+			name:     "undefined parameter",
+			template: `{{ parameter "limit" }}`,
+			wantErr:  true,
+		},
+		{
+			name:     "parents",
+			template: "{{ range parents }}{{ .Name }}{{ end }}",
+			want:     "team-a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := redhatcopv1alpha1.ResourceTemplate{}
+			template.ObjectTemplate = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  value: \"" + tt.template + "\"\n"
+			resources, _, err := ProcessTemplates([]redhatcopv1alpha1.ResourceTemplate{template}, &rest.Config{}, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessTemplates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(resources) != 1 {
+				t.Fatalf("ProcessTemplates() = %v, want a single resource", resources)
+			}
+			value, _, _ := unstructured.NestedString(resources[0].Object, "data", "value")
+			if value != tt.want {
+				t.Errorf("value = %v, want %v", value, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package common
+
+import (
+	"context"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/scylladb/go-set/strset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TemplateSet is a group of templates that are processed with the same parameters
+type TemplateSet struct {
+	// Name is the name of the ConfigTemplate the templates come from, it is empty for the inline templates
+	Name       string
+	Templates  []redhatcopv1alpha1.ResourceTemplate
+	Parameters map[string]string
+}
+
+// GetTemplateSets returns the templates defined inline in a config, to be processed with the config parameters, followed by the templates of each referenced ConfigTemplate.
+// The parameters of a referenced ConfigTemplate are obtained merging, in increasing order of precedence, the ConfigTemplate defaults, the config parameters and the reference parameters.
+func GetTemplateSets(context context.Context, reader client.Reader, templates []redhatcopv1alpha1.ResourceTemplate, templateRefs []redhatcopv1alpha1.TemplateReference, parameters map[string]string) ([]TemplateSet, error) {
+	templateSets := []TemplateSet{}
+	if len(templates) > 0 {
+		templateSets = append(templateSets, TemplateSet{
+			Templates:  templates,
+			Parameters: parameters,
+		})
+	}
+	for _, templateRef := range templateRefs {
+		configTemplate := &redhatcopv1alpha1.ConfigTemplate{}
+		err := reader.Get(context, types.NamespacedName{Name: templateRef.Name}, configTemplate)
+		if err != nil {
+			return []TemplateSet{}, err
+		}
+		templateSets = append(templateSets, TemplateSet{
+			Name:       templateRef.Name,
+			Templates:  withDefaultExcludedPaths(configTemplate.Spec.Templates),
+			Parameters: mergeParameters(configTemplate.Spec.Parameters, parameters, templateRef.Parameters),
+		})
+	}
+	return templateSets, nil
+}
+
+// IsTemplateReferenced returns whether the ConfigTemplate with the given name is one of the templateRefs
+func IsTemplateReferenced(templateRefs []redhatcopv1alpha1.TemplateReference, name string) bool {
+	for _, templateRef := range templateRefs {
+		if templateRef.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectTemplates returns the templates that apply to the given object, that is the ones without a when clause and the ones whose when clause matches the object labels and annotations,
+// together with their positions in the given templates
+func SelectTemplates(templates []redhatcopv1alpha1.ResourceTemplate, object metav1.Object) ([]redhatcopv1alpha1.ResourceTemplate, []int, error) {
+	result := []redhatcopv1alpha1.ResourceTemplate{}
+	indexes := []int{}
+	for i, template := range templates {
+		matches, err := matchesTemplateSelector(template.When, object)
+		if err != nil {
+			return []redhatcopv1alpha1.ResourceTemplate{}, []int{}, err
+		}
+		if matches {
+			result = append(result, template)
+			indexes = append(indexes, i)
+		}
+	}
+	return result, indexes, nil
+}
+
+func matchesTemplateSelector(when *redhatcopv1alpha1.TemplateSelector, object metav1.Object) (bool, error) {
+	if when == nil {
+		return true, nil
+	}
+	return MatchesSelectors(when.LabelSelector, when.AnnotationSelector, object)
+}
+
+// MatchesSelectors tells whether the labels of an object match the label selector and its annotations match the annotation selector, a nil selector matches any object
+func MatchesSelectors(labelSelector *metav1.LabelSelector, annotationSelector *metav1.LabelSelector, object metav1.Object) (bool, error) {
+	if labelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+		if err != nil {
+			return false, err
+		}
+		if !selector.Matches(labels.Set(object.GetLabels())) {
+			return false, nil
+		}
+	}
+	if annotationSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(annotationSelector)
+		if err != nil {
+			return false, err
+		}
+		if !selector.Matches(labels.Set(object.GetAnnotations())) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// withDefaultExcludedPaths returns a copy of the templates in which the excluded paths always include the DefaultExcludedPaths.
+// Inline templates get the same treatment when the config is initialized, but ConfigTemplates are shared and are never updated by the operator.
+func withDefaultExcludedPaths(templates []redhatcopv1alpha1.ResourceTemplate) []redhatcopv1alpha1.ResourceTemplate {
+	result := []redhatcopv1alpha1.ResourceTemplate{}
+	for _, template := range templates {
+		template := *template.DeepCopy()
+		template.ExcludedPaths = strset.Union(DefaultExcludedPathsSet, strset.New(template.ExcludedPaths...)).List()
+		result = append(result, template)
+	}
+	return result
+}
+
+func mergeParameters(parameters ...map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, params := range parameters {
+		for key, value := range params {
+			result[key] = value
+		}
+	}
+	return result
+}
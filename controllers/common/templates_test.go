@@ -0,0 +1,273 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/scylladb/go-set/strset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMatchesSelectors(t *testing.T) {
+	object := &metav1.ObjectMeta{
+		Name:        "web",
+		Namespace:   "team-a",
+		Labels:      map[string]string{"app": "web", "tier": "frontend"},
+		Annotations: map[string]string{"example.com/managed": "true"},
+	}
+	tests := []struct {
+		name               string
+		labelSelector      *metav1.LabelSelector
+		annotationSelector *metav1.LabelSelector
+		matches            bool
+		wantErr            bool
+	}{
+		{
+			name:    "no selectors",
+			matches: true,
+		},
+		{
+			name:               "empty selectors",
+			labelSelector:      &metav1.LabelSelector{},
+			annotationSelector: &metav1.LabelSelector{},
+			matches:            true,
+		},
+		{
+			name:          "matching labels",
+			labelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			matches:       true,
+		},
+		{
+			name:          "labels not matching",
+			labelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			matches:       false,
+		},
+		{
+			name:               "matching labels and annotations",
+			labelSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}},
+			annotationSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"example.com/managed": "true"}},
+			matches:            true,
+		},
+		{
+			name:               "matching labels and annotations not matching",
+			labelSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}},
+			annotationSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"example.com/managed": "false"}},
+			matches:            false,
+		},
+		{
+			name: "annotation expression",
+			annotationSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "example.com/managed", Operator: metav1.LabelSelectorOpExists},
+			}},
+			matches: true,
+		},
+		{
+			name: "invalid selector",
+			labelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: "Matches"},
+			}},
+			matches: false,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchesSelectors(tt.labelSelector, tt.annotationSelector, object)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MatchesSelectors() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.matches {
+				t.Errorf("MatchesSelectors() = %v, want %v", got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestGetTemplateSets(t *testing.T) {
+	inline := redhatcopv1alpha1.ResourceTemplate{}
+	inline.ObjectTemplate = "kind: ConfigMap"
+	shared := redhatcopv1alpha1.ResourceTemplate{}
+	shared.ObjectTemplate = "kind: ResourceQuota"
+	shared.ExcludedPaths = []string{".spec.hard"}
+	configTemplate := &redhatcopv1alpha1.ConfigTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "quota"},
+		Spec: redhatcopv1alpha1.ConfigTemplateSpec{
+			Templates:  []redhatcopv1alpha1.ResourceTemplate{shared},
+			Parameters: map[string]string{"cpu": "1", "memory": "1Gi", "pods": "10"},
+		},
+	}
+	reader := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(configTemplate).Build()
+	tests := []struct {
+		name         string
+		templates    []redhatcopv1alpha1.ResourceTemplate
+		templateRefs []redhatcopv1alpha1.TemplateReference
+		parameters   map[string]string
+		want         []TemplateSet
+		wantErr      bool
+	}{
+		{
+			name: "no templates",
+			want: []TemplateSet{},
+		},
+		{
+			name:       "inline templates",
+			templates:  []redhatcopv1alpha1.ResourceTemplate{inline},
+			parameters: map[string]string{"cpu": "2"},
+			want: []TemplateSet{
+				{Templates: []redhatcopv1alpha1.ResourceTemplate{inline}, Parameters: map[string]string{"cpu": "2"}},
+			},
+		},
+		{
+			name:         "inline templates followed by the referenced ones",
+			templates:    []redhatcopv1alpha1.ResourceTemplate{inline},
+			templateRefs: []redhatcopv1alpha1.TemplateReference{{Name: "quota", Parameters: map[string]string{"memory": "4Gi"}}},
+			parameters:   map[string]string{"cpu": "2", "memory": "2Gi"},
+			want: []TemplateSet{
+				{Templates: []redhatcopv1alpha1.ResourceTemplate{inline}, Parameters: map[string]string{"cpu": "2", "memory": "2Gi"}},
+				{Name: "quota", Templates: withDefaultExcludedPaths([]redhatcopv1alpha1.ResourceTemplate{shared}), Parameters: map[string]string{"cpu": "2", "memory": "4Gi", "pods": "10"}},
+			},
+		},
+		{
+			name:         "missing template",
+			templateRefs: []redhatcopv1alpha1.TemplateReference{{Name: "network"}},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetTemplateSets(context.TODO(), reader, tt.templates, tt.templateRefs, tt.parameters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetTemplateSets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, templateSets := range [][]TemplateSet{got, tt.want} {
+				for _, templateSet := range templateSets {
+					for i := range templateSet.Templates {
+						sort.Strings(templateSet.Templates[i].ExcludedPaths)
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetTemplateSets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if !strset.New(withDefaultExcludedPaths([]redhatcopv1alpha1.ResourceTemplate{shared})[0].ExcludedPaths...).Has(".spec.hard") {
+		t.Errorf("withDefaultExcludedPaths() dropped the excluded paths of the template")
+	}
+	if len(configTemplate.Spec.Templates[0].ExcludedPaths) != 1 {
+		t.Errorf("withDefaultExcludedPaths() modified the ConfigTemplate, ExcludedPaths = %v", configTemplate.Spec.Templates[0].ExcludedPaths)
+	}
+}
+
+func TestIsTemplateReferenced(t *testing.T) {
+	templateRefs := []redhatcopv1alpha1.TemplateReference{{Name: "quota"}, {Name: "network"}}
+	tests := []struct {
+		name       string
+		template   string
+		referenced bool
+	}{
+		{
+			name:       "referenced template",
+			template:   "network",
+			referenced: true,
+		},
+		{
+			name:       "template not referenced",
+			template:   "sandbox",
+			referenced: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTemplateReferenced(templateRefs, tt.template); got != tt.referenced {
+				t.Errorf("IsTemplateReferenced() = %v, want %v", got, tt.referenced)
+			}
+		})
+	}
+}
+
+func TestSelectTemplates(t *testing.T) {
+	template := func(name string, when *redhatcopv1alpha1.TemplateSelector) redhatcopv1alpha1.ResourceTemplate {
+		template := redhatcopv1alpha1.ResourceTemplate{When: when}
+		template.ObjectTemplate = "name: " + name
+		return template
+	}
+	templates := []redhatcopv1alpha1.ResourceTemplate{
+		template("always", nil),
+		template("production", &redhatcopv1alpha1.TemplateSelector{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "production"}}}),
+		template("monitored", &redhatcopv1alpha1.TemplateSelector{AnnotationSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "example.com/monitoring", Operator: metav1.LabelSelectorOpExists},
+		}}}),
+		template("monitored production", &redhatcopv1alpha1.TemplateSelector{
+			LabelSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"env": "production"}},
+			AnnotationSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "example.com/monitoring", Operator: metav1.LabelSelectorOpExists}}},
+		}),
+	}
+	tests := []struct {
+		name      string
+		templates []redhatcopv1alpha1.ResourceTemplate
+		object    *metav1.ObjectMeta
+		indexes   []int
+		wantErr   bool
+	}{
+		{
+			name:      "object without labels",
+			templates: templates,
+			object:    &metav1.ObjectMeta{Name: "sandbox"},
+			indexes:   []int{0},
+		},
+		{
+			name:      "labelled object",
+			templates: templates,
+			object:    &metav1.ObjectMeta{Name: "shop", Labels: map[string]string{"env": "production"}},
+			indexes:   []int{0, 1},
+		},
+		{
+			name:      "annotated object",
+			templates: templates,
+			object:    &metav1.ObjectMeta{Name: "shop", Annotations: map[string]string{"example.com/monitoring": "enabled"}},
+			indexes:   []int{0, 2},
+		},
+		{
+			name:      "labelled and annotated object",
+			templates: templates,
+			object:    &metav1.ObjectMeta{Name: "shop", Labels: map[string]string{"env": "production"}, Annotations: map[string]string{"example.com/monitoring": "enabled"}},
+			indexes:   []int{0, 1, 2, 3},
+		},
+		{
+			name: "invalid when clause",
+			templates: []redhatcopv1alpha1.ResourceTemplate{
+				template("invalid", &redhatcopv1alpha1.TemplateSelector{LabelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Matches"}}}}),
+			},
+			object:  &metav1.ObjectMeta{Name: "shop"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, indexes, err := SelectTemplates(tt.templates, tt.object)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelectTemplates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(indexes, tt.indexes) {
+				t.Errorf("SelectTemplates() indexes = %v, want %v", indexes, tt.indexes)
+			}
+			for i, index := range indexes {
+				if !reflect.DeepEqual(selected[i], tt.templates[index]) {
+					t.Errorf("SelectTemplates() template %d = %v, want %v", i, selected[i], tt.templates[index])
+				}
+			}
+		})
+	}
+}
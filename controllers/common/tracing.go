@@ -0,0 +1,82 @@
+package common
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/redhat-cop/namespace-configuration-operator"
+
+// attributes of the spans
+const (
+	ConfigKindAttribute = attribute.Key("config.kind")
+	ConfigNameAttribute = attribute.Key("config.name")
+	TargetKindAttribute = attribute.Key("target.kind")
+	TargetNameAttribute = attribute.Key("target.name")
+	SelectedAttribute   = attribute.Key("selected.count")
+	ResourcesAttribute  = attribute.Key("resources.count")
+)
+
+// TracingOptions configures the export of the traces
+type TracingOptions struct {
+	// Endpoint is the host:port of the OTLP gRPC collector, tracing is disabled if empty
+	Endpoint string
+	// Insecure disables the transport security of the connection to the collector
+	Insecure bool
+	// SampleRatio is the fraction of the reconciliations that are traced
+	SampleRatio float64
+}
+
+// SetupTracing installs a tracer provider exporting the spans to the OTLP collector of the options, it returns the function to flush and stop the export.
+// When no endpoint is configured nothing is installed, so that the spans are not recorded.
+func SetupTracing(ctx context.Context, options TracingOptions) (func(context.Context) error, error) {
+	if options.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporterOptions := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(options.Endpoint)}
+	if options.Insecure {
+		exporterOptions = append(exporterOptions, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOptions...)
+	if err != nil {
+		return nil, err
+	}
+	traceResource, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName("namespace-configuration-operator")))
+	if err != nil {
+		return nil, err
+	}
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(traceResource),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tracerProvider.Shutdown, nil
+}
+
+// StartSpan starts a span for a phase of the reconciliation of a config, carrying the kind and name of the config
+func StartSpan(context context.Context, name string, configKind string, configName string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(context, name, trace.WithAttributes(append([]attribute.KeyValue{
+		ConfigKindAttribute.String(configKind),
+		ConfigNameAttribute.String(configName),
+	}, attributes...)...))
+}
+
+// EndSpan ends a span, recording the error of the phase if any
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+	tests := []struct {
+		name       string
+		attributes []attribute.KeyValue
+		err        error
+		status     codes.Code
+		want       map[attribute.Key]string
+	}{
+		{
+			name:   "successful phase",
+			status: codes.Unset,
+			want:   map[attribute.Key]string{ConfigKindAttribute: "NamespaceConfig", ConfigNameAttribute: "tenant"},
+		},
+		{
+			name:       "phase with attributes",
+			attributes: []attribute.KeyValue{TargetKindAttribute.String("Namespace"), TargetNameAttribute.String("team-a")},
+			status:     codes.Unset,
+			want:       map[attribute.Key]string{ConfigKindAttribute: "NamespaceConfig", ConfigNameAttribute: "tenant", TargetKindAttribute: "Namespace", TargetNameAttribute: "team-a"},
+		},
+		{
+			name:   "failed phase",
+			err:    errors.New("unable to render"),
+			status: codes.Error,
+			want:   map[attribute.Key]string{ConfigKindAttribute: "NamespaceConfig", ConfigNameAttribute: "tenant"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, span := StartSpan(context.TODO(), tt.name, "NamespaceConfig", "tenant", tt.attributes...)
+			EndSpan(span, tt.err)
+			ended := recorder.Ended()
+			recorded := ended[len(ended)-1]
+			if recorded.Name() != tt.name {
+				t.Fatalf("span = %v, want %v", recorded.Name(), tt.name)
+			}
+			attributes := map[attribute.Key]string{}
+			for _, attribute := range recorded.Attributes() {
+				attributes[attribute.Key] = attribute.Value.AsString()
+			}
+			for key, value := range tt.want {
+				if attributes[key] != value {
+					t.Errorf("attribute %v = %v, want %v", key, attributes[key], value)
+				}
+			}
+			if len(attributes) != len(tt.want) {
+				t.Errorf("attributes = %v, want %v", attributes, tt.want)
+			}
+			if recorded.Status().Code != tt.status {
+				t.Errorf("status = %v, want %v", recorded.Status().Code, tt.status)
+			}
+			if tt.err != nil && (len(recorded.Events()) != 1 || recorded.Status().Description != tt.err.Error()) {
+				t.Errorf("events = %v, status = %v, want the error recorded", recorded.Events(), recorded.Status())
+			}
+		})
+	}
+}
+
+func TestSetupTracingDisabled(t *testing.T) {
+	shutdown, err := SetupTracing(context.TODO(), TracingOptions{})
+	if err != nil {
+		t.Fatalf("SetupTracing() error = %v", err)
+	}
+	if err := shutdown(context.TODO()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package common
+
+import (
+	"context"
+	"sort"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var namespaceGroupKind = schema.GroupKind{Kind: "Namespace"}
+var crdGroupKind = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+var deploymentGroupKind = schema.GroupKind{Group: "apps", Kind: "Deployment"}
+var statefulSetGroupKind = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+var daemonSetGroupKind = schema.GroupKind{Group: "apps", Kind: "DaemonSet"}
+var jobGroupKind = schema.GroupKind{Group: "batch", Kind: "Job"}
+var persistentVolumeClaimGroupKind = schema.GroupKind{Kind: "PersistentVolumeClaim"}
+
+// readyOnceCreatedGroupKinds are the kinds whose objects do not report their readiness, they are ready as soon as they exist
+var readyOnceCreatedGroupKinds = map[schema.GroupKind]bool{
+	{Kind: "ConfigMap"}:      true,
+	{Kind: "Secret"}:         true,
+	{Kind: "ServiceAccount"}: true,
+	{Kind: "Service"}:        true,
+	{Kind: "LimitRange"}:     true,
+	{Kind: "ResourceQuota"}:  true,
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:               true,
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:        true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:        true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}: true,
+	{Group: "networking.k8s.io", Kind: "NetworkPolicy"}:              true,
+}
+
+// GateWaves returns the resources that can be enforced. The resources generated for each target are gated on their own, so that a target whose resources are not ready does not hold back the other targets:
+// for each target, they are the resources of all the waves up to the first one containing resources that are not ready.
+// The resources of the following waves are returned only if they already exist, so that they keep being enforced. The first of those waves among all the targets is reported as blocked, waiting for the resources of all the targets that are not ready.
+// The resources whose readiness gates a following wave are returned as well, so that they can be watched and the config reconciled when their readiness changes.
+func GateWaves(context context.Context, reader client.Reader, resources []RenderedResource) ([]RenderedResource, *redhatcopv1alpha1.BlockedWaveStatus, []LookupReference, error) {
+	targets := []string{}
+	resourcesByTarget := map[string][]RenderedResource{}
+	for i := range resources {
+		target, _, _ := getTarget(&resources[i])
+		if _, ok := resourcesByTarget[target]; !ok {
+			targets = append(targets, target)
+		}
+		resourcesByTarget[target] = append(resourcesByTarget[target], resources[i])
+	}
+
+	allowed := []RenderedResource{}
+	gates := []LookupReference{}
+	var blockedWave *redhatcopv1alpha1.BlockedWaveStatus
+	for _, target := range targets {
+		targetAllowed, targetBlockedWave, targetGates, err := gateTargetWaves(context, reader, resourcesByTarget[target])
+		if err != nil {
+			return []RenderedResource{}, nil, []LookupReference{}, err
+		}
+		allowed = append(allowed, targetAllowed...)
+		gates = append(gates, targetGates...)
+		if targetBlockedWave == nil {
+			continue
+		}
+		if blockedWave == nil {
+			blockedWave = targetBlockedWave
+			continue
+		}
+		if targetBlockedWave.Wave < blockedWave.Wave {
+			blockedWave.Wave = targetBlockedWave.Wave
+		}
+		blockedWave.WaitingFor = append(blockedWave.WaitingFor, targetBlockedWave.WaitingFor...)
+	}
+	return allowed, blockedWave, gates, nil
+}
+
+// gateTargetWaves gates by wave the resources generated for a single target
+func gateTargetWaves(context context.Context, reader client.Reader, resources []RenderedResource) ([]RenderedResource, *redhatcopv1alpha1.BlockedWaveStatus, []LookupReference, error) {
+	waves := map[int32][]RenderedResource{}
+	for _, resource := range resources {
+		waves[resource.Wave] = append(waves[resource.Wave], resource)
+	}
+	waveNumbers := []int32{}
+	for wave := range waves {
+		waveNumbers = append(waveNumbers, wave)
+	}
+	sort.Slice(waveNumbers, func(i, j int) bool { return waveNumbers[i] < waveNumbers[j] })
+
+	allowed := []RenderedResource{}
+	gates := []LookupReference{}
+	var blockedWave *redhatcopv1alpha1.BlockedWaveStatus
+	for i, wave := range waveNumbers {
+		if blockedWave != nil {
+			for _, resource := range waves[wave] {
+				obj, err := getLiveObject(context, reader, &resource.Unstructured)
+				if err != nil {
+					return []RenderedResource{}, nil, []LookupReference{}, err
+				}
+				if obj != nil {
+					allowed = append(allowed, resource)
+				}
+			}
+			continue
+		}
+		allowed = append(allowed, waves[wave]...)
+		if i == len(waveNumbers)-1 {
+			break
+		}
+		notReady := []string{}
+		for _, resource := range waves[wave] {
+			gates = append(gates, LookupReference{
+				GroupVersionKind: resource.GroupVersionKind(),
+				Namespace:        resource.GetNamespace(),
+				Name:             resource.GetName(),
+			})
+			obj, err := getLiveObject(context, reader, &resource.Unstructured)
+			if err != nil {
+				return []RenderedResource{}, nil, []LookupReference{}, err
+			}
+			if obj == nil || !IsReady(obj) {
+				notReady = append(notReady, resource.GetKind()+"/"+resource.GetNamespace()+"/"+resource.GetName())
+			}
+		}
+		if len(notReady) > 0 {
+			blockedWave = &redhatcopv1alpha1.BlockedWaveStatus{
+				Wave:       waveNumbers[i+1],
+				WaitingFor: notReady,
+			}
+		}
+	}
+	return allowed, blockedWave, gates, nil
+}
+
+// IsReady returns whether the object is ready.
+// Namespaces must be Active, CustomResourceDefinitions must be Established, PersistentVolumeClaims must be Bound and Jobs must be Complete.
+// Deployments, StatefulSets and DaemonSets must have observed their current generation and have all their replicas available.
+// The objects of the kinds that do not report their readiness, like ConfigMaps or Roles, are ready as soon as they exist. Any other object is ready only when its Ready condition is True.
+func IsReady(obj *unstructured.Unstructured) bool {
+	groupKind := obj.GroupVersionKind().GroupKind()
+	switch groupKind {
+	case namespaceGroupKind:
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		return phase == "Active"
+	case crdGroupKind:
+		status, found := getConditionStatus(obj, "Established")
+		return found && status == "True"
+	case persistentVolumeClaimGroupKind:
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		return phase == "Bound"
+	case jobGroupKind:
+		status, found := getConditionStatus(obj, "Complete")
+		return found && status == "True"
+	case deploymentGroupKind:
+		return hasObservedGeneration(obj) && getReplicas(obj, "spec", "replicas") <= getReplicas(obj, "status", "availableReplicas") && getReplicas(obj, "spec", "replicas") <= getReplicas(obj, "status", "updatedReplicas")
+	case statefulSetGroupKind:
+		return hasObservedGeneration(obj) && getReplicas(obj, "spec", "replicas") <= getReplicas(obj, "status", "readyReplicas") && getReplicas(obj, "spec", "replicas") <= getReplicas(obj, "status", "updatedReplicas")
+	case daemonSetGroupKind:
+		return hasObservedGeneration(obj) && getReplicas(obj, "status", "desiredNumberScheduled") <= getReplicas(obj, "status", "numberAvailable") && getReplicas(obj, "status", "desiredNumberScheduled") <= getReplicas(obj, "status", "updatedNumberScheduled")
+	}
+	if readyOnceCreatedGroupKinds[groupKind] {
+		return true
+	}
+	status, found := getConditionStatus(obj, "Ready")
+	return found && status == "True"
+}
+
+// hasObservedGeneration returns whether the controller of the object has observed its current generation, so that its status reflects its current spec
+func hasObservedGeneration(obj *unstructured.Unstructured) bool {
+	observedGeneration, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	return observedGeneration >= obj.GetGeneration()
+}
+
+// getReplicas returns the number of replicas in the given field, the spec defaults to one replica and the status to none
+func getReplicas(obj *unstructured.Unstructured, fields ...string) int64 {
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, fields...)
+	if !found && fields[0] == "spec" {
+		return 1
+	}
+	return replicas
+}
+
+func getConditionStatus(obj *unstructured.Unstructured, conditionType string) (string, bool) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if conditionMap["type"] == conditionType {
+			status, _ := conditionMap["status"].(string)
+			return status, true
+		}
+	}
+	return "", false
+}
+
+// getLiveObject returns the current state of the object, or nil if it does not exist. Objects whose kind is not known yet, for example because it is defined by a CRD of a previous wave, do not exist either.
+func getLiveObject(context context.Context, reader client.Reader, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	liveObj := &unstructured.Unstructured{}
+	liveObj.SetGroupVersionKind(obj.GroupVersionKind())
+	err := reader.Get(context, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, liveObj)
+	if err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return liveObj, nil
+}
@@ -0,0 +1,259 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func withConditions(conditions ...map[string]interface{}) map[string]interface{} {
+	list := []interface{}{}
+	for _, condition := range conditions {
+		list = append(list, condition)
+	}
+	return map[string]interface{}{"status": map[string]interface{}{"conditions": list}}
+}
+
+func TestIsReady(t *testing.T) {
+	tests := []struct {
+		name  string
+		obj   *unstructured.Unstructured
+		ready bool
+	}{
+		{
+			name:  "active namespace",
+			obj:   newTestObject("v1", "Namespace", "", "ns", map[string]interface{}{"status": map[string]interface{}{"phase": "Active"}}),
+			ready: true,
+		},
+		{
+			name: "terminating namespace",
+			obj:  newTestObject("v1", "Namespace", "", "ns", map[string]interface{}{"status": map[string]interface{}{"phase": "Terminating"}}),
+		},
+		{
+			name: "namespace without phase",
+			obj:  newTestObject("v1", "Namespace", "", "ns", nil),
+		},
+		{
+			name:  "established crd",
+			obj:   newTestObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com", withConditions(map[string]interface{}{"type": "Established", "status": "True"})),
+			ready: true,
+		},
+		{
+			name: "crd not established",
+			obj:  newTestObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com", withConditions(map[string]interface{}{"type": "NamesAccepted", "status": "True"})),
+		},
+		{
+			name:  "object of a kind without readiness",
+			obj:   newTestObject("v1", "ConfigMap", "ns", "cm", nil),
+			ready: true,
+		},
+		{
+			name:  "role",
+			obj:   newTestObject("rbac.authorization.k8s.io/v1", "RoleBinding", "ns", "admin", nil),
+			ready: true,
+		},
+		{
+			name: "object without conditions",
+			obj:  newTestObject("example.com/v1", "Widget", "ns", "widget", nil),
+		},
+		{
+			name: "object without ready condition",
+			obj:  newTestObject("example.com/v1", "Widget", "ns", "widget", withConditions(map[string]interface{}{"type": "Synced", "status": "False"})),
+		},
+		{
+			name:  "available deployment",
+			obj:   newTestObject("apps/v1", "Deployment", "ns", "web", map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}, "status": map[string]interface{}{"observedGeneration": int64(1), "availableReplicas": int64(2), "updatedReplicas": int64(2)}}),
+			ready: true,
+		},
+		{
+			name: "deployment without available replicas",
+			obj:  newTestObject("apps/v1", "Deployment", "ns", "web", map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}, "status": map[string]interface{}{"observedGeneration": int64(1), "availableReplicas": int64(1), "updatedReplicas": int64(2)}}),
+		},
+		{
+			name: "deployment without status",
+			obj:  newTestObject("apps/v1", "Deployment", "ns", "web", nil),
+		},
+		{
+			name: "deployment with an old generation",
+			obj: func() *unstructured.Unstructured {
+				obj := newTestObject("apps/v1", "Deployment", "ns", "web", map[string]interface{}{"status": map[string]interface{}{"observedGeneration": int64(1), "availableReplicas": int64(1), "updatedReplicas": int64(1)}})
+				obj.SetGeneration(2)
+				return obj
+			}(),
+		},
+		{
+			name:  "ready statefulset",
+			obj:   newTestObject("apps/v1", "StatefulSet", "ns", "db", map[string]interface{}{"status": map[string]interface{}{"readyReplicas": int64(1), "updatedReplicas": int64(1)}}),
+			ready: true,
+		},
+		{
+			name:  "available daemonset",
+			obj:   newTestObject("apps/v1", "DaemonSet", "ns", "agent", map[string]interface{}{"status": map[string]interface{}{"desiredNumberScheduled": int64(3), "numberAvailable": int64(3), "updatedNumberScheduled": int64(3)}}),
+			ready: true,
+		},
+		{
+			name: "daemonset not available",
+			obj:  newTestObject("apps/v1", "DaemonSet", "ns", "agent", map[string]interface{}{"status": map[string]interface{}{"desiredNumberScheduled": int64(3), "numberAvailable": int64(2), "updatedNumberScheduled": int64(3)}}),
+		},
+		{
+			name:  "complete job",
+			obj:   newTestObject("batch/v1", "Job", "ns", "migrate", withConditions(map[string]interface{}{"type": "Complete", "status": "True"})),
+			ready: true,
+		},
+		{
+			name: "running job",
+			obj:  newTestObject("batch/v1", "Job", "ns", "migrate", nil),
+		},
+		{
+			name:  "bound claim",
+			obj:   newTestObject("v1", "PersistentVolumeClaim", "ns", "data", map[string]interface{}{"status": map[string]interface{}{"phase": "Bound"}}),
+			ready: true,
+		},
+		{
+			name: "pending claim",
+			obj:  newTestObject("v1", "PersistentVolumeClaim", "ns", "data", map[string]interface{}{"status": map[string]interface{}{"phase": "Pending"}}),
+		},
+		{
+			name:  "ready object",
+			obj:   newTestObject("example.com/v1", "Widget", "ns", "widget", withConditions(map[string]interface{}{"type": "Synced", "status": "False"}, map[string]interface{}{"type": "Ready", "status": "True"})),
+			ready: true,
+		},
+		{
+			name: "object not ready",
+			obj:  newTestObject("example.com/v1", "Widget", "ns", "widget", withConditions(map[string]interface{}{"type": "Ready", "status": "False"})),
+		},
+		{
+			name: "object with unknown readiness",
+			obj:  newTestObject("example.com/v1", "Widget", "ns", "widget", withConditions(map[string]interface{}{"type": "Ready", "status": "Unknown"})),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if ready := IsReady(test.obj); ready != test.ready {
+				t.Errorf("expected ready %t, found %t", test.ready, ready)
+			}
+		})
+	}
+}
+
+func TestGateWaves(t *testing.T) {
+	resource := func(wave int32, kind string, name string) RenderedResource {
+		obj := newTestObject("v1", kind, "ns", name, nil)
+		if kind == "Namespace" {
+			obj.SetNamespace("")
+		}
+		return RenderedResource{LockedResource: lockedresource.LockedResource{Unstructured: *obj}, Wave: wave}
+	}
+	targetResource := func(wave int32, kind string, name string, target string) RenderedResource {
+		resource := resource(wave, kind, name)
+		resource.SetAnnotations(map[string]string{TargetKindLabel: "Group", TargetNameLabel: target})
+		return resource
+	}
+	activeNamespace := newTestObject("v1", "Namespace", "", "ns", map[string]interface{}{"status": map[string]interface{}{"phase": "Active"}})
+	readyWidget := newTestObject("v1", "Widget", "ns", "ready", withConditions(map[string]interface{}{"type": "Ready", "status": "True"}))
+	terminatingNamespace := newTestObject("v1", "Namespace", "", "ns", map[string]interface{}{"status": map[string]interface{}{"phase": "Terminating"}})
+	existingConfigMap := newTestObject("v1", "ConfigMap", "ns", "later", nil)
+
+	tests := []struct {
+		name        string
+		live        []*unstructured.Unstructured
+		resources   []RenderedResource
+		allowed     []string
+		blockedWave *redhatcopv1alpha1.BlockedWaveStatus
+		gates       []string
+	}{
+		{
+			name:      "single wave",
+			resources: []RenderedResource{resource(0, "ConfigMap", "a"), resource(0, "ConfigMap", "b")},
+			allowed:   []string{"ConfigMap/a", "ConfigMap/b"},
+			gates:     []string{},
+		},
+		{
+			name:      "ready waves",
+			live:      []*unstructured.Unstructured{activeNamespace},
+			resources: []RenderedResource{resource(1, "ConfigMap", "a"), resource(0, "Namespace", "ns")},
+			allowed:   []string{"Namespace/ns", "ConfigMap/a"},
+			gates:     []string{"Namespace/ns"},
+		},
+		{
+			name:        "missing gate",
+			resources:   []RenderedResource{resource(1, "ConfigMap", "a"), resource(0, "Namespace", "ns")},
+			allowed:     []string{"Namespace/ns"},
+			blockedWave: &redhatcopv1alpha1.BlockedWaveStatus{Wave: 1, WaitingFor: []string{"Namespace//ns"}},
+			gates:       []string{"Namespace/ns"},
+		},
+		{
+			name:        "gate not ready",
+			live:        []*unstructured.Unstructured{terminatingNamespace},
+			resources:   []RenderedResource{resource(0, "Namespace", "ns"), resource(5, "ConfigMap", "a")},
+			allowed:     []string{"Namespace/ns"},
+			blockedWave: &redhatcopv1alpha1.BlockedWaveStatus{Wave: 5, WaitingFor: []string{"Namespace//ns"}},
+			gates:       []string{"Namespace/ns"},
+		},
+		{
+			name:        "existing resources of the blocked waves keep being enforced",
+			live:        []*unstructured.Unstructured{existingConfigMap},
+			resources:   []RenderedResource{resource(0, "Namespace", "ns"), resource(1, "ConfigMap", "a"), resource(2, "ConfigMap", "later")},
+			allowed:     []string{"Namespace/ns", "ConfigMap/later"},
+			blockedWave: &redhatcopv1alpha1.BlockedWaveStatus{Wave: 1, WaitingFor: []string{"Namespace//ns"}},
+			gates:       []string{"Namespace/ns"},
+		},
+		{
+			name: "targets gated on their own",
+			live: []*unstructured.Unstructured{readyWidget},
+			resources: []RenderedResource{
+				targetResource(0, "Widget", "ready", "team-a"), targetResource(1, "ConfigMap", "a", "team-a"),
+				targetResource(0, "Widget", "pending", "team-b"), targetResource(1, "ConfigMap", "b", "team-b"),
+			},
+			allowed:     []string{"Widget/ready", "ConfigMap/a", "Widget/pending"},
+			blockedWave: &redhatcopv1alpha1.BlockedWaveStatus{Wave: 1, WaitingFor: []string{"Widget/ns/pending"}},
+			gates:       []string{"Widget/ready", "Widget/pending"},
+		},
+		{
+			name: "first blocked wave among the targets",
+			resources: []RenderedResource{
+				targetResource(1, "Widget", "late", "team-a"), targetResource(2, "ConfigMap", "a", "team-a"),
+				targetResource(0, "Widget", "early", "team-b"), targetResource(3, "ConfigMap", "b", "team-b"),
+			},
+			allowed:     []string{"Widget/late", "Widget/early"},
+			blockedWave: &redhatcopv1alpha1.BlockedWaveStatus{Wave: 2, WaitingFor: []string{"Widget/ns/late", "Widget/ns/early"}},
+			gates:       []string{"Widget/late", "Widget/early"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objs := []client.Object{}
+			for _, obj := range test.live {
+				objs = append(objs, obj.DeepCopy())
+			}
+			reader := fake.NewClientBuilder().WithObjects(objs...).Build()
+			allowed, blockedWave, gates, err := GateWaves(context.TODO(), reader, test.resources)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			allowedNames := []string{}
+			for _, resource := range allowed {
+				allowedNames = append(allowedNames, resource.GetKind()+"/"+resource.GetName())
+			}
+			if !reflect.DeepEqual(allowedNames, test.allowed) {
+				t.Errorf("expected allowed resources %v, found %v", test.allowed, allowedNames)
+			}
+			if !reflect.DeepEqual(blockedWave, test.blockedWave) {
+				t.Errorf("expected blocked wave %+v, found %+v", test.blockedWave, blockedWave)
+			}
+			gateNames := []string{}
+			for _, gate := range gates {
+				gateNames = append(gateNames, gate.Kind+"/"+gate.Name)
+			}
+			if !reflect.DeepEqual(gateNames, test.gates) {
+				t.Errorf("expected gates %v, found %v", test.gates, gateNames)
+			}
+		})
+	}
+}
@@ -20,22 +20,24 @@ import (
 	"context"
 
 	"github.com/go-logr/logr"
-	userv1 "github.com/openshift/api/user/v1"
 	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
 	"github.com/redhat-cop/namespace-configuration-operator/controllers/common"
 	"github.com/redhat-cop/operator-utils/pkg/util"
 	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller"
-	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedpatch"
-	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
-	"github.com/scylladb/go-set/strset"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -43,13 +45,26 @@ import (
 // GroupConfigReconciler reconciles a GroupConfig object
 type GroupConfigReconciler struct {
 	lockedresourcecontroller.EnforcingReconciler
-	Log            logr.Logger
-	controllerName string
+	Log                     logr.Logger
+	controllerName          string
+	lookupWatcher           *common.LookupWatcher
+	applier                 *common.Applier
+	enforcer                *common.Enforcer
+	Ownership               *common.OwnershipRegistry
+	IdentitySource          common.IdentitySource
+	MaxConcurrentReconciles int
+	RateLimiter             ratelimiter.RateLimiter
+	ConfigSelector          labels.Selector
+	Cluster                 common.ClusterInfo
+	controller              controller.Controller
+	cache                   cache.Cache
+	identityEvents          chan event.GenericEvent
 }
 
 // +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=groupconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=groupconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=groupconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=configtemplates,verbs=get;list;watch
 // +kubebuilder:rbac:groups=*,resources=*,verbs=*
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -63,6 +78,8 @@ type GroupConfigReconciler struct {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
 func (r *GroupConfigReconciler) Reconcile(context context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("groupconfig", req.NamespacedName)
+	context, span := common.StartSpan(context, "Reconcile", "GroupConfig", req.Name)
+	defer span.End()
 
 	// Fetch the GroupConfig instance
 	instance := &redhatcopv1alpha1.GroupConfig{}
@@ -77,8 +94,13 @@ func (r *GroupConfigReconciler) Reconcile(context context.Context, req ctrl.Requ
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
+	if !common.InShard(r.ConfigSelector, instance) {
+		// the instance moved to the shard of another operator, which takes over its resources, status and finalizer
+		log.Info("releasing instance outside of the shard of this operator")
+		return reconcile.Result{}, r.enforcer.Release(instance)
+	}
 
-	if !r.IsInitialized(instance) {
+	if !common.IsInitialized(instance, r.controllerName) {
 		err := r.GetClient().Update(context, instance)
 		if err != nil {
 			log.Error(err, "unable to update instance", "instance", instance)
@@ -91,7 +113,7 @@ func (r *GroupConfigReconciler) Reconcile(context context.Context, req ctrl.Requ
 		if !util.HasFinalizer(instance, r.controllerName) {
 			return reconcile.Result{}, nil
 		}
-		err := r.manageCleanUpLogic(instance)
+		err := r.enforcer.Delete(context, instance)
 		if err != nil {
 			log.Error(err, "unable to delete instance", "instance", instance)
 			return r.ManageError(context, instance, err)
@@ -106,67 +128,84 @@ func (r *GroupConfigReconciler) Reconcile(context context.Context, req ctrl.Requ
 	}
 
 	//get selected users
-	selectedGroups, err := r.getSelectedGroups(context, instance)
+	selectContext, selectSpan := common.StartSpan(context, "getSelectedGroups", "GroupConfig", instance.GetName())
+	selectedGroups, err := r.getSelectedGroups(selectContext, instance)
+	selectSpan.SetAttributes(common.SelectedAttribute.Int(len(selectedGroups)))
+	common.EndSpan(selectSpan, err)
 	if err != nil {
 		log.Error(err, "unable to get groups selected by", "GroupConfig", instance)
-		return r.ManageError(context, instance, err)
+		return r.enforcer.ManageError(context, instance, common.SelectorInvalidReason, err)
 	}
+	common.SelectedObjects.WithLabelValues("GroupConfig", instance.GetName()).Set(float64(len(selectedGroups)))
+	instance.Status.SelectedCount = int32(len(selectedGroups))
 
-	lockedResources, err := r.getResourceList(instance, selectedGroups)
+	parameters, err := common.GetParameters(context, r.GetClient(), instance.Spec.Parameters, instance.Spec.ValuesFrom)
 	if err != nil {
-		log.Error(err, "unable to process resources", "GroupConfig", instance, "groups", selectedGroups)
-		return r.ManageError(context, instance, err)
+		log.Error(err, "unable to get parameters for", "GroupConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
+	}
+
+	templateSets, err := common.GetTemplateSets(context, r.GetClient(), instance.Spec.Templates, instance.Spec.TemplateRefs, parameters)
+	if err != nil {
+		log.Error(err, "unable to get referenced templates for", "GroupConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
 	}
 
-	err = r.UpdateLockedResources(context, instance, lockedResources, []lockedpatch.LockedPatch{})
+	enforcement := common.Enforcement{
+		Config:          instance,
+		Mode:            instance.Spec.EnforcementMode,
+		ServerSideApply: instance.Spec.ServerSideApply,
+	}
+	resources, lookups, err := r.enforcer.Render(context, enforcement, r.getTargets(instance, selectedGroups), templateSets)
 	if err != nil {
-		log.Error(err, "unable to update locked resources")
-		return r.ManageError(context, instance, err)
+		log.Error(err, "unable to process resources", "GroupConfig", instance, "groups", selectedGroups)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
 	}
 
-	return r.ManageSuccess(context, instance)
+	outcome, reason, err := r.enforcer.Enforce(context, enforcement, resources, lookups)
+	if err != nil {
+		return r.enforcer.ManageError(context, instance, reason, err)
+	}
+	return r.enforcer.ManageSuccess(context, instance, outcome)
 }
 
-func (r *GroupConfigReconciler) getResourceList(instance *redhatcopv1alpha1.GroupConfig, groups []userv1.Group) ([]lockedresource.LockedResource, error) {
-	lockedresources := []lockedresource.LockedResource{}
+// getTargets returns the targets for which the templates of a config are processed
+func (r *GroupConfigReconciler) getTargets(instance *redhatcopv1alpha1.GroupConfig, groups []client.Object) []common.Target {
+	targets := []common.Target{}
 	for _, group := range groups {
-		lrs, err := lockedresource.GetLockedResourcesFromTemplatesWithRestConfig(instance.Spec.Templates, r.GetRestConfig(), group)
-		if err != nil {
-			r.Log.Error(err, "unable to process", "templates", instance.Spec.Templates, "with param", group)
-			return []lockedresource.LockedResource{}, err
-		}
-		lockedresources = append(lockedresources, lrs...)
+		targets = append(targets, common.Target{
+			Kind:   r.IdentitySource.GroupKind(),
+			Object: group,
+			Data:   common.NewTemplateData(group, "GroupConfig", instance, r.Cluster),
+		})
 	}
-	return lockedresources, nil
+	return targets
 }
 
-func (r *GroupConfigReconciler) getSelectedGroups(context context.Context, instance *redhatcopv1alpha1.GroupConfig) ([]userv1.Group, error) {
-	groupList := &userv1.GroupList{}
-
+func (r *GroupConfigReconciler) getSelectedGroups(context context.Context, instance *redhatcopv1alpha1.GroupConfig) ([]client.Object, error) {
 	labelSelector, err := metav1.LabelSelectorAsSelector(&instance.Spec.LabelSelector)
 	if err != nil {
 		r.Log.Error(err, "unable to create ", "selector from", instance.Spec.LabelSelector)
-		return []userv1.Group{}, err
+		return []client.Object{}, err
 	}
 
 	annotationSelector, err := metav1.LabelSelectorAsSelector(&instance.Spec.AnnotationSelector)
 	if err != nil {
 		r.Log.Error(err, "unable to create ", "selector from", instance.Spec.AnnotationSelector)
-		return []userv1.Group{}, err
+		return []client.Object{}, err
 	}
 
-	err = r.GetClient().List(context, groupList, &client.ListOptions{
-		LabelSelector: labelSelector,
-	})
+	groups, err := r.IdentitySource.ListGroups(context)
 	if err != nil {
-		r.Log.Error(err, "unable to get groups with", "selector", labelSelector)
-		return []userv1.Group{}, err
+		r.Log.Error(err, "unable to get all groups of kind", "kind", r.IdentitySource.GroupKind())
+		return []client.Object{}, err
 	}
 
-	selectedGroups := []userv1.Group{}
-	for _, group := range groupList.Items {
-		annotationsAsLabels := labels.Set(group.Annotations)
-		if annotationSelector.Matches(annotationsAsLabels) {
+	selectedGroups := []client.Object{}
+	for _, group := range groups {
+		labelsAsLabels := labels.Set(group.GetLabels())
+		annotationsAsLabels := labels.Set(group.GetAnnotations())
+		if labelSelector.Matches(labelsAsLabels) && annotationSelector.Matches(annotationsAsLabels) {
 			selectedGroups = append(selectedGroups, group)
 		}
 	}
@@ -174,7 +213,7 @@ func (r *GroupConfigReconciler) getSelectedGroups(context context.Context, insta
 	return selectedGroups, nil
 }
 
-func (r *GroupConfigReconciler) findApplicableGroupConfigsFromGroup(ctx context.Context, group userv1.Group) ([]redhatcopv1alpha1.GroupConfig, error) {
+func (r *GroupConfigReconciler) findApplicableGroupConfigsFromGroup(ctx context.Context, group client.Object) ([]redhatcopv1alpha1.GroupConfig, error) {
 	groupConfigList := &redhatcopv1alpha1.GroupConfigList{}
 	err := r.GetClient().List(ctx, groupConfigList, &client.ListOptions{})
 	if err != nil {
@@ -206,65 +245,91 @@ func (r *GroupConfigReconciler) findApplicableGroupConfigsFromGroup(ctx context.
 	return applicableGroupConfigs, nil
 }
 
-// IsInitialized none
-func (r *GroupConfigReconciler) IsInitialized(instance *redhatcopv1alpha1.GroupConfig) bool {
-	needsUpdate := true
-	for i := range instance.Spec.Templates {
-		currentSet := strset.New(instance.Spec.Templates[i].ExcludedPaths...)
-		if !currentSet.IsEqual(strset.Union(common.DefaultExcludedPathsSet, currentSet)) {
-			instance.Spec.Templates[i].ExcludedPaths = strset.Union(common.DefaultExcludedPathsSet, currentSet).List()
-			needsUpdate = false
+// groupHandler returns the handler enqueuing the GroupConfigs applicable to a changed group
+func (r *GroupConfigReconciler) groupHandler() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("GroupConfig", r.IdentitySource.GroupKind(), func(ctx context.Context, a client.Object) []reconcile.Request {
+		reconcileRequests := []reconcile.Request{}
+		groupConfigs, err := r.findApplicableGroupConfigsFromGroup(ctx, a)
+		if err != nil {
+			r.Log.Error(err, "unable to find applicable GroupConfigs for", "group", a.GetName())
+			return []reconcile.Request{}
 		}
-	}
-	if len(instance.Spec.Templates) > 0 && !util.HasFinalizer(instance, r.controllerName) {
-		util.AddFinalizer(instance, r.controllerName)
-		needsUpdate = false
-	}
-	if len(instance.Spec.Templates) == 0 && util.HasFinalizer(instance, r.controllerName) {
-		util.RemoveFinalizer(instance, r.controllerName)
-		needsUpdate = false
-	}
-
-	return needsUpdate
+		for _, groupconfig := range groupConfigs {
+			reconcileRequests = append(reconcileRequests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      groupconfig.GetName(),
+					Namespace: groupconfig.GetNamespace(),
+				},
+			})
+		}
+		return reconcileRequests
+	}))
 }
 
-func (r *GroupConfigReconciler) manageCleanUpLogic(instance *redhatcopv1alpha1.GroupConfig) error {
-	err := r.Terminate(instance, true)
+// WatchIdentitySource watches the groups of the identity source the controller has switched to, and reconciles all the GroupConfigs so that the resources generated for the groups of the previous identity source are replaced
+func (r *GroupConfigReconciler) WatchIdentitySource(ctx context.Context, identitySource common.IdentitySource) error {
+	err := r.controller.Watch(source.Kind(r.cache, identitySource.NewGroup()), r.groupHandler())
 	if err != nil {
-		r.Log.Error(err, "unable to terminate enforcing reconciler for", "instance", instance)
 		return err
 	}
+	configList := &redhatcopv1alpha1.GroupConfigList{}
+	err = r.GetClient().List(ctx, configList, &client.ListOptions{})
+	if err != nil {
+		return err
+	}
+	go func() {
+		for i := range configList.Items {
+			select {
+			case r.identityEvents <- event.GenericEvent{Object: &configList.Items[i]}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GroupConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.controllerName = "groupconfig-controller"
-
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&redhatcopv1alpha1.GroupConfig{}, builder.WithPredicates(util.ResourceGenerationOrFinalizerChangedPredicate{})).
-		Watches(&userv1.Group{
+	lookupWatcher, err := common.NewLookupWatcher(mgr.GetConfig(), mgr.GetRESTMapper(), r.Log)
+	if err != nil {
+		return err
+	}
+	r.lookupWatcher = lookupWatcher
+	r.applier = common.NewApplier(r.GetClient(), r.Log)
+	r.enforcer = common.NewEnforcer("GroupConfig", &r.EnforcingReconciler, r.Ownership, r.applier, r.lookupWatcher, r.Log)
+	r.cache = mgr.GetCache()
+	r.identityEvents = make(chan event.GenericEvent)
+
+	// the controller is kept to watch the groups of the OpenShift user API when the identity source switches to it
+	groupConfigController, err := ctrl.NewControllerManagedBy(mgr).
+		For(&redhatcopv1alpha1.GroupConfig{}, builder.WithPredicates(predicate.Or(util.ResourceGenerationOrFinalizerChangedPredicate{}, predicate.LabelChangedPredicate{}), common.ShardPredicate(r.ConfigSelector))).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles, RateLimiter: r.RateLimiter}).
+		Watches(r.IdentitySource.NewGroup(), r.groupHandler()).
+		Watches(&corev1.ConfigMap{
 			TypeMeta: metav1.TypeMeta{
-				Kind: "Group",
+				Kind: "ConfigMap",
 			},
-		}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, a client.Object) []reconcile.Request {
-			reconcileRequests := []reconcile.Request{}
-			group := a.(*userv1.Group)
-			groupConfigs, err := r.findApplicableGroupConfigsFromGroup(ctx, *group)
-			if err != nil {
-				r.Log.Error(err, "unable to find applicable GroupConfigs for", "group", group)
-				return []reconcile.Request{}
-			}
-			for _, userconfig := range groupConfigs {
-				reconcileRequests = append(reconcileRequests, reconcile.Request{
-					NamespacedName: types.NamespacedName{
-						Name:      userconfig.GetName(),
-						Namespace: userconfig.GetNamespace(),
-					},
-				})
-			}
-			return reconcileRequests
-		})).
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("GroupConfig", "ConfigMap", common.FindConfigsReferencing(r.GetClient(), &redhatcopv1alpha1.GroupConfigList{}, common.ConfigMapKind, false, r.Log)))).
+		Watches(&corev1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Secret",
+			},
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("GroupConfig", "Secret", common.FindConfigsReferencing(r.GetClient(), &redhatcopv1alpha1.GroupConfigList{}, common.SecretKind, false, r.Log)))).
+		Watches(&redhatcopv1alpha1.ConfigTemplate{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "ConfigTemplate",
+			},
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("GroupConfig", "ConfigTemplate", common.FindConfigsFromConfigTemplate(r.GetClient(), &redhatcopv1alpha1.GroupConfigList{}, r.Log)))).
 		WatchesRawSource(&source.Channel{Source: r.GetStatusChangeChannel()}, &handler.EnqueueRequestForObject{}).
-		Complete(r)
+		WatchesRawSource(&source.Channel{Source: r.lookupWatcher.GetEventChannel()}, &handler.EnqueueRequestForObject{}).
+		WatchesRawSource(&source.Channel{Source: r.Ownership.GetEventChannel("GroupConfig")}, &handler.EnqueueRequestForObject{}).
+		WatchesRawSource(&source.Channel{Source: r.identityEvents}, &handler.EnqueueRequestForObject{}).
+		Build(r)
+	if err != nil {
+		return err
+	}
+	r.controller = groupConfigController
+	return nil
 }
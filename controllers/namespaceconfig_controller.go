@@ -18,16 +18,14 @@ package controllers
 
 import (
 	"context"
-	"strings"
+	"sort"
 
 	"github.com/go-logr/logr"
+	configv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/config/v1alpha1"
 	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
 	"github.com/redhat-cop/namespace-configuration-operator/controllers/common"
 	"github.com/redhat-cop/operator-utils/pkg/util"
 	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller"
-	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedpatch"
-	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
-	"github.com/scylladb/go-set/strset"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -36,7 +34,10 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -44,14 +45,23 @@ import (
 // NamespaceConfigReconciler reconciles a NamespaceConfig object
 type NamespaceConfigReconciler struct {
 	lockedresourcecontroller.EnforcingReconciler
-	Log                   logr.Logger
-	controllerName        string
-	AllowSystemNamespaces bool
+	Log                     logr.Logger
+	controllerName          string
+	ProtectedNamespaces     configv1alpha1.ProtectedNamespaces
+	lookupWatcher           *common.LookupWatcher
+	applier                 *common.Applier
+	enforcer                *common.Enforcer
+	Ownership               *common.OwnershipRegistry
+	MaxConcurrentReconciles int
+	RateLimiter             ratelimiter.RateLimiter
+	ConfigSelector          labels.Selector
+	Cluster                 common.ClusterInfo
 }
 
 // +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=namespaceconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=namespaceconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=namespaceconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=configtemplates,verbs=get;list;watch
 // +kubebuilder:rbac:groups=*,resources=*,verbs=*
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -65,6 +75,8 @@ type NamespaceConfigReconciler struct {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
 func (r *NamespaceConfigReconciler) Reconcile(context context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("namespaceconfig", req.NamespacedName)
+	context, span := common.StartSpan(context, "Reconcile", "NamespaceConfig", req.Name)
+	defer span.End()
 	log.Info("reconciling started")
 	// Fetch the NamespaceConfig instance
 	instance := &redhatcopv1alpha1.NamespaceConfig{}
@@ -79,7 +91,12 @@ func (r *NamespaceConfigReconciler) Reconcile(context context.Context, req ctrl.
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
-	if !r.IsInitialized(instance) {
+	if !common.InShard(r.ConfigSelector, instance) {
+		// the instance moved to the shard of another operator, which takes over its resources, status and finalizer
+		log.Info("releasing instance outside of the shard of this operator")
+		return reconcile.Result{}, r.enforcer.Release(instance)
+	}
+	if !common.IsInitialized(instance, r.controllerName) {
 		err := r.GetClient().Update(context, instance)
 		if err != nil {
 			log.Error(err, "unable to update instance", "instance", instance)
@@ -92,7 +109,7 @@ func (r *NamespaceConfigReconciler) Reconcile(context context.Context, req ctrl.
 		if !util.HasFinalizer(instance, r.controllerName) {
 			return reconcile.Result{}, nil
 		}
-		err := r.manageCleanUpLogic(instance)
+		err := r.enforcer.Delete(context, instance)
 		if err != nil {
 			log.Error(err, "unable to delete instance", "instance", instance)
 			return r.ManageError(context, instance, err)
@@ -106,154 +123,237 @@ func (r *NamespaceConfigReconciler) Reconcile(context context.Context, req ctrl.
 		return reconcile.Result{}, nil
 	}
 	//get selected namespaces
-	selectedNamespaces, err := r.getSelectedNamespaces(context, instance)
+	selectContext, selectSpan := common.StartSpan(context, "getSelectedNamespaces", "NamespaceConfig", instance.GetName())
+	selectedNamespaces, hierarchy, err := r.getSelectedNamespaces(selectContext, instance)
+	selectSpan.SetAttributes(common.SelectedAttribute.Int(len(selectedNamespaces)))
+	common.EndSpan(selectSpan, err)
 	if err != nil {
 		log.Error(err, "unable to get namespaces selected by", "NamespaceConfig", instance)
-		return r.ManageError(context, instance, err)
+		return r.enforcer.ManageError(context, instance, common.SelectorInvalidReason, err)
 	}
+	common.SelectedObjects.WithLabelValues("NamespaceConfig", instance.GetName()).Set(float64(len(selectedNamespaces)))
+	instance.Status.SelectedCount = int32(len(selectedNamespaces))
 
-	lockedResources, err := r.getResourceList(instance, selectedNamespaces)
+	parameters, err := common.GetParameters(context, r.GetClient(), instance.Spec.Parameters, instance.Spec.ValuesFrom)
 	if err != nil {
-		log.Error(err, "unable to process resources", "NamespaceConfig", instance, "namespaces", selectedNamespaces)
-		return r.ManageError(context, instance, err)
+		log.Error(err, "unable to get parameters for", "NamespaceConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
 	}
 
-	err = r.UpdateLockedResources(context, instance, lockedResources, []lockedpatch.LockedPatch{})
+	templateSets, err := common.GetTemplateSets(context, r.GetClient(), instance.Spec.Templates, instance.Spec.TemplateRefs, parameters)
 	if err != nil {
-		log.Error(err, "unable to update locked resources")
-		return r.ManageError(context, instance, err)
+		log.Error(err, "unable to get referenced templates for", "NamespaceConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
 	}
 
-	return r.ManageSuccess(context, instance)
-}
+	targets, err := r.getTargets(context, instance, selectedNamespaces, hierarchy)
+	if err != nil {
+		common.TemplateRenderErrors.WithLabelValues("NamespaceConfig", instance.GetName()).Inc()
+		log.Error(err, "unable to process resources", "NamespaceConfig", instance, "namespaces", selectedNamespaces)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
+	}
+	enforcement := common.Enforcement{
+		Config: instance,
+		Mode:   instance.Spec.EnforcementMode,
+		ClaimOptions: common.ClaimOptions{
+			Priority:  instance.Spec.Priority,
+			MergeMode: instance.Spec.MergeMode,
+		},
+		ServerSideApply: instance.Spec.ServerSideApply,
+	}
+	resources, lookups, err := r.enforcer.Render(context, enforcement, targets, templateSets)
+	if err != nil {
+		log.Error(err, "unable to process resources", "NamespaceConfig", instance, "namespaces", selectedNamespaces)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
+	}
+	// the copied objects are watched like the looked up ones, so that the copies are updated when they change
+	lookups = append(lookups, common.GetCopySourceReferences(instance.Spec.CopyFrom)...)
 
-func (r *NamespaceConfigReconciler) manageCleanUpLogic(instance *redhatcopv1alpha1.NamespaceConfig) error {
-	err := r.Terminate(instance, true)
+	outcome, reason, err := r.enforcer.Enforce(context, enforcement, resources, lookups)
 	if err != nil {
-		r.Log.Error(err, "unable to terminate enforcing reconciler for", "instance", instance)
-		return err
+		return r.enforcer.ManageError(context, instance, reason, err)
 	}
-	return nil
+	return r.enforcer.ManageSuccess(context, instance, outcome)
 }
 
-// IsInitialized none
-func (r *NamespaceConfigReconciler) IsInitialized(instance *redhatcopv1alpha1.NamespaceConfig) bool {
-	needsUpdate := true
-	for i := range instance.Spec.Templates {
-		currentSet := strset.New(instance.Spec.Templates[i].ExcludedPaths...)
-		if !currentSet.IsEqual(strset.Union(common.DefaultExcludedPathsSet, currentSet)) {
-			instance.Spec.Templates[i].ExcludedPaths = strset.Union(common.DefaultExcludedPathsSet, currentSet).List()
-			needsUpdate = false
+// getTargets returns the targets for which the templates of a config are processed, with the ancestors of each namespace and the objects copied into it
+func (r *NamespaceConfigReconciler) getTargets(context context.Context, instance *redhatcopv1alpha1.NamespaceConfig, namespaces []corev1.Namespace, hierarchy *common.NamespaceHierarchy) ([]common.Target, error) {
+	copySources, err := common.GetCopySources(context, r.GetClient(), instance.Spec.CopyFrom)
+	if err != nil {
+		r.Log.Error(err, "unable to read the copied objects of", "NamespaceConfig", instance.GetName())
+		return []common.Target{}, err
+	}
+	targets := []common.Target{}
+	for i := range namespaces {
+		namespace := &namespaces[i]
+		data := common.NewTemplateData(*namespace, "NamespaceConfig", instance, r.Cluster)
+		data.Parents, err = hierarchy.GetAncestors(context, namespace)
+		if err != nil {
+			r.Log.Error(err, "unable to get the ancestors of", "namespace", namespace.GetName())
+			return []common.Target{}, err
+		}
+		copies, err := common.CopyInto(copySources, namespace.GetName())
+		if err != nil {
+			r.Log.Error(err, "unable to copy objects into", "namespace", namespace.GetName())
+			return []common.Target{}, err
 		}
+		targets = append(targets, common.Target{
+			Kind:   "Namespace",
+			Object: namespace,
+			Data:   data,
+			Copies: copies,
+		})
 	}
-	if len(instance.Spec.Templates) > 0 && !util.HasFinalizer(instance, r.controllerName) {
-		util.AddFinalizer(instance, r.controllerName)
-		needsUpdate = false
+	return targets, nil
+}
+
+// getSelectedNamespaces returns the namespaces selected by a config, together with the hierarchy used to select them.
+// Only the namespaces matching the label selector are read from the cache, and each ancestor is read once.
+func (r *NamespaceConfigReconciler) getSelectedNamespaces(context context.Context, namespaceconfig *redhatcopv1alpha1.NamespaceConfig) ([]corev1.Namespace, *common.NamespaceHierarchy, error) {
+	hierarchy := common.NewNamespaceHierarchy(r.GetClient())
+	selector, err := r.newNamespaceSelector(namespaceconfig)
+	if err != nil {
+		return []corev1.Namespace{}, hierarchy, err
 	}
-	if len(instance.Spec.Templates) == 0 && util.HasFinalizer(instance, r.controllerName) {
-		util.RemoveFinalizer(instance, r.controllerName)
-		needsUpdate = false
+	nl := corev1.NamespaceList{}
+	err = r.GetClient().List(context, &nl, client.MatchingLabelsSelector{Selector: selector.labels})
+	if err != nil {
+		r.Log.Error(err, "unable to list namespaces")
+		return []corev1.Namespace{}, hierarchy, err
 	}
 
-	return needsUpdate
-}
-
-func (r *NamespaceConfigReconciler) getResourceList(instance *redhatcopv1alpha1.NamespaceConfig, groups []corev1.Namespace) ([]lockedresource.LockedResource, error) {
-	lockedresources := []lockedresource.LockedResource{}
-	for _, group := range groups {
-		lrs, err := lockedresource.GetLockedResourcesFromTemplatesWithRestConfig(instance.Spec.Templates, r.GetRestConfig(), group)
+	selectedNamespaces := []corev1.Namespace{}
+	for i := range nl.Items {
+		selected, err := r.selects(context, selector, &nl.Items[i], hierarchy)
 		if err != nil {
-			r.Log.Error(err, "unable to process", "templates", instance.Spec.Templates, "with param", group)
-			return []lockedresource.LockedResource{}, err
+			return []corev1.Namespace{}, hierarchy, err
+		}
+		if selected {
+			selectedNamespaces = append(selectedNamespaces, nl.Items[i])
 		}
-		lockedresources = append(lockedresources, lrs...)
 	}
-	return lockedresources, nil
+	sort.Slice(selectedNamespaces, func(i, j int) bool {
+		return selectedNamespaces[i].GetName() < selectedNamespaces[j].GetName()
+	})
+
+	return selectedNamespaces, hierarchy, nil
 }
 
-func (r *NamespaceConfigReconciler) getSelectedNamespaces(context context.Context, namespaceconfig *redhatcopv1alpha1.NamespaceConfig) ([]corev1.Namespace, error) {
-	nl := corev1.NamespaceList{}
-	selector, err := metav1.LabelSelectorAsSelector(&namespaceconfig.Spec.LabelSelector)
+// namespaceSelector holds the parsed selectors of a config, so that they are parsed once for all the namespaces
+type namespaceSelector struct {
+	labels      labels.Selector
+	annotations labels.Selector
+	// ancestors is nil when the config does not select the namespaces by their ancestors
+	ancestors labels.Selector
+}
+
+// newNamespaceSelector parses the selectors of a config
+func (r *NamespaceConfigReconciler) newNamespaceSelector(namespaceconfig *redhatcopv1alpha1.NamespaceConfig) (namespaceSelector, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(&namespaceconfig.Spec.LabelSelector)
 	if err != nil {
 		r.Log.Error(err, "unable to create selector from label selector", "selector", &namespaceconfig.Spec.LabelSelector)
-		return []corev1.Namespace{}, err
+		return namespaceSelector{}, err
 	}
-
 	annotationSelector, err := metav1.LabelSelectorAsSelector(&namespaceconfig.Spec.AnnotationSelector)
 	if err != nil {
 		r.Log.Error(err, "unable to create ", "selector from", namespaceconfig.Spec.AnnotationSelector)
-		return []corev1.Namespace{}, err
+		return namespaceSelector{}, err
 	}
-
-	err = r.GetClient().List(context, &nl, &client.ListOptions{LabelSelector: selector})
-	if err != nil {
-		r.Log.Error(err, "unable to list namespaces with selector", "selector", selector)
-		return []corev1.Namespace{}, err
+	selector := namespaceSelector{
+		labels:      labelSelector,
+		annotations: annotationSelector,
 	}
-
-	selectedNamespaces := []corev1.Namespace{}
-
-	for _, namespace := range nl.Items {
-		annotationsAsLabels := labels.Set(namespace.Annotations)
-		if annotationSelector.Matches(annotationsAsLabels) && (r.AllowSystemNamespaces || !isProhibitedNamespaceName(namespace.GetName())) {
-			selectedNamespaces = append(selectedNamespaces, namespace)
+	if namespaceconfig.Spec.AncestorSelector != nil {
+		selector.ancestors, err = metav1.LabelSelectorAsSelector(namespaceconfig.Spec.AncestorSelector)
+		if err != nil {
+			r.Log.Error(err, "unable to create ", "selector from", namespaceconfig.Spec.AncestorSelector)
+			return namespaceSelector{}, err
 		}
 	}
+	return selector, nil
+}
 
-	return selectedNamespaces, nil
+// selects returns whether a config selects a namespace, by its labels, its annotations and the labels of its ancestors. The protected namespaces are never selected.
+func (r *NamespaceConfigReconciler) selects(context context.Context, selector namespaceSelector, namespace *corev1.Namespace, hierarchy *common.NamespaceHierarchy) (bool, error) {
+	if r.ProtectedNamespaces.IsProtected(namespace.GetName()) {
+		return false, nil
+	}
+	if !selector.labels.Matches(labels.Set(namespace.GetLabels())) || !selector.annotations.Matches(labels.Set(namespace.GetAnnotations())) {
+		return false, nil
+	}
+	if selector.ancestors == nil {
+		return true, nil
+	}
+	return hierarchy.HasAncestorMatching(context, namespace, selector.ancestors)
 }
 
-func (r *NamespaceConfigReconciler) findApplicableNameSpaceConfigs(ctx context.Context, namespace corev1.Namespace) ([]redhatcopv1alpha1.NamespaceConfig, error) {
-	if !r.AllowSystemNamespaces && isProhibitedNamespaceName(namespace.GetName()) {
-		return []redhatcopv1alpha1.NamespaceConfig{}, nil
+// findApplicableNameSpaceConfigs returns the configs selecting a namespace or one of its descendants, whose ancestors changed with it.
+// The given version of the namespace replaces the cached one, so that the configs selecting the namespace, or its descendants, before a change are found too.
+// The descendants are found through the ParentIndex, without listing all the namespaces.
+func (r *NamespaceConfigReconciler) findApplicableNameSpaceConfigs(ctx context.Context, namespace *corev1.Namespace) ([]redhatcopv1alpha1.NamespaceConfig, error) {
+	hierarchy := common.NewNamespaceHierarchy(r.GetClient())
+	hierarchy.Replace(namespace)
+	descendants, err := hierarchy.GetDescendants(ctx, namespace.GetName())
+	if err != nil {
+		r.Log.Error(err, "unable to get the descendants of", "namespace", namespace.GetName())
+		return []redhatcopv1alpha1.NamespaceConfig{}, err
 	}
+	namespaces := append([]corev1.Namespace{*namespace}, descendants...)
+
 	//find all the namespaceconfig
 	result := []redhatcopv1alpha1.NamespaceConfig{}
 	ncl := redhatcopv1alpha1.NamespaceConfigList{}
-	err := r.GetClient().List(ctx, &ncl, &client.ListOptions{})
+	err = r.GetClient().List(ctx, &ncl, &client.ListOptions{})
 	if err != nil {
 		r.Log.Error(err, "unable to retrieve the list of namespace configs")
 		return []redhatcopv1alpha1.NamespaceConfig{}, err
 	}
-	//for each namespaceconfig see if it selects the namespace
-	for _, nc := range ncl.Items {
-		labelSelector, err := metav1.LabelSelectorAsSelector(&nc.Spec.LabelSelector)
-		if err != nil {
-			r.Log.Error(err, "unable to create selector from label selector", "selector", &nc.Spec.LabelSelector)
-			return []redhatcopv1alpha1.NamespaceConfig{}, err
-		}
-		annotationSelector, err := metav1.LabelSelectorAsSelector(&nc.Spec.AnnotationSelector)
+	//for each namespaceconfig see if it selects the namespace or one of its descendants
+	for i := range ncl.Items {
+		selector, err := r.newNamespaceSelector(&ncl.Items[i])
 		if err != nil {
-			r.Log.Error(err, "unable to create ", "selector from", nc.Spec.AnnotationSelector)
 			return []redhatcopv1alpha1.NamespaceConfig{}, err
 		}
-
-		labelsAslabels := labels.Set(namespace.GetLabels())
-		annotationsAsLabels := labels.Set(namespace.GetAnnotations())
-		if labelSelector.Matches(labelsAslabels) && annotationSelector.Matches(annotationsAsLabels) {
-			result = append(result, nc)
+		for j := range namespaces {
+			selected, err := r.selects(ctx, selector, &namespaces[j], hierarchy)
+			if err != nil {
+				return []redhatcopv1alpha1.NamespaceConfig{}, err
+			}
+			if selected {
+				result = append(result, ncl.Items[i])
+				break
+			}
 		}
 	}
 	return result, nil
 }
 
-func isProhibitedNamespaceName(name string) bool {
-	return name == "default" || strings.HasPrefix(name, "openshift-") || strings.HasPrefix(name, "kube-")
-}
-
 // SetupWithManager sets up the controller with the Manager.
 func (r *NamespaceConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.controllerName = "namespaceconfig-controller"
+	lookupWatcher, err := common.NewLookupWatcher(mgr.GetConfig(), mgr.GetRESTMapper(), r.Log)
+	if err != nil {
+		return err
+	}
+	r.lookupWatcher = lookupWatcher
+	r.applier = common.NewApplier(r.GetClient(), r.Log)
+	r.enforcer = common.NewEnforcer("NamespaceConfig", &r.EnforcingReconciler, r.Ownership, r.applier, r.lookupWatcher, r.Log)
+	// the namespaces are indexed by their parent, to find the descendants of a namespace without listing all the namespaces
+	err = mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Namespace{}, common.ParentIndex, common.IndexNamespaceParent)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&redhatcopv1alpha1.NamespaceConfig{}, builder.WithPredicates(util.ResourceGenerationOrFinalizerChangedPredicate{})).
+		For(&redhatcopv1alpha1.NamespaceConfig{}, builder.WithPredicates(predicate.Or(util.ResourceGenerationOrFinalizerChangedPredicate{}, predicate.LabelChangedPredicate{}), common.ShardPredicate(r.ConfigSelector))).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles, RateLimiter: r.RateLimiter}).
 		Watches(&corev1.Namespace{
 			TypeMeta: metav1.TypeMeta{
 				Kind: "Namespace",
 			},
-		}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, a client.Object) []reconcile.Request {
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("NamespaceConfig", "Namespace", func(ctx context.Context, a client.Object) []reconcile.Request {
 			res := []reconcile.Request{}
 			ns := a.(*corev1.Namespace)
-			ncl, err := r.findApplicableNameSpaceConfigs(ctx, *ns)
+			ncl, err := r.findApplicableNameSpaceConfigs(ctx, ns)
 			if err != nil {
 				r.Log.Error(err, "unable to find applicable NamespaceConfig for namespace", "namespace", ns.Name)
 				return []reconcile.Request{}
@@ -267,7 +367,24 @@ func (r *NamespaceConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				})
 			}
 			return res
-		})).
+		}))).
+		Watches(&corev1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "ConfigMap",
+			},
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("NamespaceConfig", "ConfigMap", common.FindConfigsReferencing(r.GetClient(), &redhatcopv1alpha1.NamespaceConfigList{}, common.ConfigMapKind, false, r.Log)))).
+		Watches(&corev1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Secret",
+			},
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("NamespaceConfig", "Secret", common.FindConfigsReferencing(r.GetClient(), &redhatcopv1alpha1.NamespaceConfigList{}, common.SecretKind, false, r.Log)))).
+		Watches(&redhatcopv1alpha1.ConfigTemplate{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "ConfigTemplate",
+			},
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("NamespaceConfig", "ConfigTemplate", common.FindConfigsFromConfigTemplate(r.GetClient(), &redhatcopv1alpha1.NamespaceConfigList{}, r.Log)))).
 		WatchesRawSource(&source.Channel{Source: r.GetStatusChangeChannel()}, &handler.EnqueueRequestForObject{}).
+		WatchesRawSource(&source.Channel{Source: r.lookupWatcher.GetEventChannel()}, &handler.EnqueueRequestForObject{}).
+		WatchesRawSource(&source.Channel{Source: r.Ownership.GetEventChannel("NamespaceConfig")}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
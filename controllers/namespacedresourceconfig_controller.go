@@ -0,0 +1,379 @@
+/*
+Copyright 2020 Red Hat Community of Practice.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	configv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/config/v1alpha1"
+	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
+	"github.com/redhat-cop/namespace-configuration-operator/controllers/common"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// NamespacedResourceConfigReconciler reconciles a NamespacedResourceConfig object
+type NamespacedResourceConfigReconciler struct {
+	lockedresourcecontroller.EnforcingReconciler
+	Log                     logr.Logger
+	controllerName          string
+	lookupWatcher           *common.LookupWatcher
+	applier                 *common.Applier
+	enforcer                *common.Enforcer
+	ProtectedNamespaces     configv1alpha1.ProtectedNamespaces
+	Ownership               *common.OwnershipRegistry
+	MaxConcurrentReconciles int
+	RateLimiter             ratelimiter.RateLimiter
+	ConfigSelector          labels.Selector
+	Cluster                 common.ClusterInfo
+	controller              controller.Controller
+	cache                   cache.Cache
+	restMapper              meta.RESTMapper
+	impersonatingClients    *common.ImpersonatingClients
+	// the target kinds are watched on demand, the first time a config selects them
+	targetsMutex sync.Mutex
+	targets      map[schema.GroupVersionKind]bool
+}
+
+// +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=namespacedresourceconfigs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=namespacedresourceconfigs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=namespacedresourceconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=configtemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=*,resources=*,verbs=*
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=impersonate
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+// TODO(user): Modify the Reconcile function to compare the state specified by
+// the NamespacedResourceConfig object against the actual cluster state, and then
+// perform operations to make the cluster state reflect the state specified by
+// the user.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
+func (r *NamespacedResourceConfigReconciler) Reconcile(context context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("namespacedresourceconfig", req.NamespacedName)
+	context, span := common.StartSpan(context, "Reconcile", "NamespacedResourceConfig", req.NamespacedName.String())
+	defer span.End()
+
+	// Fetch the NamespacedResourceConfig instance
+	instance := &redhatcopv1alpha1.NamespacedResourceConfig{}
+	err := r.GetClient().Get(context, req.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+	if !common.InShard(r.ConfigSelector, instance) {
+		// the instance moved to the shard of another operator, which takes over its resources, status and finalizer
+		log.Info("releasing instance outside of the shard of this operator")
+		return reconcile.Result{}, r.enforcer.Release(instance)
+	}
+
+	if !common.IsInitialized(instance, r.controllerName) {
+		err := r.GetClient().Update(context, instance)
+		if err != nil {
+			log.Error(err, "unable to update instance", "instance", instance)
+			return r.ManageError(context, instance, err)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if util.IsBeingDeleted(instance) {
+		if !util.HasFinalizer(instance, r.controllerName) {
+			return reconcile.Result{}, nil
+		}
+		err := r.enforcer.Delete(context, instance)
+		if err != nil {
+			log.Error(err, "unable to delete instance", "instance", instance)
+			return r.ManageError(context, instance, err)
+		}
+		util.RemoveFinalizer(instance, r.controllerName)
+		err = r.GetClient().Update(context, instance)
+		if err != nil {
+			log.Error(err, "unable to update instance", "instance", instance)
+			return r.ManageError(context, instance, err)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	//get selected objects
+	selectContext, selectSpan := common.StartSpan(context, "getSelectedObjects", "NamespacedResourceConfig", common.ConfigName(instance))
+	selectedObjects, err := r.getSelectedObjects(selectContext, instance)
+	selectSpan.SetAttributes(common.SelectedAttribute.Int(len(selectedObjects)))
+	common.EndSpan(selectSpan, err)
+	if err != nil {
+		log.Error(err, "unable to get objects selected by", "NamespacedResourceConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.SelectorInvalidReason, err)
+	}
+	common.SelectedObjects.WithLabelValues("NamespacedResourceConfig", common.ConfigName(instance)).Set(float64(len(selectedObjects)))
+	instance.Status.SelectedCount = int32(len(selectedObjects))
+
+	err = common.CheckNamespacedValues(instance.Spec.ValuesFrom, instance.GetNamespace())
+	if err != nil {
+		log.Error(err, "invalid values references in", "NamespacedResourceConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.ForbiddenReason, err)
+	}
+
+	parameters, err := common.GetParameters(context, r.GetClient(), instance.Spec.Parameters, instance.Spec.ValuesFrom)
+	if err != nil {
+		log.Error(err, "unable to get parameters for", "NamespacedResourceConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
+	}
+
+	templateSets, err := common.GetTemplateSets(context, r.GetClient(), instance.Spec.Templates, instance.Spec.TemplateRefs, parameters)
+	if err != nil {
+		log.Error(err, "unable to get referenced templates for", "NamespacedResourceConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
+	}
+
+	enforcement := common.Enforcement{
+		Config:          instance,
+		Mode:            instance.Spec.EnforcementMode,
+		ServerSideApply: instance.Spec.ServerSideApply,
+		Namespace:       instance.GetNamespace(),
+	}
+	// the objects are looked up, read, created and enforced impersonating the service account, so that the API server authorizes them
+	enforcement.Client, err = r.impersonatingClients.GetClient(instance.GetNamespace(), instance.Spec.ServiceAccountName)
+	if err != nil {
+		log.Error(err, "unable to impersonate the service account of", "NamespacedResourceConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.ApplyFailedReason, err)
+	}
+	enforcement.RestConfig = common.ImpersonateServiceAccount(r.GetRestConfig(), instance.GetNamespace(), instance.Spec.ServiceAccountName)
+
+	resources, lookups, err := r.enforcer.Render(context, enforcement, r.getTargets(instance, selectedObjects), templateSets)
+	if err != nil {
+		log.Error(err, "unable to process resources", "NamespacedResourceConfig", instance, "objects", len(selectedObjects))
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
+	}
+
+	err = common.CheckNamespacedResources(r.restMapper, instance.GetNamespace(), resources)
+	if err != nil {
+		log.Error(err, "resources not allowed for", "NamespacedResourceConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.ForbiddenReason, err)
+	}
+
+	outcome, reason, err := r.enforcer.Enforce(context, enforcement, resources, lookups)
+	if err != nil {
+		return r.enforcer.ManageError(context, instance, reason, err)
+	}
+	return r.enforcer.ManageSuccess(context, instance, outcome)
+}
+
+// getTargets returns the targets for which the templates of a config are processed
+func (r *NamespacedResourceConfigReconciler) getTargets(instance *redhatcopv1alpha1.NamespacedResourceConfig, objs []unstructured.Unstructured) []common.Target {
+	targets := []common.Target{}
+	for i := range objs {
+		obj := &objs[i]
+		targets = append(targets, common.Target{
+			Kind:   obj.GetKind(),
+			Object: obj,
+			Data:   common.NewTemplateData(obj.Object, "NamespacedResourceConfig", instance, r.Cluster),
+		})
+	}
+	return targets
+}
+
+// getSelectedObjects returns the objects of the target kind selected by a config in its namespace, nothing is selected in the protected namespaces
+func (r *NamespacedResourceConfigReconciler) getSelectedObjects(context context.Context, instance *redhatcopv1alpha1.NamespacedResourceConfig) ([]unstructured.Unstructured, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(&instance.Spec.LabelSelector)
+	if err != nil {
+		r.Log.Error(err, "unable to create ", "selector from", instance.Spec.LabelSelector)
+		return []unstructured.Unstructured{}, err
+	}
+
+	annotationSelector, err := metav1.LabelSelectorAsSelector(&instance.Spec.AnnotationSelector)
+	if err != nil {
+		r.Log.Error(err, "unable to create ", "selector from", instance.Spec.AnnotationSelector)
+		return []unstructured.Unstructured{}, err
+	}
+
+	if r.ProtectedNamespaces.IsProtected(instance.GetNamespace()) {
+		return []unstructured.Unstructured{}, nil
+	}
+
+	gvk := instance.Spec.TargetGVK.GroupVersionKind()
+	mapping, err := r.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		r.Log.Error(err, "unable to find the target", "kind", gvk)
+		return []unstructured.Unstructured{}, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return []unstructured.Unstructured{}, fmt.Errorf("kind %s is not namespaced, only namespaced objects can be selected", gvk.Kind)
+	}
+	err = r.watchTarget(gvk)
+	if err != nil {
+		r.Log.Error(err, "unable to watch the target", "kind", gvk)
+		return []unstructured.Unstructured{}, err
+	}
+
+	objList := &unstructured.UnstructuredList{}
+	objList.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	err = r.cache.List(context, objList, &client.ListOptions{
+		LabelSelector: labelSelector,
+		Namespace:     instance.GetNamespace(),
+	})
+	if err != nil {
+		r.Log.Error(err, "unable to list objects with", "kind", gvk, "selector", labelSelector)
+		return []unstructured.Unstructured{}, err
+	}
+
+	selectedObjects := []unstructured.Unstructured{}
+	for _, obj := range objList.Items {
+		annotationsAsLabels := labels.Set(obj.GetAnnotations())
+		if annotationSelector.Matches(annotationsAsLabels) {
+			selectedObjects = append(selectedObjects, obj)
+		}
+	}
+
+	return selectedObjects, nil
+}
+
+// isProtected tells whether an object is in one of the namespaces that the configs never select
+func (r *NamespacedResourceConfigReconciler) isProtected(obj client.Object) bool {
+	return obj.GetNamespace() != "" && r.ProtectedNamespaces.IsProtected(obj.GetNamespace())
+}
+
+// watchTarget starts watching the objects of a target kind the first time it is selected, so that the configs selecting them are reconciled when they change.
+// The kind must be served by the API server, otherwise the watch could never start.
+func (r *NamespacedResourceConfigReconciler) watchTarget(gvk schema.GroupVersionKind) error {
+	r.targetsMutex.Lock()
+	defer r.targetsMutex.Unlock()
+	if r.targets[gvk] {
+		return nil
+	}
+	_, err := r.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	err = r.controller.Watch(source.Kind(r.cache, obj), handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("NamespacedResourceConfig", gvk.Kind, r.findNamespacedResourceConfigsFromTarget(gvk))))
+	if err != nil {
+		return err
+	}
+	r.targets[gvk] = true
+	return nil
+}
+
+// findNamespacedResourceConfigsFromTarget returns a function mapping an object of the given kind to the NamespacedResourceConfigs selecting it
+func (r *NamespacedResourceConfigReconciler) findNamespacedResourceConfigsFromTarget(gvk schema.GroupVersionKind) handler.MapFunc {
+	return func(ctx context.Context, a client.Object) []reconcile.Request {
+		reconcileRequests := []reconcile.Request{}
+		if r.isProtected(a) {
+			return reconcileRequests
+		}
+		namespacedResourceConfigList := &redhatcopv1alpha1.NamespacedResourceConfigList{}
+		err := r.GetClient().List(ctx, namespacedResourceConfigList, &client.ListOptions{Namespace: a.GetNamespace()})
+		if err != nil {
+			r.Log.Error(err, "unable to get all namespacedresourceconfigs")
+			return []reconcile.Request{}
+		}
+		for _, namespacedResourceConfig := range namespacedResourceConfigList.Items {
+			if namespacedResourceConfig.Spec.TargetGVK.GroupVersionKind() != gvk {
+				continue
+			}
+			matches, err := common.MatchesSelectors(&namespacedResourceConfig.Spec.LabelSelector, &namespacedResourceConfig.Spec.AnnotationSelector, a)
+			if err != nil {
+				r.Log.Error(err, "unable to evaluate the selectors of", "config", namespacedResourceConfig.GetName())
+				continue
+			}
+			if matches {
+				reconcileRequests = append(reconcileRequests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      namespacedResourceConfig.GetName(),
+						Namespace: namespacedResourceConfig.GetNamespace(),
+					},
+				})
+			}
+		}
+		return reconcileRequests
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NamespacedResourceConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.controllerName = "namespacedresourceconfig-controller"
+	lookupWatcher, err := common.NewLookupWatcher(mgr.GetConfig(), mgr.GetRESTMapper(), r.Log)
+	if err != nil {
+		return err
+	}
+	r.lookupWatcher = lookupWatcher
+	r.applier = common.NewApplier(r.GetClient(), r.Log)
+	r.enforcer = common.NewEnforcer("NamespacedResourceConfig", &r.EnforcingReconciler, r.Ownership, r.applier, r.lookupWatcher, r.Log)
+
+	r.cache = mgr.GetCache()
+	r.restMapper = mgr.GetRESTMapper()
+	r.impersonatingClients = common.NewImpersonatingClients(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+	r.targetsMutex = sync.Mutex{}
+	r.targets = map[schema.GroupVersionKind]bool{}
+
+	// the target kinds are not known in advance, so the controller is kept to add their watches when the configs are reconciled
+	namespacedResourceConfigController, err := ctrl.NewControllerManagedBy(mgr).
+		For(&redhatcopv1alpha1.NamespacedResourceConfig{}, builder.WithPredicates(predicate.Or(util.ResourceGenerationOrFinalizerChangedPredicate{}, predicate.LabelChangedPredicate{}), common.ShardPredicate(r.ConfigSelector))).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles, RateLimiter: r.RateLimiter}).
+		Watches(&corev1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "ConfigMap",
+			},
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("NamespacedResourceConfig", "ConfigMap", common.FindConfigsReferencing(r.GetClient(), &redhatcopv1alpha1.NamespacedResourceConfigList{}, common.ConfigMapKind, true, r.Log)))).
+		Watches(&corev1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Secret",
+			},
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("NamespacedResourceConfig", "Secret", common.FindConfigsReferencing(r.GetClient(), &redhatcopv1alpha1.NamespacedResourceConfigList{}, common.SecretKind, true, r.Log)))).
+		Watches(&redhatcopv1alpha1.ConfigTemplate{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "ConfigTemplate",
+			},
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("NamespacedResourceConfig", "ConfigTemplate", common.FindConfigsFromConfigTemplate(r.GetClient(), &redhatcopv1alpha1.NamespacedResourceConfigList{}, r.Log)))).
+		WatchesRawSource(&source.Channel{Source: r.GetStatusChangeChannel()}, &handler.EnqueueRequestForObject{}).
+		WatchesRawSource(&source.Channel{Source: r.lookupWatcher.GetEventChannel()}, &handler.EnqueueRequestForObject{}).
+		WatchesRawSource(&source.Channel{Source: r.Ownership.GetEventChannel("NamespacedResourceConfig")}, &handler.EnqueueRequestForObject{}).
+		Build(r)
+	if err != nil {
+		return err
+	}
+	r.controller = namespacedResourceConfigController
+	return nil
+}
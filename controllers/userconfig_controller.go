@@ -18,25 +18,26 @@ package controllers
 
 import (
 	"context"
-	errs "errors"
 
 	"github.com/go-logr/logr"
-	userv1 "github.com/openshift/api/user/v1"
 	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
 	"github.com/redhat-cop/namespace-configuration-operator/controllers/common"
 	"github.com/redhat-cop/operator-utils/pkg/util"
 	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller"
-	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedpatch"
-	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller/lockedresource"
-	"github.com/scylladb/go-set/strset"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -44,13 +45,26 @@ import (
 // UserConfigReconciler reconciles a UserConfig object
 type UserConfigReconciler struct {
 	lockedresourcecontroller.EnforcingReconciler
-	Log            logr.Logger
-	controllerName string
+	Log                     logr.Logger
+	controllerName          string
+	lookupWatcher           *common.LookupWatcher
+	applier                 *common.Applier
+	enforcer                *common.Enforcer
+	Ownership               *common.OwnershipRegistry
+	IdentitySource          common.IdentitySource
+	MaxConcurrentReconciles int
+	RateLimiter             ratelimiter.RateLimiter
+	ConfigSelector          labels.Selector
+	Cluster                 common.ClusterInfo
+	controller              controller.Controller
+	cache                   cache.Cache
+	identityEvents          chan event.GenericEvent
 }
 
 // +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=userconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=userconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=userconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=redhatcop.redhat.io,resources=configtemplates,verbs=get;list;watch
 // +kubebuilder:rbac:groups=*,resources=*,verbs=*
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -64,6 +78,8 @@ type UserConfigReconciler struct {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
 func (r *UserConfigReconciler) Reconcile(context context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("userconfig", req.NamespacedName)
+	context, span := common.StartSpan(context, "Reconcile", "UserConfig", req.Name)
+	defer span.End()
 
 	// Fetch the UserConfig instance
 	instance := &redhatcopv1alpha1.UserConfig{}
@@ -78,8 +94,13 @@ func (r *UserConfigReconciler) Reconcile(context context.Context, req ctrl.Reque
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
+	if !common.InShard(r.ConfigSelector, instance) {
+		// the instance moved to the shard of another operator, which takes over its resources, status and finalizer
+		log.Info("releasing instance outside of the shard of this operator")
+		return reconcile.Result{}, r.enforcer.Release(instance)
+	}
 
-	if !r.IsInitialized(instance) {
+	if !common.IsInitialized(instance, r.controllerName) {
 		err := r.GetClient().Update(context, instance)
 		if err != nil {
 			log.Error(err, "unable to update instance", "instance", instance)
@@ -92,7 +113,7 @@ func (r *UserConfigReconciler) Reconcile(context context.Context, req ctrl.Reque
 		if !util.HasFinalizer(instance, r.controllerName) {
 			return reconcile.Result{}, nil
 		}
-		err := r.manageCleanUpLogic(instance)
+		err := r.enforcer.Delete(context, instance)
 		if err != nil {
 			log.Error(err, "unable to delete instance", "instance", instance)
 			return r.ManageError(context, instance, err)
@@ -107,71 +128,78 @@ func (r *UserConfigReconciler) Reconcile(context context.Context, req ctrl.Reque
 	}
 
 	//get selected users
-	selectedUsers, err := r.getSelectedUsers(context, instance)
+	selectContext, selectSpan := common.StartSpan(context, "getSelectedUsers", "UserConfig", instance.GetName())
+	selectedUsers, err := r.getSelectedUsers(selectContext, instance)
+	selectSpan.SetAttributes(common.SelectedAttribute.Int(len(selectedUsers)))
+	common.EndSpan(selectSpan, err)
 	if err != nil {
 		log.Error(err, "unable to get users selected by", "UserConfig", instance)
-		return r.ManageError(context, instance, err)
+		return r.enforcer.ManageError(context, instance, common.SelectorInvalidReason, err)
 	}
+	common.SelectedObjects.WithLabelValues("UserConfig", instance.GetName()).Set(float64(len(selectedUsers)))
+	instance.Status.SelectedCount = int32(len(selectedUsers))
 
-	lockedResources, err := r.getResourceList(instance, selectedUsers)
+	parameters, err := common.GetParameters(context, r.GetClient(), instance.Spec.Parameters, instance.Spec.ValuesFrom)
 	if err != nil {
-		log.Error(err, "unable to process resources", "UserConfig", instance, "users", selectedUsers)
-		return r.ManageError(context, instance, err)
+		log.Error(err, "unable to get parameters for", "UserConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
 	}
 
-	err = r.UpdateLockedResources(context, instance, lockedResources, []lockedpatch.LockedPatch{})
+	templateSets, err := common.GetTemplateSets(context, r.GetClient(), instance.Spec.Templates, instance.Spec.TemplateRefs, parameters)
 	if err != nil {
-		log.Error(err, "unable to update locked resources")
-		return r.ManageError(context, instance, err)
+		log.Error(err, "unable to get referenced templates for", "UserConfig", instance)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
 	}
 
-	return r.ManageSuccess(context, instance)
-}
+	enforcement := common.Enforcement{
+		Config:          instance,
+		Mode:            instance.Spec.EnforcementMode,
+		ServerSideApply: instance.Spec.ServerSideApply,
+	}
+	resources, lookups, err := r.enforcer.Render(context, enforcement, r.getTargets(instance, selectedUsers), templateSets)
+	if err != nil {
+		log.Error(err, "unable to process resources", "UserConfig", instance, "users", selectedUsers)
+		return r.enforcer.ManageError(context, instance, common.RenderFailedReason, err)
+	}
 
-func (r *UserConfigReconciler) getResourceList(instance *redhatcopv1alpha1.UserConfig, users []userv1.User) ([]lockedresource.LockedResource, error) {
-	lockedresources := []lockedresource.LockedResource{}
-	for _, user := range users {
-		lrs, err := lockedresource.GetLockedResourcesFromTemplatesWithRestConfig(instance.Spec.Templates, r.GetRestConfig(), user)
-		if err != nil {
-			r.Log.Error(err, "unable to process", "templates", instance.Spec.Templates, "with param", user)
-			return []lockedresource.LockedResource{}, err
-		}
-		lockedresources = append(lockedresources, lrs...)
+	outcome, reason, err := r.enforcer.Enforce(context, enforcement, resources, lookups)
+	if err != nil {
+		return r.enforcer.ManageError(context, instance, reason, err)
 	}
-	return lockedresources, nil
+	return r.enforcer.ManageSuccess(context, instance, outcome)
 }
 
-func (r *UserConfigReconciler) getSelectedUsers(context context.Context, instance *redhatcopv1alpha1.UserConfig) ([]userv1.User, error) {
-	userList := &userv1.UserList{}
-	identitiesList := &userv1.IdentityList{}
-
-	err := r.GetClient().List(context, userList, &client.ListOptions{})
-	if err != nil {
-		r.Log.Error(err, "unable to get all users")
-		return []userv1.User{}, err
+// getTargets returns the targets for which the templates of a config are processed
+func (r *UserConfigReconciler) getTargets(instance *redhatcopv1alpha1.UserConfig, users []common.User) []common.Target {
+	targets := []common.Target{}
+	for _, user := range users {
+		targets = append(targets, common.Target{
+			Kind:   r.IdentitySource.UserKind(),
+			Object: user.Object,
+			Data:   common.NewTemplateData(user.Object, "UserConfig", instance, r.Cluster),
+		})
 	}
+	return targets
+}
 
-	err = r.GetClient().List(context, identitiesList, &client.ListOptions{})
+func (r *UserConfigReconciler) getSelectedUsers(context context.Context, instance *redhatcopv1alpha1.UserConfig) ([]common.User, error) {
+	users, err := r.IdentitySource.ListUsers(context)
 	if err != nil {
-		r.Log.Error(err, "unable to get all identities")
-		return []userv1.User{}, err
+		r.Log.Error(err, "unable to get all users of kind", "kind", r.IdentitySource.UserKind())
+		return []common.User{}, err
 	}
 
-	selectedUsers := []userv1.User{}
-
-	for _, user := range userList.Items {
-		for _, identity := range identitiesList.Items {
-			if user.GetUID() == identity.User.UID {
-				if r.matches(instance, &user, &identity) {
-					selectedUsers = append(selectedUsers, user)
-				}
-			}
+	selectedUsers := []common.User{}
+	for _, user := range users {
+		if r.matches(instance, user) {
+			selectedUsers = append(selectedUsers, user)
 		}
 	}
 	return selectedUsers, nil
 }
 
-func (r *UserConfigReconciler) matches(instance *redhatcopv1alpha1.UserConfig, user *userv1.User, indentity *userv1.Identity) bool {
+// matches tells whether a user is selected by a UserConfig through at least one of its identities
+func (r *UserConfigReconciler) matches(instance *redhatcopv1alpha1.UserConfig, user common.User) bool {
 	extraFieldSelector, err := metav1.LabelSelectorAsSelector(&instance.Spec.IdentityExtraFieldSelector)
 	if err != nil {
 		r.Log.Error(err, "unable to create ", "selector from", instance.Spec.IdentityExtraFieldSelector)
@@ -188,150 +216,139 @@ func (r *UserConfigReconciler) matches(instance *redhatcopv1alpha1.UserConfig, u
 		return false
 	}
 
-	extraFieldAsLabels := labels.Set(indentity.Extra)
-	labelsAsLabels := labels.Set(user.Labels)
-	annotationsAsLabels := labels.Set(user.Annotations)
-	if instance.Spec.ProviderName != "" {
-		return extraFieldSelector.Matches(extraFieldAsLabels) && labelSelector.Matches(labelsAsLabels) && annotationSelector.Matches(annotationsAsLabels) && indentity.ProviderName == instance.Spec.ProviderName
+	labelsAsLabels := labels.Set(user.Object.GetLabels())
+	annotationsAsLabels := labels.Set(user.Object.GetAnnotations())
+	if !labelSelector.Matches(labelsAsLabels) || !annotationSelector.Matches(annotationsAsLabels) {
+		return false
+	}
+	for _, identity := range user.Identities {
+		extraFieldAsLabels := labels.Set(identity.Extra)
+		if extraFieldSelector.Matches(extraFieldAsLabels) && (instance.Spec.ProviderName == "" || identity.ProviderName == instance.Spec.ProviderName) {
+			return true
+		}
 	}
-	return extraFieldSelector.Matches(extraFieldAsLabels) && labelSelector.Matches(labelsAsLabels) && annotationSelector.Matches(annotationsAsLabels)
+	return false
 }
 
-func (r *UserConfigReconciler) findApplicableUserConfigsFromIdentities(user *userv1.User, identities []userv1.Identity) ([]redhatcopv1alpha1.UserConfig, error) {
+// findApplicableUserConfigs returns the UserConfigs selecting any of the given users
+func (r *UserConfigReconciler) findApplicableUserConfigs(ctx context.Context, users []common.User) ([]redhatcopv1alpha1.UserConfig, error) {
 	userConfigList := &redhatcopv1alpha1.UserConfigList{}
-	err := r.GetClient().List(context.TODO(), userConfigList, &client.ListOptions{})
+	err := r.GetClient().List(ctx, userConfigList, &client.ListOptions{})
 	if err != nil {
 		r.Log.Error(err, "unable to get all userconfigs")
 		return []redhatcopv1alpha1.UserConfig{}, err
 	}
 	applicableUserConfigs := []redhatcopv1alpha1.UserConfig{}
 	for _, userConfig := range userConfigList.Items {
-		for _, identity := range identities {
-			if r.matches(&userConfig, user, &identity) {
+		for _, user := range users {
+			if r.matches(&userConfig, user) {
 				applicableUserConfigs = append(applicableUserConfigs, userConfig)
+				break
 			}
 		}
 	}
 	return applicableUserConfigs, nil
 }
 
-func (r *UserConfigReconciler) findApplicableUserConfigsFromUser(ctx context.Context, user *userv1.User) ([]redhatcopv1alpha1.UserConfig, error) {
-	identitiesList := &userv1.IdentityList{}
-	err := r.GetClient().List(ctx, identitiesList, &client.ListOptions{})
-	if err != nil {
-		r.Log.Error(err, "unable to get all identities")
-		return []redhatcopv1alpha1.UserConfig{}, err
-	}
-	matchingIdentities := []userv1.Identity{}
-	for _, identity := range identitiesList.Items {
-		cidentity := identity.DeepCopy()
-		matchingIdentities = append(matchingIdentities, *cidentity)
-	}
-	return r.findApplicableUserConfigsFromIdentities(user, matchingIdentities)
-}
-
-// IsInitialized none
-func (r *UserConfigReconciler) IsInitialized(instance *redhatcopv1alpha1.UserConfig) bool {
-	needsUpdate := true
-	for i := range instance.Spec.Templates {
-		currentSet := strset.New(instance.Spec.Templates[i].ExcludedPaths...)
-		if !currentSet.IsEqual(strset.Union(common.DefaultExcludedPathsSet, currentSet)) {
-			instance.Spec.Templates[i].ExcludedPaths = strset.Union(common.DefaultExcludedPathsSet, currentSet).List()
-			needsUpdate = false
+// findUserConfigsFromUsers returns a function mapping an object watched by the identity source to the UserConfigs selecting the users affected by its change
+func (r *UserConfigReconciler) findUserConfigsFromUsers(watch common.UserWatch) handler.MapFunc {
+	return func(ctx context.Context, a client.Object) []reconcile.Request {
+		reconcileRequests := []reconcile.Request{}
+		users, err := watch.MapToUsers(ctx, a)
+		if err != nil {
+			r.Log.Error(err, "unable to find the users affected by", "kind", watch.Kind, "name", a.GetName())
+			return []reconcile.Request{}
 		}
+		userConfigs, err := r.findApplicableUserConfigs(ctx, users)
+		if err != nil {
+			r.Log.Error(err, "unable to find applicable UserConfigs for", "kind", watch.Kind, "name", a.GetName())
+			return []reconcile.Request{}
+		}
+		for _, userconfig := range userConfigs {
+			reconcileRequests = append(reconcileRequests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      userconfig.GetName(),
+					Namespace: userconfig.GetNamespace(),
+				},
+			})
+		}
+		return reconcileRequests
 	}
-	if len(instance.Spec.Templates) > 0 && !util.HasFinalizer(instance, r.controllerName) {
-		util.AddFinalizer(instance, r.controllerName)
-		needsUpdate = false
-	}
-	if len(instance.Spec.Templates) == 0 && util.HasFinalizer(instance, r.controllerName) {
-		util.RemoveFinalizer(instance, r.controllerName)
-		needsUpdate = false
-	}
-
-	return needsUpdate
 }
 
-func (r *UserConfigReconciler) manageCleanUpLogic(instance *redhatcopv1alpha1.UserConfig) error {
-	err := r.Terminate(instance, true)
-	if err != nil {
-		r.Log.Error(err, "unable to terminate enforcing reconciler for", "instance", instance)
-		return err
-	}
-	return nil
+// userWatchHandler returns the handler enqueuing the UserConfigs affected by the change of an object watched to detect the changes of the users
+func (r *UserConfigReconciler) userWatchHandler(watch common.UserWatch) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("UserConfig", watch.Kind, r.findUserConfigsFromUsers(watch)))
 }
 
-func (r *UserConfigReconciler) findUserFromIdentity(ctx context.Context, identity *userv1.Identity) (*userv1.User, error) {
-	userList := &userv1.UserList{}
-	err := r.GetClient().List(ctx, userList, &client.ListOptions{})
+// WatchIdentitySource watches the users of the identity source the controller has switched to, and reconciles all the UserConfigs so that the resources generated for the users of the previous identity source are replaced
+func (r *UserConfigReconciler) WatchIdentitySource(ctx context.Context, identitySource common.IdentitySource) error {
+	for _, watch := range identitySource.UserWatches() {
+		err := r.controller.Watch(source.Kind(r.cache, watch.Object), r.userWatchHandler(watch))
+		if err != nil {
+			return err
+		}
+	}
+	configList := &redhatcopv1alpha1.UserConfigList{}
+	err := r.GetClient().List(ctx, configList, &client.ListOptions{})
 	if err != nil {
-		r.Log.Error(err, "unable to get all users")
-		return &userv1.User{}, err
+		return err
 	}
-
-	for _, user := range userList.Items {
-		r.Log.V(1).Info("comparing", "user uid", user.GetUID(), " and identity uid", identity.User.UID)
-		if user.GetUID() == identity.User.UID {
-			return &user, nil
+	go func() {
+		for i := range configList.Items {
+			select {
+			case r.identityEvents <- event.GenericEvent{Object: &configList.Items[i]}:
+			case <-ctx.Done():
+				return
+			}
 		}
-	}
-	return &userv1.User{}, errs.New("user not found")
+	}()
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *UserConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.controllerName = "userconfig-controller"
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&redhatcopv1alpha1.UserConfig{}, builder.WithPredicates(util.ResourceGenerationOrFinalizerChangedPredicate{})).
-		Watches(&userv1.User{
+	lookupWatcher, err := common.NewLookupWatcher(mgr.GetConfig(), mgr.GetRESTMapper(), r.Log)
+	if err != nil {
+		return err
+	}
+	r.lookupWatcher = lookupWatcher
+	r.applier = common.NewApplier(r.GetClient(), r.Log)
+	r.enforcer = common.NewEnforcer("UserConfig", &r.EnforcingReconciler, r.Ownership, r.applier, r.lookupWatcher, r.Log)
+	r.cache = mgr.GetCache()
+	r.identityEvents = make(chan event.GenericEvent)
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		For(&redhatcopv1alpha1.UserConfig{}, builder.WithPredicates(predicate.Or(util.ResourceGenerationOrFinalizerChangedPredicate{}, predicate.LabelChangedPredicate{}), common.ShardPredicate(r.ConfigSelector))).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles, RateLimiter: r.RateLimiter})
+	for _, watch := range r.IdentitySource.UserWatches() {
+		controllerBuilder = controllerBuilder.Watches(watch.Object, r.userWatchHandler(watch))
+	}
+	// the controller is kept to watch the users of the OpenShift user API when the identity source switches to it
+	userConfigController, err := controllerBuilder.
+		Watches(&corev1.ConfigMap{
 			TypeMeta: metav1.TypeMeta{
-				Kind: "User",
+				Kind: "ConfigMap",
 			},
-		}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, a client.Object) []reconcile.Request {
-			reconcileRequests := []reconcile.Request{}
-			user := a.(*userv1.User)
-			userConfigs, err := r.findApplicableUserConfigsFromUser(ctx, user)
-			if err != nil {
-				r.Log.Error(err, "unable to find applicable UserConfigs for", "user", user)
-				return []reconcile.Request{}
-			}
-			for _, userconfig := range userConfigs {
-				reconcileRequests = append(reconcileRequests, reconcile.Request{
-					NamespacedName: types.NamespacedName{
-						Name:      userconfig.GetName(),
-						Namespace: userconfig.GetNamespace(),
-					},
-				})
-			}
-			return reconcileRequests
-		})).
-		Watches(&userv1.Identity{
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("UserConfig", "ConfigMap", common.FindConfigsReferencing(r.GetClient(), &redhatcopv1alpha1.UserConfigList{}, common.ConfigMapKind, false, r.Log)))).
+		Watches(&corev1.Secret{
 			TypeMeta: metav1.TypeMeta{
-				Kind: "Identity",
+				Kind: "Secret",
 			},
-		}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, a client.Object) []reconcile.Request {
-			reconcileRequests := []reconcile.Request{}
-			identity := a.(*userv1.Identity)
-			user, err := r.findUserFromIdentity(ctx, identity)
-			if err != nil {
-				r.Log.Error(err, "unable to find applicable User for", "identity", identity)
-				return []reconcile.Request{}
-			}
-			userConfigs, err := r.findApplicableUserConfigsFromIdentities(user, []userv1.Identity{*identity})
-			if err != nil {
-				r.Log.Error(err, "unable to find applicable UserConfigs for", "identity", identity)
-				return []reconcile.Request{}
-			}
-			for _, userconfig := range userConfigs {
-				reconcileRequests = append(reconcileRequests, reconcile.Request{
-					NamespacedName: types.NamespacedName{
-						Name:      userconfig.GetName(),
-						Namespace: userconfig.GetNamespace(),
-					},
-				})
-			}
-			return reconcileRequests
-		})).
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("UserConfig", "Secret", common.FindConfigsReferencing(r.GetClient(), &redhatcopv1alpha1.UserConfigList{}, common.SecretKind, false, r.Log)))).
+		Watches(&redhatcopv1alpha1.ConfigTemplate{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "ConfigTemplate",
+			},
+		}, handler.EnqueueRequestsFromMapFunc(common.TimeMapFunc("UserConfig", "ConfigTemplate", common.FindConfigsFromConfigTemplate(r.GetClient(), &redhatcopv1alpha1.UserConfigList{}, r.Log)))).
 		WatchesRawSource(&source.Channel{Source: r.GetStatusChangeChannel()}, &handler.EnqueueRequestForObject{}).
-		Complete(r)
+		WatchesRawSource(&source.Channel{Source: r.lookupWatcher.GetEventChannel()}, &handler.EnqueueRequestForObject{}).
+		WatchesRawSource(&source.Channel{Source: r.Ownership.GetEventChannel("UserConfig")}, &handler.EnqueueRequestForObject{}).
+		WatchesRawSource(&source.Channel{Source: r.identityEvents}, &handler.EnqueueRequestForObject{}).
+		Build(r)
+	if err != nil {
+		return err
+	}
+	r.controller = userConfigController
+	return nil
 }
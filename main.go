@@ -20,32 +20,29 @@ import (
 	"context"
 	"flag"
 	"os"
-	"strconv"
-	"time"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	userv1 "github.com/openshift/api/user/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
+	configv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/config/v1alpha1"
 	redhatcopv1alpha1 "github.com/redhat-cop/namespace-configuration-operator/api/v1alpha1"
 	"github.com/redhat-cop/namespace-configuration-operator/controllers"
-	"github.com/redhat-cop/operator-utils/pkg/util/discoveryclient"
+	"github.com/redhat-cop/namespace-configuration-operator/controllers/common"
 	"github.com/redhat-cop/operator-utils/pkg/util/lockedresourcecontroller"
 	// +kubebuilder:scaffold:imports
 )
 
-const (
-	AllowSystemNamespacesEnvVarKey = "ALLOW_SYSTEM_NAMESPACES"
-)
-
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -60,14 +57,27 @@ func init() {
 }
 
 func main() {
+	var configFile string
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var enabledControllers string
+	var configSelector string
+	var tracingOptions common.TracingOptions
+	flag.StringVar(&configFile, "config", "", "The path of the operator configuration file. "+
+		"The options not set in the file are read from the environment variables or defaulted.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&enabledControllers, "controllers", "", "The comma separated list of the controllers to run, among NamespaceConfig, GroupConfig, UserConfig, ResourceConfig and NamespacedResourceConfig. "+
+		"When set, the controllers not listed are disabled.")
+	flag.StringVar(&configSelector, "config-selector", "", "The label selector of the configs reconciled by this instance of the operator, "+
+		"so that multiple instances can split the configs in shards.")
+	flag.StringVar(&tracingOptions.Endpoint, "tracing-otlp-endpoint", "", "The host:port of the OTLP gRPC collector the traces are exported to. Tracing is disabled if empty.")
+	flag.BoolVar(&tracingOptions.Insecure, "tracing-otlp-insecure", false, "Connect to the OTLP collector without transport security.")
+	flag.Float64Var(&tracingOptions.SampleRatio, "tracing-sample-ratio", 1, "The fraction of the reconciliations that are traced, between 0 and 1.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -76,78 +86,197 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	var syncPeriod = 36000 * time.Second //Defaults to every 10Hrs
-	if syncPeriodSeconds, ok := os.LookupEnv("SYNC_PERIOD_SECONDS"); ok && syncPeriodSeconds != "" {
-		if syncPeriodSecondsInt, err := strconv.ParseInt(syncPeriodSeconds, 10, 64); err == nil {
-			syncPeriod = time.Duration(syncPeriodSecondsInt) * time.Second
-		} else if err != nil {
-			setupLog.Error(err, "unable to start manager")
-			os.Exit(1)
+	operatorConfig, err := configv1alpha1.Load(configFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load the operator configuration")
+		os.Exit(1)
+	}
+	// the flags set on the command line take precedence over the configuration file
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "controllers":
+			err = operatorConfig.Controllers.EnableOnly(strings.Split(enabledControllers, ","))
+		case "config-selector":
+			operatorConfig.ConfigSelector = configSelector
+		case "metrics-bind-address":
+			operatorConfig.Metrics.BindAddress = metricsAddr
+		case "health-probe-bind-address":
+			operatorConfig.Health.HealthProbeBindAddress = probeAddr
+		case "leader-elect":
+			operatorConfig.LeaderElection.LeaderElect = &enableLeaderElection
 		}
+	})
+	if err == nil {
+		err = operatorConfig.Validate()
+	}
+	if err != nil {
+		setupLog.Error(err, "invalid command line flags")
+		os.Exit(1)
+	}
+	selector, err := operatorConfig.GetConfigSelector()
+	if err != nil {
+		setupLog.Error(err, "invalid config selector")
+		os.Exit(1)
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "b0b2f089.redhat.io",
-		SyncPeriod:             &syncPeriod,
+		Scheme:                  scheme,
+		MetricsBindAddress:      operatorConfig.Metrics.BindAddress,
+		Port:                    9443,
+		HealthProbeBindAddress:  operatorConfig.Health.HealthProbeBindAddress,
+		LeaderElection:          *operatorConfig.LeaderElection.LeaderElect,
+		LeaderElectionID:        operatorConfig.LeaderElection.ResourceName,
+		LeaderElectionNamespace: operatorConfig.LeaderElection.ResourceNamespace,
+		SyncPeriod:              &operatorConfig.SyncPeriod.Duration,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if err = (&controllers.NamespaceConfigReconciler{
-		EnforcingReconciler:   lockedresourcecontroller.NewEnforcingReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetAPIReader(), mgr.GetEventRecorderFor("NamespaceConfig_controller"), true, true),
-		Log:                   ctrl.Log.WithName("controllers").WithName("NamespaceConfig"),
-		AllowSystemNamespaces: checkNamespaceScope(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "NamespaceConfig")
+	shutdownTracing, err := common.SetupTracing(context.TODO(), tracingOptions)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
 		os.Exit(1)
 	}
-	ctx := context.WithValue(context.TODO(), "restConfig", mgr.GetConfig())
 
-	userConfigController := &controllers.UserConfigReconciler{
-		EnforcingReconciler: lockedresourcecontroller.NewEnforcingReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetAPIReader(), mgr.GetEventRecorderFor("UserConfig_controller"), true, true),
-		Log:                 ctrl.Log.WithName("controllers").WithName("UserConfig"),
+	common.RegisterMetrics(metrics.Registry)
+	// the enforcing reconcilers patch a resource only to revert its drift, so their requests are counted as enforcement corrections
+	enforcingConfig := common.WithCorrectionsCounter(mgr.GetConfig(), mgr.GetRESTMapper())
+
+	// the ownership registry is shared by all the controllers, so that conflicts are detected across the config kinds and objects passing from a config to another are not deleted
+	ownershipRegistry := common.NewOwnershipRegistry(mgr.GetClient(), ctrl.Log.WithName("controllers"))
+
+	// the cluster is described to the templates as discovered at startup
+	cluster, err := common.DiscoverClusterInfo(context.TODO(), mgr.GetAPIReader(), mgr.GetConfig(), operatorConfig.Cluster)
+	if err != nil {
+		setupLog.Error(err, "unable to discover the cluster")
+		os.Exit(1)
 	}
+	setupLog.Info("cluster discovered", "baseDomain", cluster.BaseDomain, "apiServerURL", cluster.APIServerURL, "infrastructureName", cluster.InfrastructureName)
 
-	if ok, err := discoveryclient.IsGVKDefined(ctx, schema.GroupVersionKind{
-		Group:   "user.openshift.io",
-		Version: "v1",
-		Kind:    "User",
-	}); !ok || err != nil {
-		if err != nil {
-			setupLog.Error(err, "unable to set check whether resource User.user.openshift.io exists")
+	if operatorConfig.Controllers.NamespaceConfig.IsEnabled() {
+		if err = (&controllers.NamespaceConfigReconciler{
+			EnforcingReconciler:     lockedresourcecontroller.NewEnforcingReconciler(ownershipRegistry.WrapClient(mgr.GetClient()), mgr.GetScheme(), enforcingConfig, mgr.GetAPIReader(), mgr.GetEventRecorderFor("NamespaceConfig_controller"), true, true),
+			Log:                     ctrl.Log.WithName("controllers").WithName("NamespaceConfig"),
+			ProtectedNamespaces:     operatorConfig.ProtectedNamespaces,
+			Ownership:               ownershipRegistry,
+			MaxConcurrentReconciles: operatorConfig.Controllers.NamespaceConfig.MaxConcurrentReconciles,
+			RateLimiter:             common.NewRateLimiter(operatorConfig.Controllers.NamespaceConfig.RateLimiter),
+			ConfigSelector:          selector,
+			Cluster:                 cluster,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "NamespaceConfig")
 			os.Exit(1)
 		}
 	} else {
-		if err = (userConfigController).SetupWithManager(mgr); err != nil {
-			setupLog.Error(err, "unable to create controller", "controller", "UserConfig")
-			os.Exit(1)
-		}
+		setupLog.Info("controller disabled by the operator configuration", "controller", "NamespaceConfig")
 	}
 
-	groupConfigController := &controllers.GroupConfigReconciler{
-		EnforcingReconciler: lockedresourcecontroller.NewEnforcingReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetAPIReader(), mgr.GetEventRecorderFor("GroupConfig_controller"), true, true),
-		Log:                 ctrl.Log.WithName("controllers").WithName("GroupConfig"),
+	if operatorConfig.Controllers.ResourceConfig.IsEnabled() {
+		if err = (&controllers.ResourceConfigReconciler{
+			EnforcingReconciler:     lockedresourcecontroller.NewEnforcingReconciler(ownershipRegistry.WrapClient(mgr.GetClient()), mgr.GetScheme(), enforcingConfig, mgr.GetAPIReader(), mgr.GetEventRecorderFor("ResourceConfig_controller"), true, true),
+			Log:                     ctrl.Log.WithName("controllers").WithName("ResourceConfig"),
+			ProtectedNamespaces:     operatorConfig.ProtectedNamespaces,
+			Ownership:               ownershipRegistry,
+			MaxConcurrentReconciles: operatorConfig.Controllers.ResourceConfig.MaxConcurrentReconciles,
+			RateLimiter:             common.NewRateLimiter(operatorConfig.Controllers.ResourceConfig.RateLimiter),
+			ConfigSelector:          selector,
+			Cluster:                 cluster,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ResourceConfig")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("controller disabled by the operator configuration", "controller", "ResourceConfig")
 	}
 
-	if ok, err := discoveryclient.IsGVKDefined(ctx, schema.GroupVersionKind{
-		Group:   "user.openshift.io",
-		Version: "v1",
-		Kind:    "Group",
-	}); !ok || err != nil {
-		if err != nil {
-			setupLog.Error(err, "unable to set check wheter resource Group.user.openshift.io exists")
+	if operatorConfig.Controllers.NamespacedResourceConfig.IsEnabled() {
+		// the objects of a namespaced config are enforced impersonating its service account, which may only watch the namespace of the config
+		if err = (&controllers.NamespacedResourceConfigReconciler{
+			EnforcingReconciler:     lockedresourcecontroller.NewEnforcingReconciler(ownershipRegistry.WrapClient(mgr.GetClient()), mgr.GetScheme(), enforcingConfig, mgr.GetAPIReader(), mgr.GetEventRecorderFor("NamespacedResourceConfig_controller"), false, true),
+			Log:                     ctrl.Log.WithName("controllers").WithName("NamespacedResourceConfig"),
+			ProtectedNamespaces:     operatorConfig.ProtectedNamespaces,
+			Ownership:               ownershipRegistry,
+			MaxConcurrentReconciles: operatorConfig.Controllers.NamespacedResourceConfig.MaxConcurrentReconciles,
+			RateLimiter:             common.NewRateLimiter(operatorConfig.Controllers.NamespacedResourceConfig.RateLimiter),
+			ConfigSelector:          selector,
+			Cluster:                 cluster,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "NamespacedResourceConfig")
 			os.Exit(1)
 		}
 	} else {
-		if err = (groupConfigController).SetupWithManager(mgr); err != nil {
-			setupLog.Error(err, "unable to create controller", "controller", "GroupConfig")
+		setupLog.Info("controller disabled by the operator configuration", "controller", "NamespacedResourceConfig")
+	}
+
+	// the UserConfig and GroupConfig controllers are set up once the source of the users and groups is available, which may happen after the manager is started
+	identityConfigs := []client.ObjectList{}
+	if operatorConfig.Controllers.UserConfig.IsEnabled() {
+		identityConfigs = append(identityConfigs, &redhatcopv1alpha1.UserConfigList{})
+	} else {
+		setupLog.Info("controller disabled by the operator configuration", "controller", "UserConfig")
+	}
+	if operatorConfig.Controllers.GroupConfig.IsEnabled() {
+		identityConfigs = append(identityConfigs, &redhatcopv1alpha1.GroupConfigList{})
+	} else {
+		setupLog.Info("controller disabled by the operator configuration", "controller", "GroupConfig")
+	}
+	var identitySourceWatcher *common.IdentitySourceWatcher
+	var userConfigReconciler *controllers.UserConfigReconciler
+	var groupConfigReconciler *controllers.GroupConfigReconciler
+	if len(identityConfigs) > 0 {
+		identitySourceWatcher = common.NewIdentitySourceWatcher(operatorConfig.IdentitySource, mgr.GetConfig(), mgr.GetClient(), mgr.GetAPIReader(), mgr.GetEventRecorderFor("IdentitySource_watcher"), identityConfigs, func(identitySource common.IdentitySource) error {
+			if operatorConfig.Controllers.UserConfig.IsEnabled() {
+				userConfigReconciler = &controllers.UserConfigReconciler{
+					EnforcingReconciler:     lockedresourcecontroller.NewEnforcingReconciler(ownershipRegistry.WrapClient(mgr.GetClient()), mgr.GetScheme(), enforcingConfig, mgr.GetAPIReader(), mgr.GetEventRecorderFor("UserConfig_controller"), true, true),
+					Log:                     ctrl.Log.WithName("controllers").WithName("UserConfig"),
+					Ownership:               ownershipRegistry,
+					IdentitySource:          identitySource,
+					MaxConcurrentReconciles: operatorConfig.Controllers.UserConfig.MaxConcurrentReconciles,
+					RateLimiter:             common.NewRateLimiter(operatorConfig.Controllers.UserConfig.RateLimiter),
+					ConfigSelector:          selector,
+					Cluster:                 cluster,
+				}
+				if err := userConfigReconciler.SetupWithManager(mgr); err != nil {
+					setupLog.Error(err, "unable to create controller", "controller", "UserConfig")
+					return err
+				}
+			}
+			if operatorConfig.Controllers.GroupConfig.IsEnabled() {
+				groupConfigReconciler = &controllers.GroupConfigReconciler{
+					EnforcingReconciler:     lockedresourcecontroller.NewEnforcingReconciler(ownershipRegistry.WrapClient(mgr.GetClient()), mgr.GetScheme(), enforcingConfig, mgr.GetAPIReader(), mgr.GetEventRecorderFor("GroupConfig_controller"), true, true),
+					Log:                     ctrl.Log.WithName("controllers").WithName("GroupConfig"),
+					Ownership:               ownershipRegistry,
+					IdentitySource:          identitySource,
+					MaxConcurrentReconciles: operatorConfig.Controllers.GroupConfig.MaxConcurrentReconciles,
+					RateLimiter:             common.NewRateLimiter(operatorConfig.Controllers.GroupConfig.RateLimiter),
+					ConfigSelector:          selector,
+					Cluster:                 cluster,
+				}
+				if err := groupConfigReconciler.SetupWithManager(mgr); err != nil {
+					setupLog.Error(err, "unable to create controller", "controller", "GroupConfig")
+					return err
+				}
+			}
+			return nil
+		}, func(ctx context.Context, identitySource common.IdentitySource) error {
+			if userConfigReconciler != nil {
+				if err := userConfigReconciler.WatchIdentitySource(ctx, identitySource); err != nil {
+					setupLog.Error(err, "unable to watch the users of the identity source", "kind", identitySource.UserKind())
+					return err
+				}
+			}
+			if groupConfigReconciler != nil {
+				if err := groupConfigReconciler.WatchIdentitySource(ctx, identitySource); err != nil {
+					setupLog.Error(err, "unable to watch the groups of the identity source", "kind", identitySource.GroupKind())
+					return err
+				}
+			}
+			return nil
+		}, ctrl.Log.WithName("identity-source"))
+		if err := mgr.Add(identitySourceWatcher); err != nil {
+			setupLog.Error(err, "unable to set up the identity source watcher")
 			os.Exit(1)
 		}
 	}
@@ -161,22 +290,19 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if identitySourceWatcher != nil {
+		if err := mgr.AddReadyzCheck("identity-source", identitySourceWatcher.ReadyzCheck); err != nil {
+			setupLog.Error(err, "unable to set up the identity source ready check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
-}
-
-func checkNamespaceScope() bool {
-	value := os.Getenv(AllowSystemNamespacesEnvVarKey)
-	if len(value) == 0 {
-		return false
-	}
-	res, err := strconv.ParseBool(value)
-	if err != nil {
-		return false
+	if err := shutdownTracing(context.TODO()); err != nil {
+		setupLog.Error(err, "unable to flush the traces")
 	}
-	return res
 }